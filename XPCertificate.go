@@ -0,0 +1,636 @@
+package XPSuperKit
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+/*********调用示例********
+ca, err := XPSuperKit.NewCertificateAuthority(&XPSuperKit.CertificateOption{CommonName: "Internal CA"})
+
+server, err := ca.IssueServerCertificate(&XPSuperKit.CertificateOption{
+	CommonName: "api.internal",
+	Hosts:      []string{"api.internal", "localhost", "127.0.0.1"},
+})
+client, err := ca.IssueClientCertificate(&XPSuperKit.CertificateOption{CommonName: "billing-service"})
+
+// 服务端要求客户端证书 (mTLS)
+serverConfig, err := ca.ServerTLSConfig(server, true)
+
+// 客户端信任该 CA 并出示客户端证书
+clientConfig, err := ca.ClientTLSConfig(client)
+XPSuperKit.NewHttp().TLS(clientConfig).Get("https://api.internal").End()
+
+// 保存及加载
+err = ca.SaveFiles("./conf/certs/ca.pem", "./conf/certs/ca-key.pem", []byte("passphrase"))
+ca, err = XPSuperKit.LoadCertificateAuthority("./conf/certs/ca.pem", "./conf/certs/ca-key.pem", []byte("passphrase"))
+
+// 查看证书链
+infos, err := XPSuperKit.InspectCertificates(pemData)
+ ************************/
+
+const (
+	kCertificateClockSkew     = 5 * time.Minute
+	kCertificateCAValidFor    = 10 * 365 * 24 * time.Hour
+	kCertificateLeafValidFor  = 365 * 24 * time.Hour
+	kCertificateSerialNumBits = 128
+)
+
+var (
+	EncryptErrInvalidCertificate = errors.New("encrypt: invalid certificate")
+	EncryptErrNotCA              = errors.New("encrypt: certificate is not a CA")
+	EncryptErrKeyMismatch        = errors.New("encrypt: private key does not match certificate")
+)
+
+// 证书参数，未设置的字段使用默认值
+type CertificateOption struct {
+	CommonName         string
+	Organization       []string
+	OrganizationalUnit []string
+	Hosts              []string      //主机名或 IP 地址，写入 SAN，服务端证书未设置时使用 CommonName
+	EmailAddresses     []string      //写入 SAN
+	URIs               []string      //写入 SAN，如 spiffe://cluster/ns/default/sa/billing
+	NotBefore          time.Time     //默认为当前时间减 5 分钟，以容忍时钟偏差
+	ValidFor           time.Duration //有效期，CA 默认 10 年，其他证书默认 1 年
+	MaxPathLen         int           //仅用于 CA，0 表示只能签发终端证书
+	Key                crypto.Signer //证书私钥，为 nil 时生成 ECDSA P-256 私钥
+}
+
+// 证书及其私钥
+type CertificateKeyPair struct {
+	Certificate *x509.Certificate
+	PrivateKey  crypto.Signer
+	Chain       []*x509.Certificate //签发者证书链，不包含根证书
+}
+
+// 本地证书颁发机构
+type XPCertificateAuthorityImpl struct {
+	CertificateKeyPair
+}
+
+/**
+ * 创建自签名 CA
+ * @param opt *CertificateOption CA 参数，CommonName 不能为空
+ */
+func NewCertificateAuthority(opt *CertificateOption) (*XPCertificateAuthorityImpl, error) {
+	if opt == nil || opt.CommonName == "" {
+		return nil, ErrorN("certificate authority common name is required")
+	}
+
+	template, key, err := newCertificateTemplate(opt, kCertificateCAValidFor)
+	if err != nil {
+		return nil, err
+	}
+
+	template.IsCA = true
+	template.BasicConstraintsValid = true
+	template.MaxPathLen = opt.MaxPathLen
+	template.MaxPathLenZero = opt.MaxPathLen == 0
+	template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature
+
+	cert, err := createCertificate(template, template, key.Public(), key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &XPCertificateAuthorityImpl{CertificateKeyPair{Certificate: cert, PrivateKey: key}}, nil
+}
+
+/**
+ * 从文件加载 CA
+ * @param certPath string CA 证书 PEM 文件
+ * @param keyPath string CA 私钥 PEM 文件
+ * @param passphrase []byte 私钥口令，未加密时可为 nil
+ */
+func LoadCertificateAuthority(certPath, keyPath string, passphrase []byte) (*XPCertificateAuthorityImpl, error) {
+	pair, err := LoadCertificateKeyPair(certPath, keyPath, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	if !pair.Certificate.IsCA {
+		return nil, EncryptErrNotCA
+	}
+
+	return &XPCertificateAuthorityImpl{*pair}, nil
+}
+
+// 签发服务端证书，Hosts 中的主机名及 IP 写入 SAN
+func (ca *XPCertificateAuthorityImpl) IssueServerCertificate(opt *CertificateOption) (*CertificateKeyPair, error) {
+	return ca.issue(opt, x509.ExtKeyUsageServerAuth)
+}
+
+// 签发客户端证书，用于 mTLS 客户端认证
+func (ca *XPCertificateAuthorityImpl) IssueClientCertificate(opt *CertificateOption) (*CertificateKeyPair, error) {
+	return ca.issue(opt, x509.ExtKeyUsageClientAuth)
+}
+
+// 签发同时可用于服务端及客户端认证的证书，适用于服务间双向通信
+func (ca *XPCertificateAuthorityImpl) IssuePeerCertificate(opt *CertificateOption) (*CertificateKeyPair, error) {
+	return ca.issue(opt, x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth)
+}
+
+func (ca *XPCertificateAuthorityImpl) issue(opt *CertificateOption, usages ...x509.ExtKeyUsage) (*CertificateKeyPair, error) {
+	if opt == nil {
+		opt = &CertificateOption{}
+	}
+
+	template, key, err := newCertificateTemplate(opt, kCertificateLeafValidFor)
+	if err != nil {
+		return nil, err
+	}
+
+	hosts := opt.Hosts
+	if len(hosts) == 0 && opt.CommonName != "" && usages[0] == x509.ExtKeyUsageServerAuth {
+		hosts = []string{opt.CommonName}
+	}
+
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	template.BasicConstraintsValid = true
+	template.ExtKeyUsage = usages
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	if _, ok := key.Public().(*rsa.PublicKey); ok {
+		template.KeyUsage |= x509.KeyUsageKeyEncipherment
+	}
+
+	// 终端证书的有效期不超过 CA
+	if template.NotAfter.After(ca.Certificate.NotAfter) {
+		template.NotAfter = ca.Certificate.NotAfter
+	}
+
+	cert, err := createCertificate(template, ca.Certificate, key.Public(), ca.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// 根证书不放入证书链
+	var chain []*x509.Certificate
+	if !isSelfSigned(ca.Certificate) {
+		chain = append([]*x509.Certificate{ca.Certificate}, ca.Chain...)
+	}
+
+	return &CertificateKeyPair{Certificate: cert, PrivateKey: key, Chain: chain}, nil
+}
+
+// 包含该 CA 的证书池，用于 tls.Config 的 RootCAs 或 ClientCAs
+func (ca *XPCertificateAuthorityImpl) CertPool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Certificate)
+	return pool
+}
+
+/**
+ * 生成服务端 tls.Config
+ * @param pair *CertificateKeyPair 服务端证书
+ * @param requireClientCert bool 为 true 时要求客户端出示由该 CA 签发的证书
+ */
+func (ca *XPCertificateAuthorityImpl) ServerTLSConfig(pair *CertificateKeyPair, requireClientCert bool) (*tls.Config, error) {
+	cert, err := pair.TLSCertificate()
+	if err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if requireClientCert {
+		config.ClientCAs = ca.CertPool()
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return config, nil
+}
+
+/**
+ * 生成客户端 tls.Config，可直接用于 XPHttpImpl.TLS
+ * @param pair *CertificateKeyPair 客户端证书，为 nil 时不出示客户端证书
+ */
+func (ca *XPCertificateAuthorityImpl) ClientTLSConfig(pair *CertificateKeyPair) (*tls.Config, error) {
+	config := &tls.Config{
+		RootCAs:    ca.CertPool(),
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if pair != nil {
+		cert, err := pair.TLSCertificate()
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+/*********************** 证书及私钥 ********************/
+
+// 从 PEM 文件加载证书及私钥，证书文件中第一个证书之后的证书作为证书链
+func LoadCertificateKeyPair(certPath, keyPath string, passphrase []byte) (*CertificateKeyPair, error) {
+	certs, err := LoadCertificateFile(certPath)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := LoadPrivateKeyFile(keyPath, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, EncryptErrUnsupportedKey
+	}
+
+	pair := &CertificateKeyPair{Certificate: certs[0], PrivateKey: signer, Chain: certs[1:]}
+	if !publicKeyEqual(pair.Certificate.PublicKey, signer.Public()) {
+		return nil, EncryptErrKeyMismatch
+	}
+
+	return pair, nil
+}
+
+// 证书的 PEM 编码
+func (pair *CertificateKeyPair) CertificatePEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: kPEMTypeCertificate, Bytes: pair.Certificate.Raw})
+}
+
+// 证书及证书链的 PEM 编码，可直接用于 nginx 等服务的 fullchain 文件
+func (pair *CertificateKeyPair) FullChainPEM() []byte {
+	var buf bytes.Buffer
+
+	buf.Write(pair.CertificatePEM())
+	for _, cert := range pair.Chain {
+		pem.Encode(&buf, &pem.Block{Type: kPEMTypeCertificate, Bytes: cert.Raw})
+	}
+
+	return buf.Bytes()
+}
+
+// 私钥的 PKCS#8 PEM 编码，passphrase 不为 nil 时加密
+func (pair *CertificateKeyPair) PrivateKeyPEM(passphrase []byte) ([]byte, error) {
+	return MarshalPrivateKeyPEM(pair.PrivateKey, KEY_FORMAT_PKCS8, passphrase)
+}
+
+// 转换为 tls.Certificate
+func (pair *CertificateKeyPair) TLSCertificate() (tls.Certificate, error) {
+	if pair == nil || pair.Certificate == nil || pair.PrivateKey == nil {
+		return tls.Certificate{}, EncryptErrInvalidCertificate
+	}
+
+	cert := tls.Certificate{
+		Certificate: [][]byte{pair.Certificate.Raw},
+		PrivateKey:  pair.PrivateKey,
+		Leaf:        pair.Certificate,
+	}
+	for _, c := range pair.Chain {
+		cert.Certificate = append(cert.Certificate, c.Raw)
+	}
+
+	return cert, nil
+}
+
+/**
+ * 保存证书及私钥，证书文件包含证书链，私钥文件权限为 0600
+ * @param passphrase []byte 私钥口令，为 nil 时不加密
+ */
+func (pair *CertificateKeyPair) SaveFiles(certPath, keyPath string, passphrase []byte) error {
+	keyData, err := pair.PrivateKeyPEM(passphrase)
+	if err != nil {
+		return err
+	}
+
+	if err = ioutil.WriteFile(certPath, pair.FullChainPEM(), 0644); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(keyPath, keyData, 0600)
+}
+
+/*********************** 证书解析 ********************/
+
+// 解析证书，支持包含多个证书的 PEM 或单个 DER 编码的证书
+func ParseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+
+	rest := data
+	for {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+		if block.Type != kPEMTypeCertificate {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+
+	if len(certs) > 0 {
+		return certs, nil
+	}
+
+	cert, err := x509.ParseCertificate(data)
+	if err != nil {
+		return nil, EncryptErrInvalidCertificate
+	}
+
+	return []*x509.Certificate{cert}, nil
+}
+
+// 从文件加载证书，文件内容格式同 ParseCertificates
+func LoadCertificateFile(filePath string) ([]*x509.Certificate, error) {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCertificates(data)
+}
+
+/**
+ * 验证证书链
+ * @param chain []*x509.Certificate 终端证书在前，其后为中间证书
+ * @param roots []*x509.Certificate 信任的根证书，为空时使用系统根证书
+ * @param dnsName string 需要匹配的主机名，为空时不检查
+ * @return [][]*x509.Certificate 所有验证通过的证书链，从终端证书到根证书
+ */
+func VerifyCertificateChain(chain []*x509.Certificate, roots []*x509.Certificate, dnsName string) ([][]*x509.Certificate, error) {
+	if len(chain) == 0 {
+		return nil, EncryptErrInvalidCertificate
+	}
+
+	opts := x509.VerifyOptions{
+		DNSName:       dnsName,
+		Intermediates: x509.NewCertPool(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}
+
+	if len(roots) > 0 {
+		opts.Roots = x509.NewCertPool()
+		for _, cert := range roots {
+			opts.Roots.AddCert(cert)
+		}
+	}
+
+	for _, cert := range chain[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+
+	return chain[0].Verify(opts)
+}
+
+// 证书摘要信息
+type CertificateInfo struct {
+	Subject           string
+	Issuer            string
+	SerialNumber      string
+	NotBefore         time.Time
+	NotAfter          time.Time
+	IsCA              bool
+	SelfSigned        bool
+	DNSNames          []string
+	IPAddresses       []string
+	EmailAddresses    []string
+	URIs              []string
+	KeyAlgorithm      string
+	KeyUsage          []string
+	ExtKeyUsage       []string
+	SHA256Fingerprint string
+}
+
+// 提取证书摘要信息
+func NewCertificateInfo(cert *x509.Certificate) *CertificateInfo {
+	info := &CertificateInfo{
+		Subject:           cert.Subject.String(),
+		Issuer:            cert.Issuer.String(),
+		SerialNumber:      strings.ToUpper(cert.SerialNumber.Text(16)),
+		NotBefore:         cert.NotBefore,
+		NotAfter:          cert.NotAfter,
+		IsCA:              cert.IsCA,
+		SelfSigned:        isSelfSigned(cert),
+		DNSNames:          cert.DNSNames,
+		EmailAddresses:    cert.EmailAddresses,
+		KeyAlgorithm:      describePublicKey(cert.PublicKey),
+		KeyUsage:          describeKeyUsage(cert.KeyUsage),
+		ExtKeyUsage:       describeExtKeyUsage(cert.ExtKeyUsage),
+		SHA256Fingerprint: certificateFingerprint(cert),
+	}
+
+	for _, ip := range cert.IPAddresses {
+		info.IPAddresses = append(info.IPAddresses, ip.String())
+	}
+	for _, uri := range cert.URIs {
+		info.URIs = append(info.URIs, uri.String())
+	}
+
+	return info
+}
+
+// 解析并提取证书链中每个证书的摘要信息，格式同 ParseCertificates
+func InspectCertificates(data []byte) ([]*CertificateInfo, error) {
+	certs, err := ParseCertificates(data)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]*CertificateInfo, len(certs))
+	for i, cert := range certs {
+		infos[i] = NewCertificateInfo(cert)
+	}
+
+	return infos, nil
+}
+
+// 证书在 t 时刻是否处于有效期内
+func (info *CertificateInfo) ValidAt(t time.Time) bool {
+	return !t.Before(info.NotBefore) && !t.After(info.NotAfter)
+}
+
+// 生成可读的证书摘要
+func (info *CertificateInfo) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Subject:     %s\n", info.Subject)
+	fmt.Fprintf(&b, "Issuer:      %s\n", info.Issuer)
+	fmt.Fprintf(&b, "Serial:      %s\n", info.SerialNumber)
+	fmt.Fprintf(&b, "Validity:    %s - %s\n", info.NotBefore.Format(time.RFC3339), info.NotAfter.Format(time.RFC3339))
+	fmt.Fprintf(&b, "Key:         %s\n", info.KeyAlgorithm)
+	fmt.Fprintf(&b, "CA:          %t (self-signed: %t)\n", info.IsCA, info.SelfSigned)
+
+	sans := append(append(append(append([]string{}, info.DNSNames...), info.IPAddresses...), info.EmailAddresses...), info.URIs...)
+	if len(sans) > 0 {
+		fmt.Fprintf(&b, "SAN:         %s\n", strings.Join(sans, ", "))
+	}
+	if len(info.KeyUsage) > 0 {
+		fmt.Fprintf(&b, "Key usage:   %s\n", strings.Join(info.KeyUsage, ", "))
+	}
+	if len(info.ExtKeyUsage) > 0 {
+		fmt.Fprintf(&b, "Ext usage:   %s\n", strings.Join(info.ExtKeyUsage, ", "))
+	}
+	fmt.Fprintf(&b, "SHA-256:     %s\n", info.SHA256Fingerprint)
+
+	return b.String()
+}
+
+/*********************** 内部函数 ********************/
+
+func newCertificateTemplate(opt *CertificateOption, defaultValidFor time.Duration) (*x509.Certificate, crypto.Signer, error) {
+	key := opt.Key
+	if key == nil {
+		ecKey, err := GenerateECDSAKey(elliptic.P256())
+		if err != nil {
+			return nil, nil, err
+		}
+		key = ecKey
+	}
+
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), kCertificateSerialNumBits))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	notBefore := opt.NotBefore
+	if notBefore.IsZero() {
+		notBefore = time.Now().Add(-kCertificateClockSkew)
+	}
+
+	validFor := opt.ValidFor
+	if validFor <= 0 {
+		validFor = defaultValidFor
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject: pkix.Name{
+			CommonName:         opt.CommonName,
+			Organization:       opt.Organization,
+			OrganizationalUnit: opt.OrganizationalUnit,
+		},
+		NotBefore:      notBefore,
+		NotAfter:       notBefore.Add(validFor),
+		EmailAddresses: opt.EmailAddresses,
+	}
+
+	for _, rawURI := range opt.URIs {
+		uri, err := url.Parse(rawURI)
+		if err != nil {
+			return nil, nil, err
+		}
+		template.URIs = append(template.URIs, uri)
+	}
+
+	return template, key, nil
+}
+
+func createCertificate(template, parent *x509.Certificate, pub crypto.PublicKey, signer crypto.Signer) (*x509.Certificate, error) {
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, pub, signer)
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParseCertificate(der)
+}
+
+func isSelfSigned(cert *x509.Certificate) bool {
+	return bytes.Equal(cert.RawIssuer, cert.RawSubject) && cert.CheckSignatureFrom(cert) == nil
+}
+
+func publicKeyEqual(a, b crypto.PublicKey) bool {
+	key, ok := a.(interface{ Equal(crypto.PublicKey) bool })
+	return ok && key.Equal(b)
+}
+
+func certificateFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func describePublicKey(key crypto.PublicKey) string {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA %d", k.N.BitLen())
+	case *ecdsa.PublicKey:
+		return fmt.Sprintf("ECDSA %s", k.Curve.Params().Name)
+	case ed25519.PublicKey:
+		return "Ed25519"
+	default:
+		return fmt.Sprintf("%T", key)
+	}
+}
+
+var certificateKeyUsageNames = []struct {
+	usage x509.KeyUsage
+	name  string
+}{
+	{x509.KeyUsageDigitalSignature, "DigitalSignature"},
+	{x509.KeyUsageContentCommitment, "ContentCommitment"},
+	{x509.KeyUsageKeyEncipherment, "KeyEncipherment"},
+	{x509.KeyUsageDataEncipherment, "DataEncipherment"},
+	{x509.KeyUsageKeyAgreement, "KeyAgreement"},
+	{x509.KeyUsageCertSign, "CertSign"},
+	{x509.KeyUsageCRLSign, "CRLSign"},
+	{x509.KeyUsageEncipherOnly, "EncipherOnly"},
+	{x509.KeyUsageDecipherOnly, "DecipherOnly"},
+}
+
+func describeKeyUsage(usage x509.KeyUsage) []string {
+	var names []string
+	for _, u := range certificateKeyUsageNames {
+		if usage&u.usage != 0 {
+			names = append(names, u.name)
+		}
+	}
+	return names
+}
+
+var certificateExtKeyUsageNames = map[x509.ExtKeyUsage]string{
+	x509.ExtKeyUsageAny:             "Any",
+	x509.ExtKeyUsageServerAuth:      "ServerAuth",
+	x509.ExtKeyUsageClientAuth:      "ClientAuth",
+	x509.ExtKeyUsageCodeSigning:     "CodeSigning",
+	x509.ExtKeyUsageEmailProtection: "EmailProtection",
+	x509.ExtKeyUsageTimeStamping:    "TimeStamping",
+	x509.ExtKeyUsageOCSPSigning:     "OCSPSigning",
+}
+
+func describeExtKeyUsage(usages []x509.ExtKeyUsage) []string {
+	var names []string
+	for _, u := range usages {
+		if name, ok := certificateExtKeyUsageNames[u]; ok {
+			names = append(names, name)
+		} else {
+			names = append(names, fmt.Sprintf("Unknown(%d)", u))
+		}
+	}
+	return names
+}
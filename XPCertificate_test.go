@@ -0,0 +1,175 @@
+package XPSuperKit
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func certificateTestCA(t *testing.T, maxPathLen int) *XPCertificateAuthorityImpl {
+	ca, err := NewCertificateAuthority(&CertificateOption{CommonName: "Test Root CA", MaxPathLen: maxPathLen})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ca
+}
+
+// 由 parent 签发中间 CA
+func certificateTestIntermediate(t *testing.T, parent *XPCertificateAuthorityImpl) *XPCertificateAuthorityImpl {
+	template, key, err := newCertificateTemplate(&CertificateOption{CommonName: "Test Intermediate CA"}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template.IsCA = true
+	template.BasicConstraintsValid = true
+	template.MaxPathLenZero = true
+	template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+
+	cert, err := createCertificate(template, parent.Certificate, key.Public(), parent.PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &XPCertificateAuthorityImpl{CertificateKeyPair{Certificate: cert, PrivateKey: key}}
+}
+
+func TestCertificateIssueAndVerifyChain(t *testing.T) {
+	root := certificateTestCA(t, 1)
+	intermediate := certificateTestIntermediate(t, root)
+
+	server, err := intermediate.IssueServerCertificate(&CertificateOption{CommonName: "localhost", Hosts: []string{"localhost", "127.0.0.1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(server.Chain) != 1 || !server.Chain[0].Equal(intermediate.Certificate) {
+		t.Fatalf("chain %v", server.Chain)
+	}
+	if server.Certificate.IsCA || len(server.Certificate.IPAddresses) != 1 || server.Certificate.DNSNames[0] != "localhost" {
+		t.Fatalf("server certificate %+v", NewCertificateInfo(server.Certificate))
+	}
+	if server.Certificate.NotAfter.After(intermediate.Certificate.NotAfter) {
+		t.Fatal("server certificate outlives its issuer")
+	}
+
+	chain := append([]*x509.Certificate{server.Certificate}, server.Chain...)
+	roots := []*x509.Certificate{root.Certificate}
+
+	verified, err := VerifyCertificateChain(chain, roots, "localhost")
+	if err != nil || len(verified) != 1 || len(verified[0]) != 3 {
+		t.Fatalf("%v, %v", verified, err)
+	}
+	if _, err := VerifyCertificateChain(chain, roots, "127.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := VerifyCertificateChain(chain, roots, "other.example"); err == nil {
+		t.Fatal("wrong host name accepted")
+	}
+	if _, err := VerifyCertificateChain(chain[:1], roots, "localhost"); err == nil {
+		t.Fatal("chain without intermediate accepted")
+	}
+	if _, err := VerifyCertificateChain(chain, []*x509.Certificate{certificateTestCA(t, 1).Certificate}, "localhost"); err == nil {
+		t.Fatal("untrusted root accepted")
+	}
+	if _, err := VerifyCertificateChain(nil, roots, ""); err != EncryptErrInvalidCertificate {
+		t.Fatalf("empty chain: %v", err)
+	}
+
+	// MaxPathLen 为 0 的 CA 不能签发中间 CA
+	leafOnly := certificateTestCA(t, 0)
+	below := certificateTestIntermediate(t, leafOnly)
+	client, _ := below.IssueClientCertificate(&CertificateOption{CommonName: "client"})
+	if _, err := VerifyCertificateChain(append([]*x509.Certificate{client.Certificate}, client.Chain...), []*x509.Certificate{leafOnly.Certificate}, ""); err == nil {
+		t.Fatal("path length constraint ignored")
+	}
+
+	infos, err := InspectCertificates(append(server.FullChainPEM(), root.CertificatePEM()...))
+	if err != nil || len(infos) != 3 || infos[0].IsCA || !infos[1].IsCA || infos[1].SelfSigned || !infos[2].SelfSigned {
+		t.Fatalf("%v, %v", infos, err)
+	}
+	if !infos[0].ValidAt(time.Now()) || infos[0].ValidAt(time.Now().Add(2*time.Hour)) {
+		t.Fatalf("validity %v - %v", infos[0].NotBefore, infos[0].NotAfter)
+	}
+}
+
+func TestCertificateMutualTLS(t *testing.T) {
+	ca := certificateTestCA(t, 0)
+	server, err := ca.IssueServerCertificate(&CertificateOption{Hosts: []string{"127.0.0.1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, _ := GenerateRSAKey(2048)
+	client, err := ca.IssueClientCertificate(&CertificateOption{CommonName: "client", Key: rsaKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serverConfig, _ := ca.ServerTLSConfig(server, true)
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.TLS.PeerCertificates[0].Subject.CommonName)
+	}))
+	ts.TLS = serverConfig
+	ts.StartTLS()
+	defer ts.Close()
+
+	clientConfig, _ := ca.ClientTLSConfig(client)
+	resp, err := (&http.Client{Transport: &http.Transport{TLSClientConfig: clientConfig}}).Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "client" {
+		t.Fatalf("peer %q", body)
+	}
+
+	anonymous, _ := ca.ClientTLSConfig(nil)
+	if _, err := (&http.Client{Transport: &http.Transport{TLSClientConfig: anonymous}}).Get(ts.URL); err == nil {
+		t.Fatal("request without client certificate accepted")
+	}
+
+	other, _ := certificateTestCA(t, 0).IssueClientCertificate(&CertificateOption{CommonName: "other"})
+	if _, err := (&http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: ca.CertPool(), Certificates: mustTLSCertificate(t, other)}}}).Get(ts.URL); err == nil {
+		t.Fatal("client certificate from another CA accepted")
+	}
+}
+
+func TestCertificateSaveLoad(t *testing.T) {
+	ca := certificateTestCA(t, 0)
+	dir := t.TempDir()
+	certPath, keyPath := filepath.Join(dir, "ca.pem"), filepath.Join(dir, "ca.key")
+
+	if err := ca.SaveFiles(certPath, keyPath, []byte("pw")); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadCertificateAuthority(certPath, keyPath, []byte("pw"))
+	if err != nil || !loaded.Certificate.Equal(ca.Certificate) {
+		t.Fatal(err)
+	}
+	if _, err := LoadCertificateAuthority(certPath, keyPath, nil); err == nil {
+		t.Fatal("encrypted key loaded without passphrase")
+	}
+
+	leaf, _ := ca.IssuePeerCertificate(&CertificateOption{CommonName: "peer"})
+	if err := leaf.SaveFiles(filepath.Join(dir, "leaf.pem"), filepath.Join(dir, "leaf.key"), nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadCertificateAuthority(filepath.Join(dir, "leaf.pem"), filepath.Join(dir, "leaf.key"), nil); err != EncryptErrNotCA {
+		t.Fatalf("leaf as CA: %v", err)
+	}
+	if _, err := LoadCertificateKeyPair(certPath, filepath.Join(dir, "leaf.key"), nil); err != EncryptErrKeyMismatch {
+		t.Fatalf("mismatched key: %v", err)
+	}
+}
+
+func mustTLSCertificate(t *testing.T, pair *CertificateKeyPair) []tls.Certificate {
+	cert, err := pair.TLSCertificate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []tls.Certificate{cert}
+}
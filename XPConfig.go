@@ -1,17 +1,28 @@
 package XPSuperKit
 
 import (
+	"errors"
 	"os"
+	"reflect"
 	"regexp"
+	"sync"
 )
 
 type XPConfigImpl struct {
 	*XPConfigEnvironment
+	provenance sync.Map //配置结构体指针 => *configSources
 }
 
 type XPConfigEnvironment struct {
 	Environment       string
 	EnvironmentPrefix string
+	MergeSlice        MergeSliceStrategy //多个配置文件中的切片的合并方式，默认后加载的文件中出现的切片替换之前的值
+	MergeMap          MergeMapStrategy   //多个配置文件中的 map 的合并方式，默认按 key 深度合并
+	Flags             bool               //Load 时解析命令行参数，优先级高于环境变量
+	Args              []string           //解析的命令行参数，为 nil 时使用 os.Args[1:]
+	EnvFiles          []string           //Load 时读入环境变量的 .env 文件，为空时不读取
+	MasterKey         string             //解密 enc: 配置值的 base64 编码的主密钥，为空时使用 XPCONFIG_MASTER_KEY 环境变量
+	ResolveSecrets    bool               //解析全部字符串中的 ${VAR} 及 enc:，为 false 时只解析 secret 标签为 resolve 的字段
 }
 
 func NewXPConfig(configEnv *XPConfigEnvironment) *XPConfigImpl {
@@ -37,16 +48,66 @@ func (cfg *XPConfigImpl) GetEnvironment() string {
 	return cfg.Environment
 }
 
+/**
+ * 加载配置，优先级从低到高依次为：
+ * 默认值(default 标签) < 配置文件 < 环境配置文件(config.production.yml) < 本地配置文件(config.local.yml) < 环境变量 < 命令行参数(Flags 为 true 时)
+ * 传入多个文件时，靠前的文件优先级更高
+ * 每个配置文件分别解析后合并，文件中出现的键覆盖之前的值，包括 false、0 等零值，未出现的键保持不变，
+ * 切片及 map 按 MergeSlice、MergeMap 合并；通过 RegisterConfigDecoder 注册的格式无法判断出现的键，按 MergeWithConfig 合并，忽略空值
+ * 命令行参数包含 -h 或 --help 时打印参数说明并返回 flag.ErrHelp
+ * 每个字段的来源会被记录，可通过 Sources、Explain 及 Dump 查看
+ * 处理环境变量前先读取 .env 文件，全部加载完成后解析字符串中的 ${VAR}、file:// 及 enc: 引用
+ * @param config interface{} 结构体指针
+ * @param files ...string 配置文件
+ */
 func (cfg *XPConfigImpl) Load(config interface{}, files ...string) error {
+	configValue := reflect.ValueOf(config)
+	if configValue.Kind() != reflect.Ptr || configValue.Elem().Kind() != reflect.Struct {
+		return errors.New("invalid config, should be pointer to struct")
+	}
+
+	sources := cfg.resetConfigSources(config)
+	mergeConfig := &MergeConfig{Overwrite: true, Slice: cfg.MergeSlice, Map: cfg.MergeMap, DeepPointers: true}
 	for _, file := range cfg.getConfigurationFiles(files...) {
-		if err := processFile(config, file); err != nil {
+		layer := reflect.New(configValue.Elem().Type())
+		values, format, err := processFile(layer.Interface(), file)
+		if err != nil {
+			return err
+		}
+		recordConfigFile(sources, layer.Elem(), values, format, file)
+
+		if values == nil {
+			err = MergeWithConfig(config, layer.Interface(), mergeConfig)
+		} else {
+			err = mergeConfigLayer(configValue.Elem(), layer.Elem(), values, format, mergeConfig)
+		}
+		if err != nil {
 			return err
 		}
 	}
 
+	if err := cfg.processEnvFiles(); err != nil {
+		return err
+	}
+
+	var err error
 	if prefix := cfg.getEnvironmentPrefix(config); prefix == "-" {
-		return processTags(config)
+		err = processTags(config, sources, "")
 	} else {
-		return processTags(config, prefix)
+		err = processTags(config, sources, "", prefix)
 	}
+
+	if err != nil {
+		return err
+	}
+
+	if err := cfg.processFlags(config); err != nil {
+		return err
+	}
+
+	if err := cfg.processSecrets(config); err != nil {
+		return err
+	}
+
+	return ValidateConfig(config)
 }
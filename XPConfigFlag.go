@@ -0,0 +1,308 @@
+package XPSuperKit
+
+import (
+	"bytes"
+	"encoding"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+)
+
+/*********调用示例********
+type Config struct {
+	Debug bool `description:"enable debug log"`
+	DB    struct {
+		Host string `default:"localhost" description:"database host"` // --db-host
+		Port int    `default:"3306" flag:"port"`                      // --port
+	}
+	Secret string `flag:"-"` // 不生成命令行参数
+}
+
+cfg := XPSuperKit.NewXPConfig(&XPSuperKit.XPConfigEnvironment{Flags: true})
+err := cfg.Load(&config, "config.yml")
+if err == flag.ErrHelp {
+	os.Exit(0)
+}
+
+// 或者手动解析，返回非参数部分
+args, err := cfg.ParseFlags(&config, os.Args[1:])
+
+// 打印参数说明
+cfg.PrintUsage(&config, os.Stderr)
+ ************************/
+
+const (
+	kConfigFlagTag        = "flag"
+	kConfigDescriptionTag = "description"
+)
+
+var (
+	configDurationType        = reflect.TypeOf(time.Duration(0))
+	configTextUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// 由配置结构体字段生成的命令行参数
+type configFlag struct {
+	name        string
+	index       []int //字段在配置结构体中的位置，经过的空指针在设置时创建
+	fieldType   reflect.Type
+	defValue    string
+	description string
+	path        []string
+	env         string //对应的环境变量
+	fieldPath   string //字段路径，用于记录来源
+	root        reflect.Value
+	sources     *configSources
+}
+
+/**
+ * 解析命令行参数并写入配置，参数名为 flag 标签或由字段路径生成，如 DB.Host 对应 --db-host
+ * 参数值的格式与环境变量相同，切片可使用 --tags a,b 或 YAML 格式，map 使用 YAML 格式
+ * 出现 -h 或 --help 时打印参数说明并返回 flag.ErrHelp
+ * @param config interface{} 结构体指针
+ * @param args []string 命令行参数，不包含程序名
+ * @return []string 非参数部分
+ */
+func (cfg *XPConfigImpl) ParseFlags(config interface{}, args []string) ([]string, error) {
+	flagSet, err := cfg.flagSet(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := flagSet.Parse(args); err != nil {
+		return nil, err
+	}
+
+	return flagSet.Args(), nil
+}
+
+// 打印由配置结构体生成的命令行参数说明，包括类型、默认值、对应的环境变量及 description 标签
+func (cfg *XPConfigImpl) PrintUsage(config interface{}, w io.Writer) error {
+	flags, err := cfg.configFlags(config)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("Options:\n")
+	for _, f := range flags {
+		buf.WriteString("  --" + f.name)
+		if typeName := configFlagTypeName(f.fieldType); typeName != "" {
+			buf.WriteString(" " + typeName)
+		}
+		buf.WriteString("\n    \t")
+
+		if f.description != "" {
+			buf.WriteString(f.description + " ")
+		}
+		if f.defValue != "" {
+			buf.WriteString(fmt.Sprintf("(default %q) ", f.defValue))
+		}
+		buf.WriteString("[$" + f.env + "]")
+		buf.WriteString("\n")
+	}
+
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// Load 中调用，Flags 为 true 时解析 Args，Args 为 nil 时使用 os.Args[1:]
+func (cfg *XPConfigImpl) processFlags(config interface{}) error {
+	if !cfg.Flags {
+		return nil
+	}
+
+	args := cfg.Args
+	if args == nil && len(os.Args) > 1 {
+		args = os.Args[1:]
+	}
+
+	_, err := cfg.ParseFlags(config, args)
+	return err
+}
+
+func (cfg *XPConfigImpl) flagSet(config interface{}) (*flag.FlagSet, error) {
+	flags, err := cfg.configFlags(config)
+	if err != nil {
+		return nil, err
+	}
+
+	flagSet := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	flagSet.SetOutput(ioutil.Discard)
+	flagSet.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
+		cfg.PrintUsage(config, os.Stderr)
+	}
+
+	sources := cfg.configSources(config)
+	for _, f := range flags {
+		f.sources = sources
+		if flagSet.Lookup(f.name) != nil {
+			return nil, fmt.Errorf("duplicate config flag --%v", f.name)
+		}
+		flagSet.Var(f, f.name, f.description)
+	}
+
+	return flagSet, nil
+}
+
+func (cfg *XPConfigImpl) configFlags(config interface{}) ([]*configFlag, error) {
+	configValue := reflect.ValueOf(config)
+	if configValue.Kind() != reflect.Ptr || configValue.Elem().Kind() != reflect.Struct {
+		return nil, ErrorN("invalid config, should be pointer to struct")
+	}
+
+	var flags []*configFlag
+	collectConfigFlags(configValue.Elem(), configValue.Elem().Type(), nil, "", nil, map[reflect.Type]bool{}, &flags)
+
+	// 与 processTags 中的环境变量名一致
+	for _, f := range flags {
+		if f.env != "" {
+			continue
+		}
+		if prefix := cfg.getEnvironmentPrefix(config); prefix != "-" {
+			f.env = strings.ToUpper(strings.Join(append([]string{prefix}, f.path...), "_"))
+		} else {
+			f.env = strings.ToUpper(strings.Join(f.path, "_"))
+		}
+	}
+
+	return flags, nil
+}
+
+func collectConfigFlags(root reflect.Value, structType reflect.Type, index []int, path string, prefixes []string, visiting map[reflect.Type]bool, flags *[]*configFlag) {
+	if visiting[structType] {
+		return
+	}
+	visiting[structType] = true
+	defer delete(visiting, structType)
+
+	for i := 0; i < structType.NumField(); i++ {
+		fieldStruct := structType.Field(i)
+		if fieldStruct.PkgPath != "" {
+			continue
+		}
+
+		name := fieldStruct.Tag.Get(kConfigFlagTag)
+		if name == "-" {
+			continue
+		}
+
+		fieldIndex := append(append([]int{}, index...), i)
+		fieldType := fieldStruct.Type
+		for fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+
+		if fieldType.Kind() == reflect.Struct && !reflect.PtrTo(fieldType).Implements(configTextUnmarshalerType) {
+			collectConfigFlags(root, fieldType, fieldIndex, joinConfigPath(path, fieldStruct.Name), getPrefixForStruct(prefixes, &fieldStruct), visiting, flags)
+			continue
+		}
+
+		switch fieldType.Kind() {
+		case reflect.Func, reflect.Chan, reflect.Interface, reflect.UnsafePointer, reflect.Complex64, reflect.Complex128:
+			continue
+		}
+
+		if name == "" {
+			name = configFlagName(append(prefixes, fieldStruct.Name))
+		}
+
+		*flags = append(*flags, &configFlag{
+			name:        name,
+			index:       fieldIndex,
+			fieldType:   fieldType,
+			defValue:    fieldStruct.Tag.Get("default"),
+			description: fieldStruct.Tag.Get(kConfigDescriptionTag),
+			path:        append(append([]string{}, prefixes...), fieldStruct.Name),
+			env:         fieldStruct.Tag.Get("env"),
+			fieldPath:   joinConfigPath(path, fieldStruct.Name),
+			root:        root,
+		})
+	}
+}
+
+// DB.Host => db-host，HTTPServer.MaxConns => http-server-max-conns
+func configFlagName(names []string) string {
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		runes := []rune(name)
+		var word []rune
+		for i, r := range runes {
+			if i > 0 && unicode.IsUpper(r) {
+				prev := runes[i-1]
+				nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+				if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextIsLower) {
+					word = append(word, '-')
+				}
+			}
+			word = append(word, unicode.ToLower(r))
+		}
+		parts = append(parts, string(word))
+	}
+	return strings.Join(parts, "-")
+}
+
+func configFlagTypeName(fieldType reflect.Type) string {
+	if fieldType == configDurationType {
+		return "duration"
+	}
+
+	switch fieldType.Kind() {
+	case reflect.Bool:
+		return ""
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "int"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "uint"
+	case reflect.Float32, reflect.Float64:
+		return "float"
+	case reflect.Slice, reflect.Array:
+		return "list"
+	case reflect.Map:
+		return "map"
+	}
+	return "string"
+}
+
+// 定位字段，路径上的空指针会被创建
+func (f *configFlag) field() reflect.Value {
+	value := f.root
+	for _, i := range f.index {
+		for value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				value.Set(reflect.New(value.Type().Elem()))
+			}
+			value = value.Elem()
+		}
+		value = value.Field(i)
+	}
+	return value
+}
+
+// flag.Value
+func (f *configFlag) String() string {
+	return f.defValue
+}
+
+// flag.Value，格式与环境变量相同，见 setConfigScalar
+func (f *configFlag) Set(value string) error {
+	field := f.field()
+	if err := setConfigScalar(field, value); err != nil {
+		return err
+	}
+
+	f.sources.record(f.fieldPath, ConfigSource{Kind: CONFIG_SOURCE_FLAG, Name: f.name, Value: reflect.Indirect(field).Interface()})
+	return nil
+}
+
+// flag 包据此允许 --debug 省略参数值
+func (f *configFlag) IsBoolFlag() bool {
+	return f.fieldType.Kind() == reflect.Bool
+}
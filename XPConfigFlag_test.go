@@ -0,0 +1,122 @@
+package XPSuperKit
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type flagTestConfig struct {
+	Debug bool `description:"enable debug"`
+	DB    *struct {
+		Host string `default:"localhost" description:"database host"`
+		Port int    `default:"3306" flag:"port"`
+	}
+	HTTPServer struct {
+		MaxConns int
+		Timeout  time.Duration
+	}
+	Tags   []string
+	Limits map[string]int
+	Secret string `flag:"-"`
+	When   time.Time
+}
+
+func TestConfigFlagName(t *testing.T) {
+	for want, names := range map[string][]string{
+		"db-host":               {"DB", "Host"},
+		"http-server-max-conns": {"HTTPServer", "MaxConns"},
+		"user-id":               {"UserID"},
+		"port":                  {"port"},
+	} {
+		if got := configFlagName(names); got != want {
+			t.Fatalf("%v: %v, want %v", names, got, want)
+		}
+	}
+}
+
+func TestConfigFlags(t *testing.T) {
+	var c flagTestConfig
+	cfg := NewXPConfig(&XPConfigEnvironment{Flags: true, EnvironmentPrefix: "-", Args: []string{
+		"--debug", "--db-host", "db1", "--port=1", "--http-server-max-conns", "5", "--http-server-timeout", "3s",
+		"--tags", "[a, b]", "--limits", "{a: 1}", "--when", "2020-01-02T00:00:00Z", "rest",
+	}})
+	if err := cfg.Load(&c); err != nil {
+		t.Fatal(err)
+	}
+	if !c.Debug || c.DB.Host != "db1" || c.DB.Port != 1 || c.HTTPServer.MaxConns != 5 || c.HTTPServer.Timeout != 3*time.Second ||
+		len(c.Tags) != 2 || c.Limits["a"] != 1 || c.When.Year() != 2020 {
+		t.Fatalf("%+v %+v", c, c.DB)
+	}
+	if s := cfg.Sources(&c, "DB.Port"); len(s) == 0 || s[len(s)-1].Kind != CONFIG_SOURCE_FLAG || s[len(s)-1].Name != "port" {
+		t.Fatal(s)
+	}
+
+	args, err := cfg.ParseFlags(&c, []string{"--port", "2", "x"})
+	if err != nil || len(args) != 1 || args[0] != "x" || c.DB.Port != 2 {
+		t.Fatal(args, err)
+	}
+	for _, args := range [][]string{{"--secret", "x"}, {"--nope"}, {"--port", "x"}, {"--http-server-timeout", "3 parsecs"}} {
+		if _, err := cfg.ParseFlags(&c, args); err == nil {
+			t.Fatalf("%v accepted", args)
+		}
+	}
+	if _, err := cfg.ParseFlags(&c, []string{"-h"}); err != flag.ErrHelp {
+		t.Fatal(err)
+	}
+}
+
+// 命令行参数的优先级高于环境变量及配置文件
+func TestConfigFlagsPrecedence(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "c.yml")
+	if err := os.WriteFile(file, []byte("db:\n  host: filehost\n  port: 10\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("FLAGT_DB_HOST", "envhost")
+	os.Setenv("FLAGT_DB_PORT", "20")
+	defer os.Unsetenv("FLAGT_DB_HOST")
+	defer os.Unsetenv("FLAGT_DB_PORT")
+
+	var c flagTestConfig
+	cfg := NewXPConfig(&XPConfigEnvironment{Flags: true, EnvironmentPrefix: "FLAGT", Args: []string{"--port", "30"}})
+	if err := cfg.Load(&c, file); err != nil {
+		t.Fatal(err)
+	}
+	if c.DB.Host != "envhost" || c.DB.Port != 30 {
+		t.Fatalf("%+v", c.DB)
+	}
+
+	// Flags 为 false 时不解析命令行参数
+	var d flagTestConfig
+	if err := NewXPConfig(&XPConfigEnvironment{EnvironmentPrefix: "FLAGT", Args: []string{"--port", "30"}}).Load(&d, file); err != nil || d.DB.Port != 20 {
+		t.Fatalf("%+v, %v", d.DB, err)
+	}
+}
+
+func TestConfigPrintUsage(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewXPConfig(&XPConfigEnvironment{EnvironmentPrefix: "-"}).PrintUsage(&flagTestConfig{}, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	usage := buf.String()
+	for _, want := range []string{
+		"  --debug\n    \tenable debug [$DEBUG]",
+		"  --db-host string\n    \tdatabase host (default \"localhost\") [$DB_HOST]",
+		"  --port int\n    \t(default \"3306\") [$DB_PORT]",
+		"  --http-server-timeout duration\n    \t[$HTTPSERVER_TIMEOUT]",
+		"  --tags list\n",
+		"  --limits map\n",
+	} {
+		if !strings.Contains(usage, want) {
+			t.Fatalf("usage missing %q:\n%v", want, usage)
+		}
+	}
+	if strings.Contains(usage, "secret") {
+		t.Fatalf("flag:\"-\" field listed:\n%v", usage)
+	}
+}
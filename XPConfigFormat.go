@@ -0,0 +1,479 @@
+package XPSuperKit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	"github.com/go-yaml/yaml"
+)
+
+/*********调用示例********
+// config.ini
+//   name = app
+//   [db]
+//   host = localhost
+//   port = 3306
+//
+// config.properties
+//   name=app
+//   db.host=localhost
+//   db.port=3306
+
+err := XPSuperKit.XPConfig().Load(&config, "config.ini")
+
+// 注册其他格式，扩展名忽略大小写
+XPSuperKit.RegisterConfigDecoder(".hcl", func(data []byte, config interface{}) error {
+	return hcl.Unmarshal(data, config)
+})
+ ************************/
+
+// 配置文件解码器，将 data 解码至结构体指针 config
+type ConfigDecoder func(data []byte, config interface{}) error
+
+// 配置文件解析错误，包含文件名及出错的行号
+type ConfigFormatError struct {
+	File   string
+	Line   int //出错的行号，无法确定时为 0
+	Format string
+	Err    error
+}
+
+func (e *ConfigFormatError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%v:%d: failed to decode %v config: %v", e.File, e.Line, e.Format, e.Err)
+	}
+	return fmt.Sprintf("%v: failed to decode %v config: %v", e.File, e.Format, e.Err)
+}
+
+var (
+	configDecoderLock sync.RWMutex
+	configDecoders    = map[string]ConfigDecoder{
+		".yaml":       yaml.Unmarshal,
+		".yml":        yaml.Unmarshal,
+		".toml":       toml.Unmarshal,
+		".json":       json.Unmarshal,
+		".ini":        DecodeIni,
+		".properties": DecodeProperties,
+	}
+
+	// 内置格式的原始解码器，解码为 map[string]interface{} 等原始值，用于判断文件中实际出现的键
+	// 内置格式被 RegisterConfigDecoder 替换后不再使用
+	configRawDecoders = map[string]func(data []byte) (interface{}, error){
+		".yaml":       decodeYamlRaw,
+		".yml":        decodeYamlRaw,
+		".toml":       decodeTomlRaw,
+		".json":       decodeJsonRaw,
+		".ini":        decodeIniRaw,
+		".properties": decodePropertiesRaw,
+	}
+
+	// 扩展名未注册时依次尝试的格式
+	configFallbackFormats = []string{".toml", ".json", ".yaml"}
+
+	configErrorLineRegexp = regexp.MustCompile(`(?i)line (\d+)`)
+)
+
+/**
+ * 注册配置文件解码器，已存在的扩展名会被替换
+ * @param ext string 扩展名，如 .hcl
+ * @param decoder ConfigDecoder 解码器，返回 *ConfigFormatError 时保留其行号
+ */
+func RegisterConfigDecoder(ext string, decoder ConfigDecoder) {
+	configDecoderLock.Lock()
+	defer configDecoderLock.Unlock()
+	configDecoders[normalizeConfigExt(ext)] = decoder
+	delete(configRawDecoders, normalizeConfigExt(ext))
+}
+
+func normalizeConfigExt(ext string) string {
+	ext = strings.ToLower(ext)
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
+
+func configDecoder(ext string) ConfigDecoder {
+	configDecoderLock.RLock()
+	defer configDecoderLock.RUnlock()
+	return configDecoders[normalizeConfigExt(ext)]
+}
+
+// 按扩展名选择解码器，未注册的扩展名依次尝试 TOML、JSON、YAML，全部失败时返回最后一个错误
+// 返回实际使用的扩展名
+func decodeConfigData(file string, data []byte, config interface{}) (string, error) {
+	if ext := path.Ext(file); ext != "" {
+		if decoder := configDecoder(ext); decoder != nil {
+			ext = normalizeConfigExt(ext)
+			return ext, newConfigFormatError(file, strings.TrimPrefix(ext, "."), data, decoder(data, config))
+		}
+	}
+
+	var err error
+	for _, ext := range configFallbackFormats {
+		layer := reflect.New(reflect.TypeOf(config).Elem()).Interface()
+		if err = configDecoder(ext)(data, layer); err == nil {
+			reflect.ValueOf(config).Elem().Set(reflect.ValueOf(layer).Elem())
+			return ext, nil
+		}
+		err = newConfigFormatError(file, strings.TrimPrefix(ext, "."), data, err)
+	}
+	return "", err
+}
+
+// 按扩展名将配置解码为原始值，map 的键均为字符串；没有原始解码器时 ok 为 false
+func decodeConfigRaw(ext string, data []byte) (raw interface{}, ok bool, err error) {
+	configDecoderLock.RLock()
+	decoder := configRawDecoders[normalizeConfigExt(ext)]
+	configDecoderLock.RUnlock()
+
+	if decoder == nil {
+		return nil, false, nil
+	}
+	raw, err = decoder(data)
+	return raw, true, err
+}
+
+func decodeYamlRaw(data []byte) (interface{}, error) {
+	var raw interface{}
+	err := yaml.Unmarshal(data, &raw)
+	return normalizeConfigRaw(raw), err
+}
+
+func decodeJsonRaw(data []byte) (interface{}, error) {
+	var raw interface{}
+	err := json.Unmarshal(data, &raw)
+	return raw, err
+}
+
+func decodeTomlRaw(data []byte) (interface{}, error) {
+	var table map[string]interface{}
+	err := toml.Unmarshal(data, &table)
+	return normalizeConfigRaw(table), err
+}
+
+func decodeIniRaw(data []byte) (interface{}, error) {
+	values, err := parseIniValues(data)
+	return configKeyValuesToRaw(values), err
+}
+
+func decodePropertiesRaw(data []byte) (interface{}, error) {
+	values, err := parsePropertiesValues(data)
+	return configKeyValuesToRaw(values), err
+}
+
+// 从解码器的错误中提取行号：yaml 及 toml 的错误信息中的 line N，json 错误的偏移量
+func newConfigFormatError(file, format string, data []byte, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if formatErr, ok := err.(*ConfigFormatError); ok {
+		formatErr.File = file
+		if formatErr.Format == "" {
+			formatErr.Format = format
+		}
+		return formatErr
+	}
+
+	line := 0
+	switch jsonErr := err.(type) {
+	case *json.SyntaxError:
+		line = configOffsetLine(data, jsonErr.Offset)
+	case *json.UnmarshalTypeError:
+		line = configOffsetLine(data, jsonErr.Offset)
+	default:
+		if match := configErrorLineRegexp.FindStringSubmatch(err.Error()); match != nil {
+			line, _ = strconv.Atoi(match[1])
+		}
+	}
+
+	return &ConfigFormatError{File: file, Line: line, Format: format, Err: err}
+}
+
+func configOffsetLine(data []byte, offset int64) int {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
+
+/*********************** INI / Properties ********************/
+
+// 配置文件中的一个值及其所在行
+type configKeyValue struct {
+	value string
+	line  int
+}
+
+/**
+ * 解码 INI 格式的配置
+ * [section] 对应同名的结构体字段，[a.b] 对应嵌套的字段，section 之前的键对应顶层字段
+ * 支持 key = value 及 key: value，; 及 # 开头的行为注释，值两端的引号会被去掉
+ */
+func DecodeIni(data []byte, config interface{}) error {
+	values, err := parseIniValues(data)
+	if err != nil {
+		return err
+	}
+
+	return decodeConfigValues(values, config, "ini")
+}
+
+// 解析 INI 内容为嵌套的 map，值为 configKeyValue
+func parseIniValues(data []byte) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	section := values
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == ';' || line[0] == '#' {
+			continue
+		}
+
+		if line[0] == '[' {
+			if !strings.HasSuffix(line, "]") {
+				return nil, &ConfigFormatError{Line: lineNumber, Format: "ini", Err: fmt.Errorf("invalid section %q", line)}
+			}
+
+			section = values
+			for _, name := range strings.Split(strings.TrimSpace(line[1:len(line)-1]), ".") {
+				section = configSubSection(section, strings.TrimSpace(name))
+			}
+			continue
+		}
+
+		pos := strings.IndexAny(line, "=:")
+		if pos <= 0 {
+			return nil, &ConfigFormatError{Line: lineNumber, Format: "ini", Err: fmt.Errorf("expected key = value, got %q", line)}
+		}
+
+		value := strings.TrimSpace(line[pos+1:])
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		section[strings.TrimSpace(line[:pos])] = configKeyValue{value: value, line: lineNumber}
+	}
+
+	return values, scanner.Err()
+}
+
+/**
+ * 解码 Java .properties 格式的配置，键中的 . 表示嵌套的字段，如 db.host
+ * 支持 key=value、key: value 及 key value，# 及 ! 开头的行为注释，行尾的 \ 表示续行，支持 \t \n \uXXXX 等转义
+ */
+func DecodeProperties(data []byte, config interface{}) error {
+	values, err := parsePropertiesValues(data)
+	if err != nil {
+		return err
+	}
+
+	return decodeConfigValues(values, config, "properties")
+}
+
+// 解析 properties 内容为嵌套的 map，值为 configKeyValue
+func parsePropertiesValues(data []byte) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		startLine := lineNumber
+		line := strings.TrimLeft(scanner.Text(), " \t\f")
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+
+		// 行尾奇数个 \ 表示续行
+		for strings.HasSuffix(line, "\\") && (len(line)-len(strings.TrimRight(line, "\\")))%2 == 1 && scanner.Scan() {
+			lineNumber++
+			line = line[:len(line)-1] + strings.TrimLeft(scanner.Text(), " \t\f")
+		}
+
+		key, value, err := splitPropertiesLine(line)
+		if err != nil {
+			return nil, &ConfigFormatError{Line: startLine, Format: "properties", Err: err}
+		}
+
+		names := strings.Split(key, ".")
+		section := values
+		for _, name := range names[:len(names)-1] {
+			section = configSubSection(section, name)
+		}
+		section[names[len(names)-1]] = configKeyValue{value: value, line: startLine}
+	}
+
+	return values, scanner.Err()
+}
+
+// 键与值以第一个未转义的 =、: 或空白分隔
+func splitPropertiesLine(line string) (key, value string, err error) {
+	end := len(line)
+	for i := 0; i < len(line); i++ {
+		if line[i] == '\\' {
+			i++
+			continue
+		}
+		if line[i] == '=' || line[i] == ':' || line[i] == ' ' || line[i] == '\t' {
+			end = i
+			break
+		}
+	}
+
+	rest := strings.TrimLeft(line[end:], " \t\f")
+	if strings.HasPrefix(rest, "=") || strings.HasPrefix(rest, ":") {
+		rest = strings.TrimLeft(rest[1:], " \t\f")
+	}
+
+	if key, err = unescapeProperties(line[:end]); err != nil {
+		return
+	}
+	value, err = unescapeProperties(rest)
+	return
+}
+
+func unescapeProperties(s string) (string, error) {
+	if !strings.Contains(s, "\\") {
+		return s, nil
+	}
+
+	var buf strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 >= len(s) {
+			buf.WriteByte(s[i])
+			continue
+		}
+
+		i++
+		switch s[i] {
+		case 't':
+			buf.WriteByte('\t')
+		case 'n':
+			buf.WriteByte('\n')
+		case 'r':
+			buf.WriteByte('\r')
+		case 'f':
+			buf.WriteByte('\f')
+		case 'u':
+			if i+5 > len(s) {
+				return "", fmt.Errorf("invalid unicode escape in %q", s)
+			}
+			code, err := strconv.ParseUint(s[i+1:i+5], 16, 32)
+			if err != nil {
+				return "", fmt.Errorf("invalid unicode escape in %q", s)
+			}
+			buf.WriteRune(rune(code))
+			i += 4
+		default:
+			buf.WriteByte(s[i])
+		}
+	}
+	return buf.String(), nil
+}
+
+func configSubSection(section map[string]interface{}, name string) map[string]interface{} {
+	if sub, ok := section[name].(map[string]interface{}); ok {
+		return sub
+	}
+	sub := make(map[string]interface{})
+	section[name] = sub
+	return sub
+}
+
+// 将 INI、properties 解析得到的嵌套 map 写入结构体，字段按 ini 标签、yaml 标签、json 标签或字段名匹配，忽略大小写及 - _
+func decodeConfigValues(values map[string]interface{}, config interface{}, format string) error {
+	configValue := reflect.ValueOf(config)
+	if configValue.Kind() != reflect.Ptr || configValue.Elem().Kind() != reflect.Struct {
+		return ErrorN("invalid config, should be pointer to struct")
+	}
+	return assignConfigValues(configValue.Elem(), values, format)
+}
+
+func assignConfigValues(target reflect.Value, values map[string]interface{}, format string) error {
+	for target.Kind() == reflect.Ptr {
+		if target.IsNil() {
+			target.Set(reflect.New(target.Type().Elem()))
+		}
+		target = target.Elem()
+	}
+
+	switch target.Kind() {
+	case reflect.Struct:
+		fields := configFieldsByKey(target.Type())
+		for key, value := range values {
+			index, ok := fields[normalizeConfigKey(key)]
+			if !ok {
+				continue
+			}
+			if err := assignConfigValue(target.FieldByIndex(index), value, format); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if target.IsNil() {
+			target.Set(reflect.MakeMap(target.Type()))
+		}
+		for key, value := range values {
+			mapKey := reflect.New(target.Type().Key()).Elem()
+			if err := setConfigScalar(mapKey, key); err != nil {
+				return err
+			}
+			element := reflect.New(target.Type().Elem()).Elem()
+			if err := assignConfigValue(element, value, format); err != nil {
+				return err
+			}
+			target.SetMapIndex(mapKey, element)
+		}
+	default:
+		return fmt.Errorf("cannot decode section into %v", target.Type())
+	}
+
+	return nil
+}
+
+func assignConfigValue(field reflect.Value, value interface{}, format string) error {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		return assignConfigValues(field, value, format)
+	case configKeyValue:
+		if err := setConfigScalar(field, value.value); err != nil {
+			return &ConfigFormatError{Line: value.line, Format: format, Err: err}
+		}
+	}
+	return nil
+}
+
+// 结构体字段可被匹配的键名 => 字段位置
+func configFieldsByKey(structType reflect.Type) map[string][]int {
+	fields := make(map[string][]int)
+	for i := 0; i < structType.NumField(); i++ {
+		fieldStruct := structType.Field(i)
+		if fieldStruct.PkgPath != "" {
+			continue
+		}
+
+		names := configKeyNames(&fieldStruct)
+		if name := strings.Split(fieldStruct.Tag.Get("ini"), ",")[0]; name != "" && name != "-" {
+			names = append(names, name)
+		}
+		for _, name := range names {
+			fields[normalizeConfigKey(name)] = fieldStruct.Index
+		}
+	}
+	return fields
+}
+
+func normalizeConfigKey(key string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", "_", "").Replace(key))
+}
+
+/*********************** INI / Properties ********************/
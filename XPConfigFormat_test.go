@@ -0,0 +1,117 @@
+package XPSuperKit
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type formatTestConfig struct {
+	Name    string
+	Debug   bool
+	Tags    []string
+	Timeout time.Duration
+	DB      struct {
+		Host    string
+		Port    int
+		MaxConn int `ini:"max_connections"`
+		Replica *struct{ Host string }
+	}
+	Labels map[string]int
+}
+
+func formatTestLoad(t *testing.T, name, content string, config interface{}) (string, error) {
+	file := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return file, NewXPConfig(&XPConfigEnvironment{EnvironmentPrefix: "-"}).Load(config, file)
+}
+
+func TestDecodeIni(t *testing.T) {
+	var c formatTestConfig
+	_, err := formatTestLoad(t, "c.ini", "; comment\nname = \"app\"\ndebug = true\ntags = a, b\ntimeout = 3s\n"+
+		"[db]\nhost = h\nport: 5\nmax_connections = 7\n[db.replica]\nhost = r\n[labels]\nx = 1\n", &c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Name != "app" || !c.Debug || len(c.Tags) != 2 || c.Tags[1] != "b" || c.Timeout != 3*time.Second ||
+		c.DB.Host != "h" || c.DB.Port != 5 || c.DB.MaxConn != 7 || c.DB.Replica == nil || c.DB.Replica.Host != "r" || c.Labels["x"] != 1 {
+		t.Fatalf("%+v", c)
+	}
+
+	for content, line := range map[string]int{
+		"name = a\n[db\n":              2,
+		"name = a\n\nnovalue\n":        3,
+		"name = a\n[db]\nport = abc\n": 3,
+	} {
+		var fe *ConfigFormatError
+		if err := DecodeIni([]byte(content), &formatTestConfig{}); !errors.As(err, &fe) || fe.Line != line {
+			t.Fatalf("%q: %v", content, err)
+		}
+	}
+}
+
+func TestDecodeProperties(t *testing.T) {
+	var c formatTestConfig
+	_, err := formatTestLoad(t, "c.properties", "# comment\n! comment\nname=a\\u0041pp\ndb.host : h2\ndb.port 6\n"+
+		"tags=x,\\\n  y\ndb.replica.host=r\\=1\n", &c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Name != "aApp" || c.DB.Host != "h2" || c.DB.Port != 6 || len(c.Tags) != 2 || c.Tags[1] != "y" || c.DB.Replica.Host != "r=1" {
+		t.Fatalf("%+v", c)
+	}
+
+	// 续行的错误报告在起始行
+	for content, line := range map[string]int{
+		"name=a\nname=\\u00\n":          2,
+		"name=a\ntags=a,\\\nb\\uZZZZ\n": 2,
+		"db.port=1\ndb.port=x\n":        2,
+	} {
+		var fe *ConfigFormatError
+		if err := DecodeProperties([]byte(content), &formatTestConfig{}); !errors.As(err, &fe) || fe.Line != line {
+			t.Fatalf("%q: %v", content, err)
+		}
+	}
+}
+
+// 各格式的解析错误均包含文件名及行号
+func TestConfigFormatError(t *testing.T) {
+	for name, c := range map[string]struct {
+		content string
+		line    int
+	}{
+		"c.ini":  {"name = a\n[db]\nport = abc\n", 3},
+		"c.json": {"{\n\"name\": \"a\",\n\"debug\": 5\n}", 3},
+		"c.yml":  {"name: a\ndb:\n  port: [\n", -1},
+		"c.toml": {"name = \"a\"\n\ndebug = \n", -1},
+	} {
+		file, err := formatTestLoad(t, name, c.content, &formatTestConfig{})
+		var fe *ConfigFormatError
+		if !errors.As(err, &fe) || fe.File != file || fe.Line == 0 || (c.line > 0 && fe.Line != c.line) {
+			t.Fatalf("%v: %v", name, err)
+		}
+	}
+}
+
+func TestRegisterConfigDecoder(t *testing.T) {
+	RegisterConfigDecoder("XPTEST", func(data []byte, config interface{}) error {
+		if string(data) == "bad" {
+			return errors.New("bad data")
+		}
+		config.(*formatTestConfig).Name = string(data)
+		return nil
+	})
+
+	var c formatTestConfig
+	if _, err := formatTestLoad(t, "c.xptest", "custom", &c); err != nil || c.Name != "custom" {
+		t.Fatalf("%q, %v", c.Name, err)
+	}
+	var fe *ConfigFormatError
+	if _, err := formatTestLoad(t, "c.xptest", "bad", &c); !errors.As(err, &fe) || fe.Line != 0 {
+		t.Fatal(err)
+	}
+}
@@ -1,18 +1,19 @@
 package XPSuperKit
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path"
 	"reflect"
+	"strconv"
 	"strings"
+)
 
-	"github.com/BurntSushi/toml"
-	"github.com/go-yaml/yaml"
-
+const (
+	kConfigLocalEnvironment  = "local"
+	kConfigEnvSliceMaxGrowth = 256 //环境变量中切片的索引最多超出原长度的元素数
 )
 
 func (cfg *XPConfigImpl) getEnvironmentPrefix(config interface{}) string {
@@ -27,18 +28,19 @@ func (cfg *XPConfigImpl) getEnvironmentPrefix(config interface{}) string {
 	return cfg.XPConfigEnvironment.EnvironmentPrefix
 }
 
-func getConfigurationFileWithEnvironmentPrefix(file, env string) (string, error) {
-	var (
-		envFile string
-		extname = path.Ext(file)
-	)
+func configurationFileWithEnvironment(file, env string) string {
+	extname := path.Ext(file)
 
 	if extname == "" {
-		envFile = fmt.Sprintf("%v.%v", file, env)
-	} else {
-		envFile = fmt.Sprintf("%v.%v%v", strings.TrimSuffix(file, extname), env, extname)
+		return fmt.Sprintf("%v.%v", file, env)
 	}
 
+	return fmt.Sprintf("%v.%v%v", strings.TrimSuffix(file, extname), env, extname)
+}
+
+func getConfigurationFileWithEnvironmentPrefix(file, env string) (string, error) {
+	envFile := configurationFileWithEnvironment(file, env)
+
 	if fileInfo, err := os.Stat(envFile); err == nil && fileInfo.Mode().IsRegular() {
 		return envFile, nil
 	}
@@ -46,8 +48,9 @@ func getConfigurationFileWithEnvironmentPrefix(file, env string) (string, error)
 	return "", fmt.Errorf("failed to find file %v", file)
 }
 
+// 返回按加载顺序排列的配置文件，后加载的优先级更高：全部基础及环境配置文件之后才是本地配置文件
 func (cfg *XPConfigImpl) getConfigurationFiles(files ...string) []string {
-	var results []string
+	var results, localFiles []string
 
 	for i := len(files) - 1; i >= 0; i-- {
 		foundFile := false
@@ -74,35 +77,174 @@ func (cfg *XPConfigImpl) getConfigurationFiles(files ...string) []string {
 				fmt.Printf("Failed to find configuration %v\n", file)
 			}
 		}
+
+		// check local configuration, not committed to version control, overrides the others
+		if file, err := getConfigurationFileWithEnvironmentPrefix(file, kConfigLocalEnvironment); err == nil {
+			localFiles = append(localFiles, file)
+		}
 	}
 
-	return results
+	return append(results, localFiles...)
 }
 
-func processFile(config interface{}, file string) error {
+// 解码配置文件至 config，同时返回文件的原始解码结果及格式，格式没有原始解码器时 values 为 nil
+func processFile(config interface{}, file string) (values map[string]interface{}, format string, err error) {
 	data, err := ioutil.ReadFile(file)
 	if err != nil {
-		return err
+		return nil, "", err
 	}
 
-	switch {
-	case strings.HasSuffix(file, ".yaml") || strings.HasSuffix(file, ".yml"):
-		return yaml.Unmarshal(data, config)
-	case strings.HasSuffix(file, ".toml"):
-		return toml.Unmarshal(data, config)
-	case strings.HasSuffix(file, ".json"):
-		return json.Unmarshal(data, config)
-	default:
-		if toml.Unmarshal(data, config) != nil {
-			if json.Unmarshal(data, config) != nil {
-				if yaml.Unmarshal(data, config) != nil {
-					return errors.New("failed to decode config")
+	if format, err = decodeConfigData(file, data, config); err != nil {
+		return nil, format, err
+	}
+
+	raw, ok, err := decodeConfigRaw(format, data)
+	if err != nil || !ok {
+		return nil, format, newConfigFormatError(file, strings.TrimPrefix(format, "."), data, err)
+	}
+	if values, _ = raw.(map[string]interface{}); values == nil {
+		values = map[string]interface{}{}
+	}
+	return values, format, nil
+}
+
+/**
+ * 将一个配置文件解码得到的 src 合并到 dst，raw 为同一文件的原始解码结果
+ * 文件中出现的键即使值为 false、0、"" 或 null 也会覆盖 dst，未出现的键保持不变
+ * 结构体逐字段合并，切片及 map 按 config 中的策略合并
+ */
+func mergeConfigLayer(dst, src reflect.Value, raw interface{}, format string, config *MergeConfig) error {
+	if raw == nil {
+		dst.Set(src)
+		return nil
+	}
+
+	switch dst.Kind() {
+	case reflect.Ptr:
+		if dst.IsNil() || src.IsNil() {
+			dst.Set(src)
+			return nil
+		}
+		return mergeConfigLayer(dst.Elem(), src.Elem(), raw, format, config)
+	case reflect.Struct:
+		values, ok := raw.(map[string]interface{})
+		if !ok || !hasExportedField(dst) {
+			dst.Set(src)
+			return nil
+		}
+
+		for i := 0; i < dst.NumField(); i++ {
+			fieldStruct := dst.Type().Field(i)
+			if fieldStruct.PkgPath != "" {
+				continue
+			}
+
+			value, present := lookupConfigRawKey(values, &fieldStruct, format)
+			if present {
+				if err := mergeConfigLayer(dst.Field(i), src.Field(i), value, format, config); err != nil {
+					return err
 				}
 			}
 		}
+	case reflect.Slice:
+		items, ok := raw.([]interface{})
+		if !ok || dst.Len() == 0 || config.Slice == MERGE_SLICE_REPLACE {
+			dst.Set(src)
+			return nil
+		}
+
+		if config.Slice != MERGE_SLICE_BY_INDEX {
+			merged, err := mergeSlices(dst, src, make(map[uintptr]*visit), 0, config)
+			if err == nil {
+				dst.Set(merged)
+			}
+			return err
+		}
 
-		return nil
+		merged := reflect.AppendSlice(reflect.MakeSlice(dst.Type(), 0, dst.Len()+src.Len()), dst)
+		for i := 0; i < src.Len(); i++ {
+			if i >= merged.Len() || i >= len(items) {
+				merged = reflect.Append(merged, src.Index(i))
+			} else if err := mergeConfigLayer(merged.Index(i), src.Index(i), items[i], format, config); err != nil {
+				return err
+			}
+		}
+		dst.Set(merged)
+	case reflect.Map:
+		values, ok := raw.(map[string]interface{})
+		if !ok || dst.IsNil() || src.IsNil() || config.Map == MERGE_MAP_REPLACE {
+			dst.Set(src)
+			return nil
+		}
+
+		for _, key := range src.MapKeys() {
+			element := reflect.New(dst.Type().Elem()).Elem()
+			element.Set(src.MapIndex(key))
+			if existing := dst.MapIndex(key); existing.IsValid() {
+				if value, present := values[fmt.Sprint(key.Interface())]; present {
+					element.Set(existing)
+					if err := mergeConfigLayer(element, src.MapIndex(key), value, format, config); err != nil {
+						return err
+					}
+				}
+			}
+			dst.SetMapIndex(key, element)
+		}
+	default:
+		dst.Set(src)
+	}
+	return nil
+}
+
+// 查找字段在原始解码结果中对应的值，键名的匹配规则与各格式的解码器一致；内嵌的结构体使用 values 本身
+func lookupConfigRawKey(values map[string]interface{}, fieldStruct *reflect.StructField, format string) (interface{}, bool) {
+	names, match, inline, skip := configRawKeyNames(fieldStruct, format)
+	if skip {
+		return nil, false
+	}
+	if inline {
+		return values, true
+	}
+
+	for _, name := range names {
+		if value, ok := values[name]; ok {
+			return value, true
+		}
+	}
+	for key, value := range values {
+		for _, name := range names {
+			if match(key, name) {
+				return value, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// 字段在 format 格式的文件中可使用的键名及键的匹配函数，inline 表示字段的键直接位于上层
+func configRawKeyNames(fieldStruct *reflect.StructField, format string) (names []string, match func(key, name string) bool, inline bool, skip bool) {
+	switch format {
+	case ".yaml", ".yml":
+		key, yamlInline, yamlSkip := configYamlKey(fieldStruct)
+		return []string{key}, func(key, name string) bool { return key == name }, yamlInline, yamlSkip
+	case ".json", ".toml":
+		name := strings.Split(fieldStruct.Tag.Get(strings.TrimPrefix(format, ".")), ",")[0]
+		if name == "-" {
+			return nil, nil, false, true
+		}
+		if name == "" {
+			name = fieldStruct.Name
+			inline = fieldStruct.Anonymous && indirectType(fieldStruct.Type).Kind() == reflect.Struct
+		}
+		return []string{name}, strings.EqualFold, inline, false
 	}
+
+	// INI 及 properties 与 configFieldsByKey 一致
+	names = configKeyNames(fieldStruct)
+	if name := strings.Split(fieldStruct.Tag.Get("ini"), ",")[0]; name != "" && name != "-" {
+		names = append(names, name)
+	}
+	return names, func(key, name string) bool { return normalizeConfigKey(key) == normalizeConfigKey(name) }, false, false
 }
 
 func getPrefixForStruct(prefixes []string, fieldStruct *reflect.StructField) []string {
@@ -112,7 +254,8 @@ func getPrefixForStruct(prefixes []string, fieldStruct *reflect.StructField) []s
 	return append(prefixes, fieldStruct.Name)
 }
 
-func processTags(config interface{}, prefixes ...string) error {
+// 处理 env 及 default 标签，sources 记录字段的来源，path 为结构体在配置中的路径
+func processTags(config interface{}, sources *configSources, path string, prefixes ...string) error {
 	configValue := reflect.Indirect(reflect.ValueOf(config))
 	if configValue.Kind() != reflect.Struct {
 		return errors.New("invalid config, should be struct")
@@ -127,6 +270,13 @@ func processTags(config interface{}, prefixes ...string) error {
 			envName     = fieldStruct.Tag.Get("env") // read configuration from shell env
 		)
 
+		// unexported fields, e.g. those of time.Time, can't be set
+		if fieldStruct.PkgPath != "" {
+			continue
+		}
+
+		fieldPath := joinConfigPath(path, fieldStruct.Name)
+
 		if envName == "" {
 			envNames = append(envNames, strings.Join(append(prefixes, fieldStruct.Name), "_"))                  // Configor_DB_Name
 			envNames = append(envNames, strings.ToUpper(strings.Join(append(prefixes, fieldStruct.Name), "_"))) // CONFIGOR_DB_NAME
@@ -137,22 +287,21 @@ func processTags(config interface{}, prefixes ...string) error {
 		// Load From Shell ENV
 		for _, env := range envNames {
 			if value := os.Getenv(env); value != "" {
-				if err := yaml.Unmarshal([]byte(value), field.Addr().Interface()); err != nil {
-					return err
+				if err := setConfigScalar(field, value); err != nil {
+					return fmt.Errorf("failed to parse environment variable %v: %v", env, err)
 				}
+				sources.record(fieldPath, ConfigSource{Kind: CONFIG_SOURCE_ENV, Name: env, Value: field.Interface()})
 				break
 			}
 		}
 
 		if isBlank := reflect.DeepEqual(field.Interface(), reflect.Zero(field.Type()).Interface()); isBlank {
-			// Set default configuration if blank
+			// Set default configuration if blank, required fields are checked by ValidateConfig
 			if value := fieldStruct.Tag.Get("default"); value != "" {
-				if err := yaml.Unmarshal([]byte(value), field.Addr().Interface()); err != nil {
-					return err
+				if err := setConfigScalar(field, value); err != nil {
+					return fmt.Errorf("failed to parse default value of %v: %v", fieldPath, err)
 				}
-			} else if fieldStruct.Tag.Get("required") == "true" {
-				// return error if it is required but blank
-				return errors.New(fieldStruct.Name + " is required, but blank")
+				sources.record(fieldPath, ConfigSource{Kind: CONFIG_SOURCE_DEFAULT, Value: field.Interface()})
 			}
 		}
 
@@ -160,21 +309,200 @@ func processTags(config interface{}, prefixes ...string) error {
 			field = field.Elem()
 		}
 
-		if field.Kind() == reflect.Struct {
-			if err := processTags(field.Addr().Interface(), getPrefixForStruct(prefixes, &fieldStruct)...); err != nil {
+		// time.Time 等没有导出字段的结构体作为整体处理
+		if field.Kind() == reflect.Struct && hasExportedField(field) {
+			if err := processTags(field.Addr().Interface(), sources, fieldPath, getPrefixForStruct(prefixes, &fieldStruct)...); err != nil {
 				return err
 			}
 		}
 
 		if field.Kind() == reflect.Slice {
-			for i := 0; i < field.Len(); i++ {
-				if reflect.Indirect(field.Index(i)).Kind() == reflect.Struct {
-					if err := processTags(field.Index(i).Addr().Interface(), append(getPrefixForStruct(prefixes, &fieldStruct), fmt.Sprint(i))...); err != nil {
-						return err
-					}
+			if err := processSliceTags(field, sources, fieldPath, envNames, getPrefixForStruct(prefixes, &fieldStruct)); err != nil {
+				return err
+			}
+		}
+
+		if field.Kind() == reflect.Map {
+			if err := processMapTags(field, sources, fieldPath, envNames, getPrefixForStruct(prefixes, &fieldStruct)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// 环境变量中以 NAME_ 开头的部分，NAME 为字段对应的环境变量名
+func envVarsWithPrefix(envNames []string) map[string]string {
+	results := make(map[string]string)
+	for _, env := range os.Environ() {
+		pos := strings.Index(env, "=")
+		if pos < 0 || env[pos+1:] == "" {
+			continue
+		}
+
+		for _, name := range envNames {
+			if strings.HasPrefix(env[:pos], name+"_") {
+				results[env[len(name)+1:pos]] = env[pos+1:]
+				break
+			}
+		}
+	}
+	return results
+}
+
+func configElemIsStruct(elemType reflect.Type) bool {
+	for elemType.Kind() == reflect.Ptr {
+		elemType = elemType.Elem()
+	}
+	return elemType.Kind() == reflect.Struct && hasExportedField(reflect.New(elemType).Elem())
+}
+
+// 切片元素: NAME_0=value 设置第 0 个元素，NAME_0_FIELD=value 设置结构体元素的字段，索引超出长度时扩展切片
+// 扩展时索引最多超出原长度 kConfigEnvSliceMaxGrowth，避免 NAME_1000000000 之类的索引分配巨大的切片
+func processSliceTags(field reflect.Value, sources *configSources, path string, envNames, prefixes []string) error {
+	isStruct := configElemIsStruct(field.Type().Elem())
+	envName := envNames[len(envNames)-1]
+
+	values := make(map[int]string)
+	length := field.Len()
+	for suffix, value := range envVarsWithPrefix(envNames) {
+		indexText, rest := suffix, ""
+		if pos := strings.Index(suffix, "_"); pos >= 0 {
+			indexText, rest = suffix[:pos], suffix[pos+1:]
+		}
+
+		index, err := strconv.Atoi(indexText)
+		if err != nil || index < 0 || (rest != "" && !isStruct) {
+			continue
+		}
+		if index >= field.Len()+kConfigEnvSliceMaxGrowth {
+			return fmt.Errorf("invalid environment variable %v_%v: index %d exceeds the %d element(s) of %v by more than %d", envName, suffix, index, field.Len(), path, kConfigEnvSliceMaxGrowth)
+		}
+		if rest == "" {
+			values[index] = value
+		}
+		if index >= length {
+			length = index + 1
+		}
+	}
+
+	if length > field.Len() {
+		grown := reflect.MakeSlice(field.Type(), length, length)
+		reflect.Copy(grown, field)
+		field.Set(grown)
+	}
+
+	for i := 0; i < field.Len(); i++ {
+		elemPath := fmt.Sprintf("%v[%d]", path, i)
+		elem := field.Index(i)
+
+		if value, ok := values[i]; ok {
+			name := fmt.Sprintf("%v_%d", envName, i)
+			if err := setConfigScalar(elem, value); err != nil {
+				return fmt.Errorf("failed to parse environment variable %v: %v", name, err)
+			}
+			sources.record(elemPath, ConfigSource{Kind: CONFIG_SOURCE_ENV, Name: name, Value: elem.Interface()})
+		}
+
+		if isStruct {
+			for elem.Kind() == reflect.Ptr {
+				if elem.IsNil() {
+					elem.Set(reflect.New(elem.Type().Elem()))
+				}
+				elem = elem.Elem()
+			}
+			if err := processTags(elem.Addr().Interface(), sources, elemPath, append(append([]string{}, prefixes...), fmt.Sprint(i))...); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// map 元素: NAME_KEY=value 设置 key 对应的值，值为结构体时 NAME_KEY_FIELD=value 设置其字段
+// KEY 与已有的 key 忽略大小写匹配，否则使用小写的 KEY；值为结构体时新的 KEY 不能包含 _
+func processMapTags(field reflect.Value, sources *configSources, path string, envNames, prefixes []string) error {
+	if field.Type().Key().Kind() != reflect.String {
+		return nil
+	}
+
+	envVars := envVarsWithPrefix(envNames)
+	if len(envVars) == 0 {
+		return nil
+	}
+
+	isStruct := configElemIsStruct(field.Type().Elem())
+	envName := envNames[len(envNames)-1]
+
+	existingKeys := make(map[string]reflect.Value)
+	for _, key := range field.MapKeys() {
+		existingKeys[strings.ToUpper(key.String())] = key
+	}
+	mapKey := func(upper string) reflect.Value {
+		if key, ok := existingKeys[upper]; ok {
+			return key
+		}
+		return reflect.ValueOf(strings.ToLower(upper)).Convert(field.Type().Key())
+	}
+
+	if field.IsNil() {
+		field.Set(reflect.MakeMap(field.Type()))
+	}
+
+	// 值为结构体时先确定 key，再由 processTags 处理其字段
+	structKeys := make(map[string]bool)
+	for suffix, value := range envVars {
+		upper := strings.ToUpper(suffix)
+		if isStruct {
+			keyText := ""
+			for existing := range existingKeys {
+				if strings.HasPrefix(upper, existing+"_") && len(existing) > len(keyText) {
+					keyText = existing
 				}
 			}
+			if pos := strings.Index(upper, "_"); keyText == "" && pos > 0 {
+				keyText = upper[:pos]
+			}
+			if keyText != "" {
+				structKeys[keyText] = true
+			}
+			continue
+		}
+
+		key := mapKey(upper)
+		elem := reflect.New(field.Type().Elem()).Elem()
+		if existing := field.MapIndex(key); existing.IsValid() {
+			elem.Set(existing)
+		}
+		if err := setConfigScalar(elem, value); err != nil {
+			return fmt.Errorf("failed to parse environment variable %v_%v: %v", envName, suffix, err)
+		}
+
+		field.SetMapIndex(key, elem)
+		sources.record(fmt.Sprintf("%v[%v]", path, key.Interface()), ConfigSource{Kind: CONFIG_SOURCE_ENV, Name: envName + "_" + suffix, Value: elem.Interface()})
+	}
+
+	for keyText := range structKeys {
+		key := mapKey(keyText)
+		elem := reflect.New(field.Type().Elem()).Elem()
+		if existing := field.MapIndex(key); existing.IsValid() {
+			elem.Set(existing)
+		}
+
+		target := elem
+		for target.Kind() == reflect.Ptr {
+			if target.IsNil() {
+				target.Set(reflect.New(target.Type().Elem()))
+			}
+			target = target.Elem()
 		}
+
+		if err := processTags(target.Addr().Interface(), sources, fmt.Sprintf("%v[%v]", path, key.Interface()), append(append([]string{}, prefixes...), keyText)...); err != nil {
+			return err
+		}
+		field.SetMapIndex(key, elem)
 	}
+
 	return nil
-}
\ No newline at end of file
+}
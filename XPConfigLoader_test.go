@@ -0,0 +1,84 @@
+package XPSuperKit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type envTestConfig struct {
+	Tags     []string
+	Ports    []int
+	Replicas []struct {
+		Host string
+		Port int `default:"5432"`
+	}
+	PtrReps []*struct{ Host string }
+	Limits  map[string]int
+	DBs     map[string]struct{ Host string }
+	MaxBody ByteSize      `default:"10MiB"`
+	Retain  time.Duration `default:"7d"`
+	Timeout time.Duration
+}
+
+func TestConfigEnvAddressing(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yml")
+	ioutil.WriteFile(file, []byte("replicas: [{host: a}]\nlimits: {Foo: 1, bar: 2}\ndbs: {main_db: {host: x}}\n"), 0644)
+	env := map[string]string{
+		"EA_TAGS":             "a, b\\,c",
+		"EA_PORTS_1":          "81",
+		"EA_REPLICAS_0_PORT":  "1",
+		"EA_REPLICAS_2_HOST":  "c",
+		"EA_PTRREPS_0_HOST":   "p",
+		"EA_LIMITS_FOO":       "10",
+		"EA_LIMITS_NEW_KEY":   "3",
+		"EA_DBS_MAIN_DB_HOST": "y",
+		"EA_DBS_OTHER_HOST":   "z",
+		"EA_MAXBODY":          "1.5GiB",
+		"EA_TIMEOUT":          "1d2h",
+	}
+	for k, v := range env {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+	cfg := NewXPConfig(&XPConfigEnvironment{EnvironmentPrefix: "EA"})
+	var c envTestConfig
+	if err := cfg.Load(&c, file); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Tags) != 2 || c.Tags[1] != "b,c" || len(c.Ports) != 2 || c.Ports[1] != 81 {
+		t.Fatalf("%+v", c)
+	}
+	if len(c.Replicas) != 3 || c.Replicas[0].Host != "a" || c.Replicas[0].Port != 1 || c.Replicas[1].Port != 5432 || c.Replicas[2].Host != "c" {
+		t.Fatalf("%+v", c.Replicas)
+	}
+	if len(c.PtrReps) != 1 || c.PtrReps[0].Host != "p" {
+		t.Fatalf("%+v", c.PtrReps)
+	}
+	if c.Limits["Foo"] != 10 || c.Limits["bar"] != 2 || c.Limits["new_key"] != 3 {
+		t.Fatalf("%+v", c.Limits)
+	}
+	if c.DBs["main_db"].Host != "y" || c.DBs["other"].Host != "z" {
+		t.Fatalf("%+v", c.DBs)
+	}
+	if c.MaxBody != 3*GiB/2 || c.Retain != 7*24*time.Hour || c.Timeout != 26*time.Hour {
+		t.Fatalf("%v %v %v", c.MaxBody, c.Retain, c.Timeout)
+	}
+	if s := cfg.Sources(&c, "Limits[Foo]"); len(s) == 0 || s[len(s)-1].Name != "EA_LIMITS_FOO" {
+		t.Fatal(s)
+	}
+}
+
+func TestConfigEnvSliceIndexLimit(t *testing.T) {
+	os.Setenv("EA_TAGS_1000000000", "x")
+	defer os.Unsetenv("EA_TAGS_1000000000")
+
+	var config struct{ Tags []string }
+	err := NewXPConfig(&XPConfigEnvironment{EnvironmentPrefix: "EA"}).Load(&config)
+	if err == nil || !strings.Contains(err.Error(), "EA_TAGS_1000000000") {
+		t.Fatalf("expected index limit error, got %v", err)
+	}
+}
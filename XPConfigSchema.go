@@ -0,0 +1,674 @@
+package XPSuperKit
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/mail"
+	"net/url"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-yaml/yaml"
+)
+
+/*********调用示例********
+type Config struct {
+	Env  string `default:"development" validate:"oneof=development test production" description:"runtime environment"`
+	Port int    `default:"8080" validate:"port"`
+	DB   struct {
+		Host string `required:"true"`
+	}
+}
+
+// 生成 JSON Schema，保存后在编辑器中关联即可获得补全及校验
+// YAML 文件可在首行添加: # yaml-language-server: $schema=./config.schema.json
+schema, err := XPSuperKit.GenerateConfigSchema(&Config{})
+data, err := json.MarshalIndent(schema, "", "  ")
+
+// CI 中校验配置文件，返回的 *ConfigValidationError 列出全部错误
+err = schema.ValidateFile("config.yml")
+ ************************/
+
+const (
+	kConfigSchemaDraft      = "http://json-schema.org/draft-07/schema#"
+	kConfigDurationPattern  = `^-?([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`
+	kConfigSchemaMaxPortNum = 65535
+	kConfigByteSizePattern  = `^[+-]?[0-9.]+ *([kKmMgGtTpP]([iI]?[bB])?|[bB])?$`
+)
+
+// JSON Schema (draft-07) 的子集，属性名与 go-yaml 的规则一致：yaml 标签中的名称，否则为小写的字段名
+type ConfigSchema struct {
+	Schema               string                   `json:"$schema,omitempty"`
+	Title                string                   `json:"title,omitempty"`
+	Description          string                   `json:"description,omitempty"`
+	Type                 string                   `json:"type,omitempty"`
+	Properties           map[string]*ConfigSchema `json:"properties,omitempty"`
+	Required             []string                 `json:"required,omitempty"`
+	AdditionalProperties interface{}              `json:"additionalProperties,omitempty"` //false 或 *ConfigSchema
+	Items                *ConfigSchema            `json:"items,omitempty"`
+	Default              interface{}              `json:"default,omitempty"`
+	Enum                 []interface{}            `json:"enum,omitempty"`
+	Minimum              *float64                 `json:"minimum,omitempty"`
+	Maximum              *float64                 `json:"maximum,omitempty"`
+	MinLength            *int                     `json:"minLength,omitempty"`
+	MaxLength            *int                     `json:"maxLength,omitempty"`
+	MinItems             *int                     `json:"minItems,omitempty"`
+	MaxItems             *int                     `json:"maxItems,omitempty"`
+	MinProperties        *int                     `json:"minProperties,omitempty"`
+	MaxProperties        *int                     `json:"maxProperties,omitempty"`
+	Pattern              string                   `json:"pattern,omitempty"`
+	Format               string                   `json:"format,omitempty"`
+
+	fields map[string]reflect.StructField //属性对应的结构体字段，用于按文件格式匹配键名
+}
+
+var (
+	configTimeType     = reflect.TypeOf(time.Time{})
+	configByteSizeType = reflect.TypeOf(ByteSize(0))
+)
+
+/**
+ * 由配置结构体生成 JSON Schema
+ * 类型来自字段类型，default 标签生成默认值，description 标签生成说明，
+ * required 标签及 validate 标签中的 required、min、max、len、oneof、regex、url、email、port、duration 生成对应的约束
+ * 结构体不允许未声明的属性，以便发现拼写错误
+ * @param config interface{} 结构体或结构体指针
+ */
+func GenerateConfigSchema(config interface{}) (*ConfigSchema, error) {
+	configType := reflect.TypeOf(config)
+	for configType != nil && configType.Kind() == reflect.Ptr {
+		configType = configType.Elem()
+	}
+	if configType == nil || configType.Kind() != reflect.Struct {
+		return nil, ErrorN("invalid config, should be struct")
+	}
+
+	schema := configTypeSchema(configType, map[reflect.Type]bool{})
+	schema.Schema = kConfigSchemaDraft
+	schema.Title = configType.Name()
+	return schema, nil
+}
+
+func configTypeSchema(fieldType reflect.Type, visiting map[reflect.Type]bool) *ConfigSchema {
+	for fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+
+	switch {
+	case fieldType == configDurationType:
+		return &ConfigSchema{Type: "string", Pattern: kConfigDurationPattern}
+	case fieldType == configByteSizeType:
+		// 数字或带单位的字符串
+		return &ConfigSchema{Pattern: kConfigByteSizePattern}
+	case fieldType == configTimeType:
+		return &ConfigSchema{Type: "string", Format: "date-time"}
+	case fieldType.Kind() == reflect.Slice && fieldType.Elem().Kind() == reflect.Uint8:
+		return &ConfigSchema{Type: "string"}
+	case reflect.PtrTo(fieldType).Implements(configTextUnmarshalerType):
+		return &ConfigSchema{Type: "string"}
+	}
+
+	switch fieldType.Kind() {
+	case reflect.Bool:
+		return &ConfigSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &ConfigSchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &ConfigSchema{Type: "number"}
+	case reflect.String:
+		return &ConfigSchema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &ConfigSchema{Type: "array", Items: configTypeSchema(fieldType.Elem(), visiting)}
+	case reflect.Map:
+		return &ConfigSchema{Type: "object", AdditionalProperties: configTypeSchema(fieldType.Elem(), visiting)}
+	case reflect.Struct:
+		// 递归的类型不再展开
+		if visiting[fieldType] {
+			return &ConfigSchema{Type: "object"}
+		}
+		visiting[fieldType] = true
+		defer delete(visiting, fieldType)
+
+		schema := &ConfigSchema{Type: "object", Properties: make(map[string]*ConfigSchema), AdditionalProperties: false,
+			fields: make(map[string]reflect.StructField)}
+		configStructSchema(schema, fieldType, visiting)
+		return schema
+	}
+
+	// interface{} 等可以是任意值
+	return &ConfigSchema{}
+}
+
+func configStructSchema(schema *ConfigSchema, structType reflect.Type, visiting map[reflect.Type]bool) {
+	for i := 0; i < structType.NumField(); i++ {
+		fieldStruct := structType.Field(i)
+		if fieldStruct.PkgPath != "" {
+			continue
+		}
+
+		key, inline, skip := configYamlKey(&fieldStruct)
+		if skip {
+			continue
+		}
+
+		fieldType := fieldStruct.Type
+		for fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+
+		if inline && fieldType.Kind() == reflect.Struct {
+			configStructSchema(schema, fieldType, visiting)
+			continue
+		}
+
+		property := configTypeSchema(fieldStruct.Type, visiting)
+		property.Description = fieldStruct.Tag.Get(kConfigDescriptionTag)
+
+		if value := fieldStruct.Tag.Get("default"); value != "" {
+			property.Default = configSchemaDefault(fieldType, value)
+		}
+
+		required := fieldStruct.Tag.Get("required") == "true"
+		for _, rule := range parseValidateRules(fieldStruct.Tag.Get(kConfigValidateTag)) {
+			if rule.name == "required" {
+				required = true
+			}
+			applyConfigSchemaRule(property, fieldType, rule)
+		}
+		if required {
+			schema.Required = append(schema.Required, key)
+		}
+
+		schema.Properties[key] = property
+		schema.fields[key] = fieldStruct
+	}
+}
+
+// default 标签按 Load 中的规则解析后转换为 YAML 对应的值，如 time.Duration 为 "5s"
+func configSchemaDefault(fieldType reflect.Type, value string) interface{} {
+	parsed := reflect.New(fieldType)
+	if err := setConfigScalar(parsed.Elem(), value); err != nil {
+		return value
+	}
+
+	data, err := yaml.Marshal(parsed.Interface())
+	if err != nil {
+		return value
+	}
+
+	var result interface{}
+	if err := yaml.Unmarshal(data, &result); err != nil {
+		return value
+	}
+	return normalizeConfigRaw(result)
+}
+
+// 将 validate 规则转换为 JSON Schema 约束，无法表示的规则 (如 eqfield) 被忽略
+func applyConfigSchemaRule(schema *ConfigSchema, fieldType reflect.Type, rule configRule) {
+	switch rule.name {
+	case "min", "max", "len":
+		number, err := strconv.ParseFloat(rule.param, 64)
+		if err != nil || fieldType == configDurationType {
+			return
+		}
+		count := int(number)
+
+		switch schema.Type {
+		case "integer", "number":
+			if rule.name != "max" {
+				schema.Minimum = &number
+			}
+			if rule.name != "min" {
+				schema.Maximum = &number
+			}
+		case "string":
+			if rule.name != "max" {
+				schema.MinLength = &count
+			}
+			if rule.name != "min" {
+				schema.MaxLength = &count
+			}
+		case "array":
+			if rule.name != "max" {
+				schema.MinItems = &count
+			}
+			if rule.name != "min" {
+				schema.MaxItems = &count
+			}
+		case "object":
+			if rule.name != "max" {
+				schema.MinProperties = &count
+			}
+			if rule.name != "min" {
+				schema.MaxProperties = &count
+			}
+		}
+	case "oneof":
+		schema.Enum = nil
+		for _, option := range strings.Fields(rule.param) {
+			schema.Enum = append(schema.Enum, configSchemaDefault(fieldType, option))
+		}
+	case "regex":
+		schema.Pattern = rule.param
+	case "url":
+		schema.Format = "uri"
+	case "email":
+		schema.Format = "email"
+	case "duration":
+		schema.Pattern = kConfigDurationPattern
+	case "port":
+		min, max := float64(1), float64(kConfigSchemaMaxPortNum)
+		schema.Minimum, schema.Maximum = &min, &max
+	}
+}
+
+/**
+ * 按 Schema 校验配置文件，文件格式由扩展名决定，支持 YAML、JSON、TOML、INI 及 properties
+ * 由结构体生成的 Schema 按文件格式对应的标签 (json、toml 等) 匹配属性名，规则与 Load 的解码一致：YAML 文件的键区分大小写，其他格式忽略大小写
+ * INI 及 properties 中的值为字符串，按属性类型转换后校验
+ * 返回 *ConfigValidationError，Path 为文件中的键路径，如 db.port、servers[0].host
+ */
+func (schema *ConfigSchema) ValidateFile(file string) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	ext := strings.ToLower(path.Ext(file))
+	validator := &configSchemaValidator{format: ext, result: &ConfigValidationError{}}
+	var raw interface{}
+
+	switch ext {
+	case ".yaml", ".yml":
+		raw, err = decodeYamlRaw(data)
+	case ".json":
+		raw, err = decodeJsonRaw(data)
+		validator.caseInsensitive = true
+	case ".toml":
+		raw, err = decodeTomlRaw(data)
+		validator.caseInsensitive = true
+	case ".ini", ".properties":
+		if ext == ".ini" {
+			raw, err = decodeIniRaw(data)
+		} else {
+			raw, err = decodePropertiesRaw(data)
+		}
+		validator.caseInsensitive, validator.coerceStrings = true, true
+	default:
+		return fmt.Errorf("%v: unsupported config format for schema validation", file)
+	}
+
+	if err != nil {
+		return newConfigFormatError(file, strings.TrimPrefix(path.Ext(file), "."), data, err)
+	}
+
+	validator.validate(schema, normalizeConfigRaw(raw), "")
+	if len(validator.result.Errors) > 0 {
+		return validator.result
+	}
+	return nil
+}
+
+// 按 Schema 校验已解码的值，map 的键必须为字符串
+func (schema *ConfigSchema) Validate(raw interface{}) error {
+	validator := &configSchemaValidator{result: &ConfigValidationError{}}
+	validator.validate(schema, normalizeConfigRaw(raw), "")
+	if len(validator.result.Errors) > 0 {
+		return validator.result
+	}
+	return nil
+}
+
+// go-yaml 解码得到的 map[interface{}]interface{} 转换为 map[string]interface{}
+func normalizeConfigRaw(raw interface{}) interface{} {
+	switch value := raw.(type) {
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(value))
+		for k, v := range value {
+			result[fmt.Sprint(k)] = normalizeConfigRaw(v)
+		}
+		return result
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(value))
+		for k, v := range value {
+			result[k] = normalizeConfigRaw(v)
+		}
+		return result
+	case []map[string]interface{}:
+		result := make([]interface{}, len(value))
+		for i, v := range value {
+			result[i] = normalizeConfigRaw(v)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(value))
+		for i, v := range value {
+			result[i] = normalizeConfigRaw(v)
+		}
+		return result
+	}
+	return raw
+}
+
+func configKeyValuesToRaw(values map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		switch value := value.(type) {
+		case map[string]interface{}:
+			result[key] = configKeyValuesToRaw(value)
+		case configKeyValue:
+			result[key] = value.value
+		}
+	}
+	return result
+}
+
+type configSchemaValidator struct {
+	caseInsensitive bool   //属性名忽略大小写
+	format          string //文件的扩展名，由结构体生成的 Schema 按该格式的标签匹配属性名
+	coerceStrings   bool   //字符串按属性类型转换后校验
+	result          *ConfigValidationError
+}
+
+func (validator *configSchemaValidator) fail(path, rule string, value interface{}, format string, args ...interface{}) {
+	if path == "" {
+		path = "(root)"
+	}
+	validator.result.Errors = append(validator.result.Errors, &ConfigFieldError{
+		Path:    path,
+		Rule:    rule,
+		Value:   value,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (validator *configSchemaValidator) validate(schema *ConfigSchema, value interface{}, path string) {
+	if schema == nil || value == nil {
+		return
+	}
+
+	if str, ok := value.(string); ok && validator.coerceStrings {
+		value = coerceConfigString(schema, str)
+	}
+
+	if schema.Type != "" && !configSchemaTypeMatches(schema.Type, value) {
+		validator.fail(path, "type", value, "expected %v, got %v", schema.Type, configRawTypeName(value))
+		return
+	}
+
+	if len(schema.Enum) > 0 {
+		found := false
+		for _, option := range schema.Enum {
+			if configRawEqual(option, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			validator.fail(path, "enum", value, "must be one of %v", schema.Enum)
+		}
+	}
+
+	switch value := value.(type) {
+	case map[string]interface{}:
+		validator.validateObject(schema, value, path)
+	case []interface{}:
+		validator.validateLength(path, "Items", "items", len(value), value, schema.MinItems, schema.MaxItems)
+		for i, item := range value {
+			validator.validate(schema.Items, item, fmt.Sprintf("%v[%d]", path, i))
+		}
+	case string:
+		validator.validateLength(path, "Length", "characters", len([]rune(value)), value, schema.MinLength, schema.MaxLength)
+		if schema.Pattern != "" {
+			if re, err := regexp.Compile(schema.Pattern); err == nil && !re.MatchString(value) {
+				validator.fail(path, "pattern", value, "must match %v", schema.Pattern)
+			}
+		}
+		if message := checkConfigSchemaFormat(schema.Format, value); message != "" {
+			validator.fail(path, "format", value, "%v", message)
+		}
+	default:
+		if number, ok := configRawNumber(value); ok {
+			if schema.Minimum != nil && number < *schema.Minimum {
+				validator.fail(path, "minimum", value, "must be at least %v", *schema.Minimum)
+			}
+			if schema.Maximum != nil && number > *schema.Maximum {
+				validator.fail(path, "maximum", value, "must be at most %v", *schema.Maximum)
+			}
+		}
+	}
+}
+
+func (validator *configSchemaValidator) validateObject(schema *ConfigSchema, value map[string]interface{}, path string) {
+	validator.validateLength(path, "Properties", "properties", len(value), value, schema.MinProperties, schema.MaxProperties)
+
+	keys := make([]string, 0, len(value))
+	for key := range value {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, name := range schema.Required {
+		if _, ok := validator.lookup(schema, value, name); !ok {
+			validator.fail(joinConfigPath(path, validator.keyName(schema, name)), "required", nil, "is required")
+		}
+	}
+
+	for _, key := range keys {
+		keyPath := joinConfigPath(path, key)
+
+		if property, ok := validator.property(schema, key); ok {
+			validator.validate(property, value[key], keyPath)
+			continue
+		}
+
+		switch additional := schema.AdditionalProperties.(type) {
+		case bool:
+			if !additional {
+				validator.fail(keyPath, "additionalProperties", value[key], "unknown property")
+			}
+		case *ConfigSchema:
+			validator.validate(additional, value[key], keyPath)
+		}
+	}
+}
+
+func (validator *configSchemaValidator) property(schema *ConfigSchema, key string) (*ConfigSchema, bool) {
+	if field, ok := validator.field(schema, key); ok {
+		return schema.Properties[field], true
+	}
+	if property, ok := schema.Properties[key]; ok && !validator.matchFields(schema) {
+		return property, true
+	}
+	if validator.caseInsensitive && !validator.matchFields(schema) {
+		for name, property := range schema.Properties {
+			if normalizeConfigKey(name) == normalizeConfigKey(key) {
+				return property, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func (validator *configSchemaValidator) lookup(schema *ConfigSchema, value map[string]interface{}, name string) (interface{}, bool) {
+	if validator.matchFields(schema) {
+		for key, v := range value {
+			if field, ok := validator.field(schema, key); ok && field == name {
+				return v, true
+			}
+		}
+		return nil, false
+	}
+
+	if v, ok := value[name]; ok {
+		return v, true
+	}
+	if validator.caseInsensitive {
+		for key, v := range value {
+			if normalizeConfigKey(key) == normalizeConfigKey(name) {
+				return v, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// 由结构体生成的 Schema 按文件格式对应的标签匹配属性名，如 JSON 文件使用 json 标签
+func (validator *configSchemaValidator) matchFields(schema *ConfigSchema) bool {
+	return validator.format != "" && len(schema.fields) > 0
+}
+
+// 查找文件中的键对应的属性名，优先精确匹配
+func (validator *configSchemaValidator) field(schema *ConfigSchema, key string) (string, bool) {
+	if !validator.matchFields(schema) {
+		return "", false
+	}
+
+	found := ""
+	for property, field := range schema.fields {
+		names, match, _, skip := configRawKeyNames(&field, validator.format)
+		if skip {
+			continue
+		}
+		for _, name := range names {
+			if key == name {
+				return property, true
+			}
+			if found == "" && match(key, name) {
+				found = property
+			}
+		}
+	}
+	return found, found != ""
+}
+
+// 属性在文件中的键名，用于报告缺少的必填属性
+func (validator *configSchemaValidator) keyName(schema *ConfigSchema, property string) string {
+	if field, ok := schema.fields[property]; ok && validator.matchFields(schema) {
+		if names, _, _, skip := configRawKeyNames(&field, validator.format); !skip && len(names) > 0 {
+			return names[0]
+		}
+	}
+	return property
+}
+
+func (validator *configSchemaValidator) validateLength(path, rule, what string, length int, value interface{}, min, max *int) {
+	if min != nil && length < *min {
+		validator.fail(path, "min"+rule, value, "must have at least %d %v", *min, what)
+	}
+	if max != nil && length > *max {
+		validator.fail(path, "max"+rule, value, "must have at most %d %v", *max, what)
+	}
+}
+
+func configSchemaTypeMatches(schemaType string, value interface{}) bool {
+	switch schemaType {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		switch value.(type) {
+		case string, time.Time:
+			return true
+		}
+		return false
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "integer":
+		number, ok := configRawNumber(value)
+		return ok && number == math.Trunc(number)
+	case "number":
+		_, ok := configRawNumber(value)
+		return ok
+	}
+	return true
+}
+
+func configRawNumber(value interface{}) (float64, bool) {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+func configRawTypeName(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	}
+	if _, ok := configRawNumber(value); ok {
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// 数字的类型可能不同 (int 与 float64)，按数值比较
+func configRawEqual(a, b interface{}) bool {
+	if x, ok := configRawNumber(a); ok {
+		y, ok := configRawNumber(b)
+		return ok && x == y
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// INI 及 properties 中的字符串按 Schema 的类型转换，无法转换时保留原值以报告类型错误
+func coerceConfigString(schema *ConfigSchema, value string) interface{} {
+	switch schema.Type {
+	case "integer", "number":
+		if number, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+			return number
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(strings.TrimSpace(value)); err == nil {
+			return b
+		}
+	case "array":
+		var items []interface{}
+		for _, item := range strings.Split(strings.Trim(value, "[]"), ",") {
+			items = append(items, strings.TrimSpace(item))
+		}
+		return items
+	}
+	return value
+}
+
+func checkConfigSchemaFormat(format, value string) string {
+	switch format {
+	case "uri":
+		if u, err := url.Parse(value); err != nil || u.Scheme == "" {
+			return "must be a valid URI"
+		}
+	case "email":
+		if _, err := mail.ParseAddress(value); err != nil {
+			return "must be a valid email address"
+		}
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			return "must be an RFC 3339 date-time"
+		}
+	}
+	return ""
+}
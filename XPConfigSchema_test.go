@@ -0,0 +1,127 @@
+package XPSuperKit
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type schemaTestConfig struct {
+	Env     string        `default:"development" validate:"oneof=development test production" description:"runtime environment"`
+	Port    int           `default:"8080" validate:"port"`
+	Workers int           `validate:"min=1,max=64"`
+	Timeout time.Duration `default:"5s"`
+	Admin   string        `validate:"omitempty,email"`
+	DB      struct {
+		Host string   `required:"true"`
+		Tags []string `validate:"max=2"`
+	} `yaml:"database"`
+	Labels map[string]int
+	Any    interface{}
+	Next   *schemaTestConfig
+}
+
+func schemaTestValidate(t *testing.T, schema *ConfigSchema, name, content string) map[string]string {
+	file := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := schema.ValidateFile(file)
+	if err == nil {
+		return nil
+	}
+
+	var validationErr *ConfigValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("%v: %v", name, err)
+	}
+	rules := map[string]string{}
+	for _, fieldErr := range validationErr.Errors {
+		rules[fieldErr.Path] = fieldErr.Rule
+	}
+	return rules
+}
+
+func TestGenerateConfigSchema(t *testing.T) {
+	schema, err := GenerateConfigSchema(&schemaTestConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := json.MarshalIndent(schema, "", "  ")
+	for _, want := range []string{`"enum": [`, `"default": 8080`, `"maximum": 65535`, `"database"`, `"required": [`,
+		`"additionalProperties": false`, `"format": "email"`, `"description": "runtime environment"`} {
+		if !strings.Contains(string(data), want) {
+			t.Fatalf("schema missing %v:\n%s", want, data)
+		}
+	}
+}
+
+func TestConfigSchemaValidateFile(t *testing.T) {
+	schema, err := GenerateConfigSchema(&schemaTestConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rules := schemaTestValidate(t, schema, "good.yml", "env: test\nport: 80\nworkers: 2\ntimeout: 3s\ndatabase:\n  host: h\n  tags: [a]\nlabels: {a: 1}\nany: [1, x]\n"); rules != nil {
+		t.Fatal(rules)
+	}
+
+	rules := schemaTestValidate(t, schema, "bad.yml", "env: staging\nport: 70000\nworkers: x\ntimeout: 3 s\nadmin: nope\ndatabase:\n  tags: [a, b, c]\nlabels: {a: b}\nextra: 1\n")
+	want := map[string]string{"env": "enum", "port": "maximum", "workers": "type", "timeout": "pattern", "admin": "format",
+		"database.host": "required", "database.tags": "maxItems", "labels.a": "type", "extra": "additionalProperties"}
+	for path, rule := range want {
+		if rules[path] != rule {
+			t.Fatalf("%v: got %v", path, rules)
+		}
+	}
+
+	// INI 中的值按属性类型转换后校验
+	rules = schemaTestValidate(t, schema, "c.ini", "Port = 99999\nworkers = 3\n[database]\nhost = h\n")
+	if len(rules) != 1 || rules["Port"] != "maximum" {
+		t.Fatal(rules)
+	}
+
+	rules = schemaTestValidate(t, schema, "c.json", `{"DB": {"Host": "h"}, "Workers": 1.5}`)
+	if len(rules) != 1 || rules["Workers"] != "type" {
+		t.Fatal(rules)
+	}
+}
+
+// JSON 及 TOML 文件按对应的标签匹配属性名，与 Load 的解码一致
+func TestConfigSchemaFormatTags(t *testing.T) {
+	type config struct {
+		Database struct {
+			Host string `json:"host" toml:"host" required:"true"`
+		} `json:"database" toml:"db"`
+		MaxConns int `json:"max_conns" toml:"max-conns" yaml:"maxConns" validate:"max=10"`
+	}
+
+	schema, err := GenerateConfigSchema(&config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rules := schemaTestValidate(t, schema, "c.json", `{"database": {"host": "h"}, "max_conns": 5}`); rules != nil {
+		t.Fatal(rules)
+	}
+	if rules := schemaTestValidate(t, schema, "c.json", `{"database": {}, "max_conns": 50}`); len(rules) != 2 ||
+		rules["database.host"] != "required" || rules["max_conns"] != "maximum" {
+		t.Fatal(rules)
+	}
+	if rules := schemaTestValidate(t, schema, "c.json", `{"maxConns": 5, "database": {"host": "h"}}`); len(rules) != 1 || rules["maxConns"] != "additionalProperties" {
+		t.Fatal(rules)
+	}
+
+	if rules := schemaTestValidate(t, schema, "c.toml", "max-conns = 5\n[db]\nhost = \"h\"\n"); rules != nil {
+		t.Fatal(rules)
+	}
+	if rules := schemaTestValidate(t, schema, "c.yml", "maxConns: 5\ndatabase:\n  host: h\n"); rules != nil {
+		t.Fatal(rules)
+	}
+}
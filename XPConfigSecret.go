@@ -0,0 +1,365 @@
+package XPSuperKit
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+)
+
+/*********调用示例********
+type Config struct {
+	DB struct {
+		User     string
+		Password string `secret:"resolve"` // 解析 ${VAR}、file:// 及 enc:，并在 Explain、Dump 中显示为 ******
+		Token    string `secret:"resolve"`
+	}
+}
+
+// config.yml
+//   db:
+//     user: ${DB_USER:-root}             // 仅在 ResolveSecrets 为 true 时解析
+//     password: file:///run/secrets/db_password
+//     token: enc:AQEk3l...               // 由 EncryptConfigValue 生成
+
+// 生成主密钥，通过 XPCONFIG_MASTER_KEY 环境变量或 MasterKey 提供
+masterKey := XPSuperKit.GenerateConfigMasterKey()
+value, err := XPSuperKit.EncryptConfigValue(masterKey, "s3cr3t")
+
+cfg := XPSuperKit.NewXPConfig(&XPSuperKit.XPConfigEnvironment{
+	EnvFiles:       []string{".env", ".env.local"},
+	ResolveSecrets: true,
+})
+err = cfg.Load(&config, "config.yml")
+ ************************/
+
+const (
+	kConfigMasterKeyEnv     = "XPCONFIG_MASTER_KEY"
+	kConfigSecretResolve    = "resolve"
+	kConfigEncryptedPrefix  = "enc:"
+	kConfigFilePrefix       = "file://"
+	kConfigMasterKeySize    = 32
+	kConfigEncryptedContext = "XPConfig"
+)
+
+var (
+	// 配置中有 enc: 加密值，但未提供主密钥
+	ConfigErrMissingMasterKey = errors.New("config: master key is required to decrypt enc: values")
+	// 主密钥不是合法的 base64 编码的 16、24 或 32 字节密钥
+	ConfigErrInvalidMasterKey = errors.New("config: invalid master key")
+)
+
+// 生成 base64 编码的 32 字节随机主密钥
+func GenerateConfigMasterKey() string {
+	key := make([]byte, kConfigMasterKeySize)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+/**
+ * 使用主密钥加密配置值，返回 enc: 开头的字符串，可直接写入配置文件，Load 时自动解密
+ * @param masterKey string base64 编码的主密钥
+ * @param plaintext string 明文
+ */
+func EncryptConfigValue(masterKey, plaintext string) (string, error) {
+	key, err := decodeConfigMasterKey(masterKey)
+	if err != nil {
+		return "", err
+	}
+
+	crypt, err := AEADEncryptToString(AEAD_AES_GCM, key, []byte(plaintext), []byte(kConfigEncryptedContext))
+	if err != nil {
+		return "", err
+	}
+	return kConfigEncryptedPrefix + crypt, nil
+}
+
+// 解密 EncryptConfigValue 生成的值
+func DecryptConfigValue(masterKey, value string) (string, error) {
+	key, err := decodeConfigMasterKey(masterKey)
+	if err != nil {
+		return "", err
+	}
+
+	plaintext, err := AEADDecryptString(strings.TrimPrefix(value, kConfigEncryptedPrefix), key, []byte(kConfigEncryptedContext))
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+func decodeConfigMasterKey(masterKey string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(masterKey))
+	if err != nil {
+		return nil, ConfigErrInvalidMasterKey
+	}
+
+	switch len(key) {
+	case 16, 24, 32:
+		return key, nil
+	}
+	return nil, ConfigErrInvalidMasterKey
+}
+
+/**
+ * 读取 .env 文件并写入环境变量，已存在的环境变量不会被覆盖，靠前的文件优先
+ * 支持 KEY=VALUE、export KEY=VALUE、# 注释、单引号(原样)及双引号(支持 \n \t \" \\ 转义)
+ * @param files ...string .env 文件路径
+ */
+func LoadEnvFile(files ...string) error {
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+
+		values, err := ParseEnvFile(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%v: %v", file, err)
+		}
+
+		for key, value := range values {
+			if _, exists := os.LookupEnv(key); !exists {
+				if err := os.Setenv(key, value); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// 解析 .env 格式的内容，格式同 LoadEnvFile
+func ParseEnvFile(r io.Reader) (map[string]string, error) {
+	values := make(map[string]string)
+
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+		pos := strings.Index(line, "=")
+		if pos <= 0 {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", lineNumber)
+		}
+
+		key := strings.TrimSpace(line[:pos])
+		value, err := parseEnvValue(strings.TrimSpace(line[pos+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNumber, err)
+		}
+		values[key] = value
+	}
+
+	return values, scanner.Err()
+}
+
+func parseEnvValue(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+
+	switch quote := value[0]; quote {
+	case '\'':
+		end := strings.IndexByte(value[1:], '\'')
+		if end < 0 {
+			return "", errors.New("unterminated single quote")
+		}
+		return value[1 : end+1], nil
+	case '"':
+		var buf strings.Builder
+		for i := 1; i < len(value); i++ {
+			c := value[i]
+			if c == '"' {
+				return buf.String(), nil
+			}
+			if c == '\\' && i+1 < len(value) {
+				i++
+				switch value[i] {
+				case 'n':
+					c = '\n'
+				case 't':
+					c = '\t'
+				case 'r':
+					c = '\r'
+				default:
+					c = value[i]
+				}
+			}
+			buf.WriteByte(c)
+		}
+		return "", errors.New("unterminated double quote")
+	}
+
+	// 未加引号的值中 # 之后为注释
+	if pos := strings.Index(value, " #"); pos >= 0 {
+		value = value[:pos]
+	}
+	return strings.TrimSpace(value), nil
+}
+
+// Load 中调用，读取 EnvFiles 中的 .env 文件
+func (cfg *XPConfigImpl) processEnvFiles() error {
+	if len(cfg.EnvFiles) == 0 {
+		return nil
+	}
+	return LoadEnvFile(cfg.EnvFiles...)
+}
+
+// Load 中调用，解析 secret 标签为 resolve 的字段中的引用，ResolveSecrets 为 true 时解析全部字符串中的 ${VAR} 及 enc:
+func (cfg *XPConfigImpl) processSecrets(config interface{}) error {
+	resolver := &configSecretResolver{masterKey: cfg.MasterKey, all: cfg.ResolveSecrets}
+	if resolver.masterKey == "" {
+		resolver.masterKey = os.Getenv(kConfigMasterKeyEnv)
+	}
+
+	return resolver.resolve(reflect.ValueOf(config).Elem(), "", false)
+}
+
+type configSecretResolver struct {
+	masterKey string
+	all       bool //解析全部字符串中的 ${VAR} 及 enc:，file:// 仍只在 secret 标签为 resolve 的字段中解析
+}
+
+// 遍历结构体、指针、切片及 map 中的字符串，map 的值不可寻址，解析后重新写入
+// tagged 表示 value 位于 secret 标签为 resolve 的字段中
+func (resolver *configSecretResolver) resolve(value reflect.Value, path string, tagged bool) error {
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if value.IsNil() {
+			return nil
+		}
+		if value.Kind() == reflect.Interface && value.Elem().Kind() == reflect.String && value.CanSet() {
+			resolved, err := resolver.resolveString(value.Elem().String(), path, tagged)
+			if err != nil {
+				return err
+			}
+			value.Set(reflect.ValueOf(resolved))
+			return nil
+		}
+		return resolver.resolve(value.Elem(), path, tagged)
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			fieldStruct := value.Type().Field(i)
+			if fieldStruct.PkgPath != "" {
+				continue
+			}
+			fieldTagged := tagged || fieldStruct.Tag.Get(kConfigSecretTag) == kConfigSecretResolve
+			if err := resolver.resolve(value.Field(i), joinConfigPath(path, fieldStruct.Name), fieldTagged); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if err := resolver.resolve(value.Index(i), fmt.Sprintf("%v[%d]", path, i), tagged); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		for _, key := range value.MapKeys() {
+			element := reflect.New(value.Type().Elem()).Elem()
+			element.Set(value.MapIndex(key))
+			if err := resolver.resolve(element, fmt.Sprintf("%v[%v]", path, key.Interface()), tagged); err != nil {
+				return err
+			}
+			value.SetMapIndex(key, element)
+		}
+	case reflect.String:
+		if !value.CanSet() {
+			return nil
+		}
+		resolved, err := resolver.resolveString(value.String(), path, tagged)
+		if err != nil {
+			return err
+		}
+		value.SetString(resolved)
+	}
+
+	return nil
+}
+
+/**
+ * 解析单个字符串，未设置 ResolveSecrets 时只解析 secret 标签为 resolve 的字段
+ * enc:BASE64   使用主密钥解密
+ * file://PATH  读取文件内容，去掉末尾的换行，只在 secret 标签为 resolve 的字段中解析
+ * ${VAR}       替换为环境变量，未设置时报错；${VAR:-default} 未设置或为空时使用默认值；$${ 表示 ${ 本身
+ */
+func (resolver *configSecretResolver) resolveString(value, path string, tagged bool) (string, error) {
+	if !tagged && !resolver.all {
+		return value, nil
+	}
+
+	switch {
+	case strings.HasPrefix(value, kConfigEncryptedPrefix):
+		if resolver.masterKey == "" {
+			return "", fmt.Errorf("%v: %v", path, ConfigErrMissingMasterKey)
+		}
+		plaintext, err := DecryptConfigValue(resolver.masterKey, value)
+		if err != nil {
+			return "", fmt.Errorf("%v: failed to decrypt value: %v", path, err)
+		}
+		return plaintext, nil
+	case tagged && strings.HasPrefix(value, kConfigFilePrefix):
+		data, err := ioutil.ReadFile(strings.TrimPrefix(value, kConfigFilePrefix))
+		if err != nil {
+			return "", fmt.Errorf("%v: %v", path, err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	case strings.Contains(value, "${"):
+		return expandConfigEnv(value, path)
+	}
+
+	return value, nil
+}
+
+func expandConfigEnv(value, path string) (string, error) {
+	var buf strings.Builder
+
+	for {
+		start := strings.Index(value, "${")
+		if start < 0 {
+			buf.WriteString(value)
+			return buf.String(), nil
+		}
+
+		// $${ 为转义
+		if start > 0 && value[start-1] == '$' {
+			buf.WriteString(value[:start-1] + "${")
+			value = value[start+2:]
+			continue
+		}
+
+		end := strings.IndexByte(value[start:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("%v: unterminated ${ in %q", path, value)
+		}
+
+		name, defaultValue, hasDefault := value[start+2:start+end], "", false
+		if pos := strings.Index(name, ":-"); pos >= 0 {
+			name, defaultValue, hasDefault = name[:pos], name[pos+2:], true
+		}
+
+		env, exists := os.LookupEnv(name)
+		if env == "" && hasDefault {
+			env = defaultValue
+		} else if !exists {
+			return "", fmt.Errorf("%v: environment variable %v is not set", path, name)
+		}
+
+		buf.WriteString(value[:start] + env)
+		value = value[start+end+1:]
+	}
+}
@@ -0,0 +1,104 @@
+package XPSuperKit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type secretTestConfig struct {
+	User     string
+	Password string `secret:"resolve"`
+	Token    string `secret:"resolve"`
+	DSN      string
+	DataURL  string
+	Template string
+	Literal  string
+	Labels   map[string]string
+	List     []string
+}
+
+func TestConfigSecretResolution(t *testing.T) {
+	dir := t.TempDir()
+	masterKey := GenerateConfigMasterKey()
+	token, err := EncryptConfigValue(masterKey, "tok")
+	if err != nil || !strings.HasPrefix(token, kConfigEncryptedPrefix) {
+		t.Fatal(token, err)
+	}
+
+	passwordFile := filepath.Join(dir, "password")
+	ioutil.WriteFile(passwordFile, []byte("hunter2\n"), 0600)
+	envFile := filepath.Join(dir, ".env")
+	ioutil.WriteFile(envFile, []byte("# comment\nexport SECRET_TEST_USER=bob # comment\nSECRET_TEST_HOST=\"h\\tx\"\nSECRET_TEST_KEEP='a b'\nSECRET_TEST_PRESET=from-file\n"), 0600)
+	os.Setenv("SECRET_TEST_PRESET", "preset")
+	defer func() {
+		for _, key := range []string{"SECRET_TEST_USER", "SECRET_TEST_HOST", "SECRET_TEST_KEEP", "SECRET_TEST_PRESET"} {
+			os.Unsetenv(key)
+		}
+	}()
+
+	// 默认只解析 secret 标签为 resolve 的字段，不读取 .env
+	file := filepath.Join(dir, "config.yml")
+	ioutil.WriteFile(file, []byte(strings.Join([]string{
+		"user: ${SECRET_TEST_USER}",
+		"password: file://" + passwordFile,
+		"token: " + token,
+		"dataurl: file:///etc/hostname",
+		"template: hello ${name}",
+	}, "\n")), 0644)
+
+	var config secretTestConfig
+	if err := NewXPConfig(&XPConfigEnvironment{EnvironmentPrefix: "-", MasterKey: masterKey}).Load(&config, file); err != nil {
+		t.Fatal(err)
+	}
+	if config.User != "${SECRET_TEST_USER}" || config.Password != "hunter2" || config.Token != "tok" || config.DataURL != "file:///etc/hostname" || config.Template != "hello ${name}" {
+		t.Fatalf("%#v", config)
+	}
+	if _, loaded := os.LookupEnv("SECRET_TEST_USER"); loaded {
+		t.Fatal(".env was loaded without EnvFiles")
+	}
+
+	// 加密值需要主密钥
+	config = secretTestConfig{}
+	if err := NewXPConfig(&XPConfigEnvironment{EnvironmentPrefix: "-"}).Load(&config, file); err == nil || !strings.Contains(err.Error(), "Token") {
+		t.Fatalf("expected missing master key error, got %v", err)
+	}
+
+	// ResolveSecrets 解析全部字符串中的 ${VAR} 及 enc:，file:// 仍只在标记的字段中解析
+	ioutil.WriteFile(file, []byte(strings.Join([]string{
+		"user: ${SECRET_TEST_USER}",
+		"token: " + token,
+		"dsn: pg://${SECRET_TEST_USER}@${SECRET_TEST_HOST}/${SECRET_TEST_DB:-app}?p=${SECRET_TEST_PRESET}",
+		"dataurl: file:///etc/hostname",
+		"literal: $${X}",
+		"labels: {a: '${SECRET_TEST_KEEP}'}",
+		"list: ['${SECRET_TEST_USER}']",
+	}, "\n")), 0644)
+
+	config = secretTestConfig{}
+	env := &XPConfigEnvironment{EnvironmentPrefix: "-", EnvFiles: []string{envFile}, MasterKey: masterKey, ResolveSecrets: true}
+	if err := NewXPConfig(env).Load(&config, file); err != nil {
+		t.Fatal(err)
+	}
+	if config.User != "bob" || config.Token != "tok" || config.DSN != "pg://bob@h\tx/app?p=preset" || config.DataURL != "file:///etc/hostname" ||
+		config.Literal != "${X}" || config.Labels["a"] != "a b" || config.List[0] != "bob" {
+		t.Fatalf("%#v", config)
+	}
+
+	ioutil.WriteFile(file, []byte("user: ${SECRET_TEST_MISSING}\n"), 0644)
+	if err := NewXPConfig(env).Load(&secretTestConfig{}, file); err == nil || !strings.Contains(err.Error(), "SECRET_TEST_MISSING") {
+		t.Fatalf("expected unset variable error, got %v", err)
+	}
+}
+
+func TestParseEnvFile(t *testing.T) {
+	values, err := ParseEnvFile(strings.NewReader("A=1\nexport B='x y'\nC=\"a\\nb\" # comment\n"))
+	if err != nil || values["A"] != "1" || values["B"] != "x y" || values["C"] != "a\nb" {
+		t.Fatalf("%#v %v", values, err)
+	}
+	if _, err := ParseEnvFile(strings.NewReader("A=1\nBAD\n")); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Fatalf("expected line 2 error, got %v", err)
+	}
+}
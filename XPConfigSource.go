@@ -0,0 +1,487 @@
+package XPSuperKit
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-yaml/yaml"
+)
+
+/*********调用示例********
+type Config struct {
+	DB struct {
+		Host     string `default:"localhost"`
+		Password string `secret:"true"` // Explain 及 Dump 中显示为 ******
+	}
+}
+
+cfg := XPSuperKit.XPConfig()
+err := cfg.Load(&config, "config.yml")
+
+// 查看单个字段的值及依次设置它的来源
+explanation, err := cfg.Explain(&config, "DB.Host")
+// DB.Host = "db.internal"
+//   default                    "localhost"
+//   file config.yml:3          "db.local"
+//   env XPCONFIG_DB_HOST       "db.internal" (effective)
+
+// 以 YAML 格式输出当前生效的配置，注释中为每个字段的来源
+cfg.Dump(&config, os.Stdout)
+ ************************/
+
+const (
+	kConfigSecretTag  = "secret"
+	kConfigSecretMask = "******"
+)
+
+type ConfigSourceKind string
+
+const (
+	CONFIG_SOURCE_DEFAULT ConfigSourceKind = "default" //default 标签
+	CONFIG_SOURCE_FILE    ConfigSourceKind = "file"    //配置文件
+	CONFIG_SOURCE_ENV     ConfigSourceKind = "env"     //环境变量
+	CONFIG_SOURCE_FLAG    ConfigSourceKind = "flag"    //命令行参数
+)
+
+// 字段值的来源
+type ConfigSource struct {
+	Kind  ConfigSourceKind
+	Name  string      //配置文件路径、环境变量名或命令行参数名
+	Line  int         //配置文件中的行号，按键名查找，找不到时为 0
+	Value interface{} //该来源设置的值
+}
+
+func (s ConfigSource) String() string {
+	switch s.Kind {
+	case CONFIG_SOURCE_FILE:
+		if s.Line > 0 {
+			return fmt.Sprintf("file %v:%d", s.Name, s.Line)
+		}
+		return "file " + s.Name
+	case CONFIG_SOURCE_FLAG:
+		return "flag --" + s.Name
+	case CONFIG_SOURCE_DEFAULT:
+		return string(s.Kind)
+	}
+	return string(s.Kind) + " " + s.Name
+}
+
+// 一次加载中每个字段的来源，按设置的先后顺序排列，最后一个为生效的来源
+type configSources struct {
+	lock   sync.Mutex
+	fields map[string][]ConfigSource
+}
+
+func (sources *configSources) record(path string, source ConfigSource) {
+	if sources == nil {
+		return
+	}
+
+	sources.lock.Lock()
+	defer sources.lock.Unlock()
+	sources.fields[path] = append(sources.fields[path], source)
+}
+
+func (sources *configSources) get(path string) []ConfigSource {
+	if sources == nil {
+		return nil
+	}
+
+	sources.lock.Lock()
+	defer sources.lock.Unlock()
+	return append([]ConfigSource(nil), sources.fields[path]...)
+}
+
+// Load 开始时清空 config 之前的来源记录
+func (cfg *XPConfigImpl) resetConfigSources(config interface{}) *configSources {
+	sources := &configSources{fields: make(map[string][]ConfigSource)}
+	cfg.provenance.Store(config, sources)
+	return sources
+}
+
+func (cfg *XPConfigImpl) configSources(config interface{}) *configSources {
+	sources, _ := cfg.provenance.LoadOrStore(config, &configSources{fields: make(map[string][]ConfigSource)})
+	return sources.(*configSources)
+}
+
+// Load 记录的来源，config 没有被加载过时返回 nil，不创建新的记录
+func (cfg *XPConfigImpl) loadedConfigSources(config interface{}) *configSources {
+	if sources, ok := cfg.provenance.Load(config); ok {
+		return sources.(*configSources)
+	}
+	return nil
+}
+
+// 不再使用的配置，如 Watch 替换掉的旧配置，释放其来源记录
+func (cfg *XPConfigImpl) forgetConfigSources(config interface{}) {
+	cfg.provenance.Delete(config)
+}
+
+/**
+ * 获取字段的来源，按设置的先后顺序排列，最后一个为生效的来源
+ * 与 Explain 一致，secret 标签为 true 或 resolve 的字段及其子字段中非空的 Value 为 ******
+ * @param config interface{} 传给 Load 的结构体指针
+ * @param path string 字段路径，如 DB.Host、Servers[0].Port
+ */
+func (cfg *XPConfigImpl) Sources(config interface{}, path string) []ConfigSource {
+	sources := cfg.loadedConfigSources(config).get(path)
+
+	if _, secret, err := lookupConfigField(config, path); err == nil && secret {
+		for i := range sources {
+			if sources[i].Value != nil && !isZeroValue(reflect.ValueOf(sources[i].Value)) {
+				sources[i].Value = kConfigSecretMask
+			}
+		}
+	}
+	return sources
+}
+
+/**
+ * 说明字段当前的值以及依次设置它的来源，secret 标签为 true 或 resolve 的字段及其子字段的值显示为 ******
+ * @param config interface{} 传给 Load 的结构体指针
+ * @param path string 字段路径，如 DB.Host、Servers[0].Port
+ */
+func (cfg *XPConfigImpl) Explain(config interface{}, path string) (string, error) {
+	field, secret, err := lookupConfigField(config, path)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%v = %v\n", path, formatConfigValue(field.Interface(), secret))
+
+	sources := cfg.Sources(config, path)
+	if len(sources) == 0 {
+		buf.WriteString("  not set by any source\n")
+	}
+	for i, source := range sources {
+		fmt.Fprintf(&buf, "  %-30v %v", source.String(), formatConfigValue(source.Value, secret))
+		if i == len(sources)-1 {
+			buf.WriteString(" (effective)")
+		}
+		buf.WriteString("\n")
+	}
+
+	return buf.String(), nil
+}
+
+// 以 YAML 格式输出当前生效的配置，每个字段后以注释标明来源，secret 标签为 true 或 resolve 的字段显示为 ******
+func (cfg *XPConfigImpl) Dump(config interface{}, w io.Writer) error {
+	configValue := reflect.ValueOf(config)
+	if configValue.Kind() != reflect.Ptr || configValue.Elem().Kind() != reflect.Struct {
+		return ErrorN("invalid config, should be pointer to struct")
+	}
+
+	var sources *configSources
+	if value, ok := cfg.provenance.Load(config); ok {
+		sources = value.(*configSources)
+	}
+
+	var buf bytes.Buffer
+	if err := dumpConfigStruct(&buf, configValue.Elem(), "", 0, false, sources); err != nil {
+		return err
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func dumpConfigStruct(buf *bytes.Buffer, structValue reflect.Value, path string, indent int, secret bool, sources *configSources) error {
+	structType := structValue.Type()
+	prefix := strings.Repeat(" ", indent)
+
+	for i := 0; i < structType.NumField(); i++ {
+		fieldStruct := structType.Field(i)
+		if fieldStruct.PkgPath != "" {
+			continue
+		}
+
+		key, inline, skip := configYamlKey(&fieldStruct)
+		if skip {
+			continue
+		}
+
+		field := structValue.Field(i)
+		fieldPath := joinConfigPath(path, fieldStruct.Name)
+		fieldSecret := secret || isConfigSecretField(&fieldStruct)
+
+		comment := ""
+		if fieldSources := sources.get(fieldPath); len(fieldSources) > 0 {
+			comment = " # " + fieldSources[len(fieldSources)-1].String()
+		}
+
+		value := reflect.Indirect(field)
+		if value.Kind() == reflect.Struct && hasExportedField(value) {
+			if inline {
+				if err := dumpConfigStruct(buf, value, fieldPath, indent, fieldSecret, sources); err != nil {
+					return err
+				}
+				continue
+			}
+
+			buf.WriteString(prefix + key + ":" + comment + "\n")
+			if err := dumpConfigStruct(buf, value, fieldPath, indent+2, fieldSecret, sources); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var text string
+		if fieldSecret && !isZeroValue(field) {
+			text = strconv.Quote(kConfigSecretMask)
+		} else {
+			data, err := yaml.Marshal(field.Interface())
+			if err != nil {
+				return err
+			}
+			text = strings.TrimSuffix(string(data), "\n")
+		}
+
+		// 非空的切片及 map 使用块格式
+		block := (value.Kind() == reflect.Slice || value.Kind() == reflect.Map || value.Kind() == reflect.Array) && value.Len() > 0
+		if !strings.Contains(text, "\n") && (!block || text == strconv.Quote(kConfigSecretMask)) {
+			buf.WriteString(prefix + key + ": " + text + comment + "\n")
+			continue
+		}
+
+		buf.WriteString(prefix + key + ":" + comment + "\n")
+		for _, line := range strings.Split(text, "\n") {
+			buf.WriteString(prefix + "  " + line + "\n")
+		}
+	}
+
+	return nil
+}
+
+// secret 标签为 true 或 resolve 的字段在 Explain、Dump 及写出的配置中隐藏
+func isConfigSecretField(fieldStruct *reflect.StructField) bool {
+	tag := fieldStruct.Tag.Get(kConfigSecretTag)
+	return tag == "true" || tag == kConfigSecretResolve
+}
+
+// 与 go-yaml 的规则一致：yaml 标签中的名称，否则为小写的字段名
+func configYamlKey(fieldStruct *reflect.StructField) (key string, inline bool, skip bool) {
+	tag := fieldStruct.Tag.Get("yaml")
+	if tag == "-" {
+		return "", false, true
+	}
+
+	options := strings.Split(tag, ",")
+	for _, option := range options[1:] {
+		if option == "inline" {
+			inline = true
+		}
+	}
+
+	if options[0] != "" {
+		return options[0], inline, false
+	}
+	return strings.ToLower(fieldStruct.Name), inline, false
+}
+
+func formatConfigValue(value interface{}, secret bool) string {
+	if value == nil {
+		return "<nil>"
+	}
+
+	v := reflect.ValueOf(value)
+	if secret && !isZeroValue(v) {
+		return kConfigSecretMask
+	}
+
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+
+	if v.Kind() == reflect.String {
+		return strconv.Quote(v.String())
+	}
+	if stringer, ok := v.Interface().(fmt.Stringer); ok {
+		return stringer.String()
+	}
+	return fmt.Sprintf("%v", v.Interface())
+}
+
+func joinConfigPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// 按路径查找字段，secret 表示路径上是否有 secret 标签为 true 或 resolve 的字段
+func lookupConfigField(config interface{}, path string) (field reflect.Value, secret bool, err error) {
+	field = reflect.ValueOf(config)
+	if field.Kind() != reflect.Ptr || field.Elem().Kind() != reflect.Struct {
+		return field, false, ErrorN("invalid config, should be pointer to struct")
+	}
+
+	for _, segment := range strings.Split(path, ".") {
+		name, indexes := segment, []int(nil)
+		if pos := strings.Index(segment, "["); pos > 0 {
+			name = segment[:pos]
+			for _, index := range strings.Split(strings.TrimSuffix(segment[pos+1:], "]"), "][") {
+				i, err := strconv.Atoi(index)
+				if err != nil {
+					return field, false, fmt.Errorf("invalid config path %v", path)
+				}
+				indexes = append(indexes, i)
+			}
+		}
+
+		field = reflect.Indirect(field)
+		if field.Kind() != reflect.Struct {
+			return field, false, fmt.Errorf("unknown config field %v", path)
+		}
+
+		fieldStruct, ok := field.Type().FieldByName(name)
+		if !ok || fieldStruct.PkgPath != "" {
+			return field, false, fmt.Errorf("unknown config field %v", path)
+		}
+		secret = secret || isConfigSecretField(&fieldStruct)
+		field = field.FieldByIndex(fieldStruct.Index)
+
+		for _, i := range indexes {
+			field = reflect.Indirect(field)
+			if (field.Kind() != reflect.Slice && field.Kind() != reflect.Array) || i < 0 || i >= field.Len() {
+				return field, false, fmt.Errorf("config field %v out of range", path)
+			}
+			field = field.Index(i)
+		}
+	}
+
+	return field, secret, nil
+}
+
+// 记录配置文件中设置的字段，layer 为该文件单独解析得到的结构体，values 为原始解码结果，与合并的规则一致：
+// values 不为 nil 时记录文件中出现的键，否则只记录非空的字段
+func recordConfigFile(sources *configSources, layer reflect.Value, values map[string]interface{}, format, file string) {
+	data, _ := ioutil.ReadFile(file)
+	lines := strings.Split(string(data), "\n")
+	recordConfigLayer(sources, layer, values, format, "", file, lines, configKeyScope{indent: -1})
+}
+
+func recordConfigLayer(sources *configSources, structValue reflect.Value, values map[string]interface{}, format, path, file string, lines []string, scope configKeyScope) {
+	structType := structValue.Type()
+
+	for i := 0; i < structType.NumField(); i++ {
+		fieldStruct := structType.Field(i)
+		if fieldStruct.PkgPath != "" {
+			continue
+		}
+
+		field := structValue.Field(i)
+		fieldPath := joinConfigPath(path, fieldStruct.Name)
+		fieldLine, fieldScope := findConfigKeyLine(lines, scope, configKeyNames(&fieldStruct))
+
+		var fieldValues map[string]interface{}
+		if values != nil {
+			raw, present := lookupConfigRawKey(values, &fieldStruct, format)
+			if !present {
+				continue
+			}
+			fieldValues, _ = raw.(map[string]interface{})
+		}
+
+		value := reflect.Indirect(field)
+		if value.Kind() == reflect.Struct && hasExportedField(value) && (values == nil || fieldValues != nil) {
+			recordConfigLayer(sources, value, fieldValues, format, fieldPath, file, lines, fieldScope)
+			continue
+		}
+
+		if values == nil && (isEmptyValue(field) || (field.Kind() == reflect.Struct && isZeroValue(field))) {
+			continue
+		}
+
+		sources.record(fieldPath, ConfigSource{Kind: CONFIG_SOURCE_FILE, Name: file, Line: fieldLine, Value: field.Interface()})
+	}
+}
+
+// 字段在配置文件中可能使用的键名
+func configKeyNames(fieldStruct *reflect.StructField) []string {
+	names := []string{fieldStruct.Name}
+	for _, tagName := range []string{"yaml", "toml", "json"} {
+		if name := strings.Split(fieldStruct.Tag.Get(tagName), ",")[0]; name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// 查找键所在行的范围：from 行之后，缩进为 indent 的键之下的子键，或 TOML、INI 中表头为 table 的表中的键
+type configKeyScope struct {
+	from   int
+	indent int    //上层键的缩进，-1 表示文件顶层或表头下
+	table  string //所在的表头，如 database.pool
+}
+
+/**
+ * 在 scope 内查找以 names 之一为键的行，忽略大小写，返回行号 (从 1 开始) 及其子键的查找范围，找不到时行号为 0
+ * YAML、JSON 按缩进区分层级，只匹配 scope 内第一层的键；TOML、INI 按表头区分层级，子表的表头如 [database.pool] 作为键匹配
+ */
+func findConfigKeyLine(lines []string, scope configKeyScope, names []string) (int, configKeyScope) {
+	notFound := configKeyScope{from: len(lines), indent: -1}
+	table, childIndent := scope.table, -1
+
+	for i := scope.from; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "//") ||
+			strings.Trim(line, "{}[], \t") == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") && !strings.ContainsAny(line, ",\"'") {
+			table = strings.Trim(line, "[] \t")
+			name := table
+			if scope.table != "" {
+				if !strings.HasPrefix(table, scope.table+".") {
+					continue
+				}
+				name = strings.TrimPrefix(table, scope.table+".")
+			}
+			if scope.indent < 0 && !strings.Contains(name, ".") && matchConfigKeyName(name+"=", names) {
+				return i + 1, configKeyScope{from: i + 1, indent: -1, table: table}
+			}
+			continue
+		}
+
+		if table != scope.table {
+			continue
+		}
+
+		indent := len(lines[i]) - len(strings.TrimLeft(lines[i], " \t"))
+		if indent <= scope.indent {
+			break
+		}
+		if childIndent < 0 {
+			childIndent = indent
+		}
+		if indent != childIndent {
+			continue
+		}
+
+		if matchConfigKeyName(strings.TrimLeft(line, "-\"' \t"), names) {
+			return i + 1, configKeyScope{from: i + 1, indent: indent, table: table}
+		}
+	}
+	return 0, notFound
+}
+
+// line 是否以 names 之一为键，键后为 : 或 =
+func matchConfigKeyName(line string, names []string) bool {
+	for _, name := range names {
+		if len(line) <= len(name) || !strings.EqualFold(line[:len(name)], name) {
+			continue
+		}
+		if rest := strings.TrimLeft(line[len(name):], "\"' \t"); strings.HasPrefix(rest, ":") || strings.HasPrefix(rest, "=") {
+			return true
+		}
+	}
+	return false
+}
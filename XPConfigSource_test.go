@@ -0,0 +1,104 @@
+package XPSuperKit
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type sourceTestConfig struct {
+	Name string `default:"n"`
+	Host string
+	DB   struct {
+		Host     string `default:"localhost"`
+		Port     int
+		Password string `secret:"true"`
+	}
+	Tags []string
+}
+
+func sourceTestFile(t *testing.T, name, content string) string {
+	file := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestConfigSources(t *testing.T) {
+	file := sourceTestFile(t, "c.yml", "tags: [a]\ndb:\n  port: 1\n  host: h\n  password: pw\n")
+	os.Setenv("SRCT_DB_HOST", "envhost")
+	defer os.Unsetenv("SRCT_DB_HOST")
+
+	cfg := NewXPConfig(&XPConfigEnvironment{EnvironmentPrefix: "SRCT", Flags: true, Args: []string{"--db-port", "9"}})
+	var c sourceTestConfig
+	if err := cfg.Load(&c, file); err != nil {
+		t.Fatal(err)
+	}
+
+	if s := cfg.Sources(&c, "DB.Host"); len(s) != 2 || s[0].Kind != CONFIG_SOURCE_FILE || s[0].Line != 4 ||
+		s[1].Name != "SRCT_DB_HOST" {
+		t.Fatal(s)
+	}
+	if s := cfg.Sources(&c, "DB.Port"); len(s) != 2 || s[0].Line != 3 || s[1].Kind != CONFIG_SOURCE_FLAG {
+		t.Fatal(s)
+	}
+	if s := cfg.Sources(&c, "Name"); len(s) != 1 || s[0].Kind != CONFIG_SOURCE_DEFAULT {
+		t.Fatal(s)
+	}
+
+	// Sources 与 Explain、Dump 一致，不返回 secret 字段的明文
+	if s := cfg.Sources(&c, "DB.Password"); len(s) != 1 || s[0].Value != kConfigSecretMask {
+		t.Fatal(s)
+	}
+	if c.DB.Password != "pw" {
+		t.Fatalf("password %q", c.DB.Password)
+	}
+
+	explanation, err := cfg.Explain(&c, "DB.Password")
+	if err != nil || strings.Contains(explanation, "pw") || !strings.Contains(explanation, kConfigSecretMask) {
+		t.Fatal(explanation, err)
+	}
+	if _, err := cfg.Explain(&c, "DB.Nope"); err == nil {
+		t.Fatal("unknown field should fail")
+	}
+
+	var buf bytes.Buffer
+	if err := cfg.Dump(&c, &buf); err != nil {
+		t.Fatal(err)
+	}
+	dump := buf.String()
+	if strings.Contains(dump, "pw") || !strings.Contains(dump, "  host: envhost # env SRCT_DB_HOST") ||
+		!strings.Contains(dump, "tags: # file "+file+":1\n  - a") {
+		t.Fatal(dump)
+	}
+}
+
+// 顶层的键不应匹配到之前其他块中的同名子键
+func TestConfigSourcesNestedKeyLine(t *testing.T) {
+	for name, content := range map[string]string{
+		"c.yml":  "db:\n  host: dbhost\n  port: 1\nhost: top\n",
+		"c.json": "{\n  \"db\": {\n    \"host\": \"dbhost\",\n    \"port\": 1\n  },\n  \"host\": \"top\"\n}\n",
+		"c.toml": "host = \"top\"\n\n[db]\nhost = \"dbhost\"\nport = 1\n",
+	} {
+		file := sourceTestFile(t, name, content)
+		var c sourceTestConfig
+		cfg := NewXPConfig(nil)
+		if err := cfg.Load(&c, file); err != nil {
+			t.Fatal(name, err)
+		}
+
+		want := map[string]int{"c.yml": 4, "c.json": 6, "c.toml": 1}[name]
+		if s := cfg.Sources(&c, "Host"); len(s) != 1 || s[0].Line != want {
+			t.Fatalf("%v: Host %v, want line %d", name, s, want)
+		}
+
+		want = map[string]int{"c.yml": 2, "c.json": 3, "c.toml": 4}[name]
+		if s := cfg.Sources(&c, "DB.Host"); len(s) != 1 || s[0].Line != want {
+			t.Fatalf("%v: DB.Host %v, want line %d", name, s, want)
+		}
+	}
+}
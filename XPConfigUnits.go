@@ -0,0 +1,227 @@
+package XPSuperKit
+
+import (
+	"encoding"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-yaml/yaml"
+)
+
+/*********调用示例********
+type Config struct {
+	MaxBody  XPSuperKit.ByteSize `default:"10MiB"`
+	Timeout  time.Duration       `default:"30s"`
+	Retain   time.Duration       `default:"7d"`  // 环境变量、命令行参数及 INI 中支持 d (天)
+	Tags     []string                            // XPCONFIG_TAGS=a,b,c 或 XPCONFIG_TAGS_0=a
+	Replicas []struct{ Host string }             // XPCONFIG_REPLICAS_0_HOST=db1
+	Limits   map[string]int                      // XPCONFIG_LIMITS_FOO=10 => Limits["foo"]
+}
+ ************************/
+
+// 字节数，可从 512、64KB、1.5GiB 等格式解析
+// KB、MB、GB、TB、PB 为 1000 的倍数，KiB、MiB、GiB、TiB、PiB 及单字母 K、M、G、T、P 为 1024 的倍数
+type ByteSize int64
+
+const (
+	KiB ByteSize = 1 << (10 * (iota + 1))
+	MiB
+	GiB
+	TiB
+	PiB
+)
+
+var byteSizeUnits = map[string]float64{
+	"":    1,
+	"B":   1,
+	"KB":  1e3,
+	"MB":  1e6,
+	"GB":  1e9,
+	"TB":  1e12,
+	"PB":  1e15,
+	"K":   float64(KiB),
+	"M":   float64(MiB),
+	"G":   float64(GiB),
+	"T":   float64(TiB),
+	"P":   float64(PiB),
+	"KIB": float64(KiB),
+	"MIB": float64(MiB),
+	"GIB": float64(GiB),
+	"TIB": float64(TiB),
+	"PIB": float64(PiB),
+}
+
+var byteSizeFormatUnits = []struct {
+	unit string
+	size ByteSize
+}{{"PiB", PiB}, {"TiB", TiB}, {"GiB", GiB}, {"MiB", MiB}, {"KiB", KiB}}
+
+// 解析字节数，单位忽略大小写，数字与单位之间可以有空格
+func ParseByteSize(s string) (ByteSize, error) {
+	s = strings.TrimSpace(s)
+	end := 0
+	for end < len(s) && (s[end] >= '0' && s[end] <= '9' || s[end] == '.' || (end == 0 && (s[end] == '-' || s[end] == '+'))) {
+		end++
+	}
+
+	number, err := strconv.ParseFloat(s[:end], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid byte size %q", s)
+	}
+
+	unit, ok := byteSizeUnits[strings.ToUpper(strings.TrimSpace(s[end:]))]
+	if !ok {
+		return 0, fmt.Errorf("invalid byte size unit in %q", s)
+	}
+
+	size := number * unit
+	if size > math.MaxInt64 || size < math.MinInt64 {
+		return 0, fmt.Errorf("byte size %q out of range", s)
+	}
+	return ByteSize(size), nil
+}
+
+// 能被整除时使用最大的 IEC 单位，如 10MiB，否则为字节数
+func (size ByteSize) String() string {
+	for _, u := range byteSizeFormatUnits {
+		if size != 0 && size%u.size == 0 {
+			return strconv.FormatInt(int64(size/u.size), 10) + u.unit
+		}
+	}
+	return strconv.FormatInt(int64(size), 10)
+}
+
+func (size ByteSize) MarshalText() ([]byte, error) {
+	return []byte(size.String()), nil
+}
+
+func (size *ByteSize) UnmarshalText(text []byte) error {
+	parsed, err := ParseByteSize(string(text))
+	if err != nil {
+		return err
+	}
+	*size = parsed
+	return nil
+}
+
+// 支持数字及字符串
+func (size *ByteSize) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var text string
+	if err := unmarshal(&text); err != nil {
+		return err
+	}
+	return size.UnmarshalText([]byte(text))
+}
+
+// 支持数字及字符串
+func (size *ByteSize) UnmarshalJSON(data []byte) error {
+	return size.UnmarshalText([]byte(strings.Trim(string(data), `"`)))
+}
+
+// 在 time.ParseDuration 的基础上支持 d (24 小时)，如 7d、1d12h
+func parseConfigDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if pos := strings.Index(s, "d"); pos > 0 {
+		// 符号作用于整个时长，如 -1d2h 为 -26h
+		text, negative := s, false
+		if s[0] == '-' || s[0] == '+' {
+			text, negative = s[1:], s[0] == '-'
+			pos--
+		}
+		if pos <= 0 || strings.ContainsAny(text[:1], "+-") {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+
+		days, err := strconv.ParseFloat(text[:pos], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+
+		duration := time.Duration(days * float64(24*time.Hour))
+		if rest := text[pos+1:]; rest != "" {
+			extra, err := time.ParseDuration(rest)
+			if err != nil || strings.ContainsAny(rest[:1], "+-") {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			duration += extra
+		}
+		if negative {
+			duration = -duration
+		}
+		return duration, nil
+	}
+
+	// 纯数字与 YAML 的处理一致，单位为纳秒
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Duration(n), nil
+	}
+	return time.ParseDuration(s)
+}
+
+/**
+ * 将字符串写入字段，用于环境变量、命令行参数、INI 及 properties
+ * 实现 encoding.TextUnmarshaler 的类型调用 UnmarshalText，字符串原样写入，time.Duration 支持 d，
+ * 切片可使用 a,b,c 或 YAML 格式 [a, b, c]，其他类型按 YAML 解析
+ */
+func setConfigScalar(field reflect.Value, value string) error {
+	for field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+		field = field.Elem()
+	}
+
+	if unmarshaler, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return unmarshaler.UnmarshalText([]byte(value))
+	}
+
+	switch {
+	case field.Type() == configDurationType:
+		duration, err := parseConfigDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(duration))
+		return nil
+	case field.Kind() == reflect.String:
+		field.SetString(value)
+		return nil
+	case field.Kind() == reflect.Slice && field.Type().Elem().Kind() != reflect.Uint8 && !strings.HasPrefix(strings.TrimSpace(value), "["):
+		return setConfigList(field, value)
+	}
+
+	return yaml.Unmarshal([]byte(value), field.Addr().Interface())
+}
+
+// 以逗号分隔的列表，\, 表示逗号本身
+func setConfigList(field reflect.Value, value string) error {
+	var items []string
+	if strings.TrimSpace(value) != "" {
+		var item strings.Builder
+		for i := 0; i < len(value); i++ {
+			if value[i] == '\\' && i+1 < len(value) && value[i+1] == ',' {
+				item.WriteByte(',')
+				i++
+			} else if value[i] == ',' {
+				items = append(items, strings.TrimSpace(item.String()))
+				item.Reset()
+			} else {
+				item.WriteByte(value[i])
+			}
+		}
+		items = append(items, strings.TrimSpace(item.String()))
+	}
+
+	list := reflect.MakeSlice(field.Type(), len(items), len(items))
+	for i, item := range items {
+		if err := setConfigScalar(list.Index(i), item); err != nil {
+			return fmt.Errorf("item %d: %v", i, err)
+		}
+	}
+	field.Set(list)
+	return nil
+}
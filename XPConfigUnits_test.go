@@ -0,0 +1,59 @@
+package XPSuperKit
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseByteSize(t *testing.T) {
+	for text, want := range map[string]ByteSize{"512": 512, "64KB": 64000, "2k": 2048, "1 mb": 1e6, "1.5GiB": 3 * GiB / 2} {
+		if got, err := ParseByteSize(text); err != nil || got != want {
+			t.Fatalf("ParseByteSize(%q) = %v, %v, want %v", text, got, err, want)
+		}
+	}
+	for _, text := range []string{"", "MB", "1XB", "1e30PB"} {
+		if _, err := ParseByteSize(text); err == nil {
+			t.Fatalf("ParseByteSize(%q) should fail", text)
+		}
+	}
+
+	if text := ByteSize(10 * MiB).String(); text != "10MiB" {
+		t.Fatalf("String() = %q", text)
+	}
+	if text := ByteSize(1000).String(); text != "1000" {
+		t.Fatalf("String() = %q", text)
+	}
+}
+
+func TestParseConfigDuration(t *testing.T) {
+	for text, want := range map[string]time.Duration{"7d": 7 * 24 * time.Hour, "1d2h": 26 * time.Hour, "1.5h": 90 * time.Minute, "100": 100,
+		"-1d2h": -26 * time.Hour, "+1d": 24 * time.Hour, "-0.5d": -12 * time.Hour} {
+		if got, err := parseConfigDuration(text); err != nil || got != want {
+			t.Fatalf("parseConfigDuration(%q) = %v, %v, want %v", text, got, err, want)
+		}
+	}
+	for _, text := range []string{"xd", "-d", "--1d", "1d-2h", "-1d+2h"} {
+		if _, err := parseConfigDuration(text); err == nil {
+			t.Fatalf("invalid duration %q accepted", text)
+		}
+	}
+}
+
+func TestSetConfigScalarList(t *testing.T) {
+	var config struct {
+		Tags  []string
+		Ports []int
+	}
+	value := reflect.ValueOf(&config).Elem()
+
+	if err := setConfigScalar(value.Field(0), `a, b\,c`); err != nil || !reflect.DeepEqual(config.Tags, []string{"a", "b,c"}) {
+		t.Fatalf("%q %v", config.Tags, err)
+	}
+	if err := setConfigScalar(value.Field(0), "[x, y]"); err != nil || !reflect.DeepEqual(config.Tags, []string{"x", "y"}) {
+		t.Fatalf("%q %v", config.Tags, err)
+	}
+	if err := setConfigScalar(value.Field(1), "80,x"); err == nil {
+		t.Fatalf("invalid list item accepted: %v", config.Ports)
+	}
+}
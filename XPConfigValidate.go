@@ -0,0 +1,433 @@
+package XPSuperKit
+
+import (
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+/*********调用示例********
+type Config struct {
+	Env     string        `validate:"oneof=development test production"`
+	Port    int           `default:"8080" validate:"port"`
+	Workers int           `validate:"min=1,max=64"`
+	Timeout time.Duration `validate:"min=100ms,max=1m"`
+	Admin   string        `validate:"omitempty,email"`
+	Webhook string        `validate:"required_if=Env production,url"`
+	MinConn int
+	MaxConn int           `validate:"gtefield=MinConn"`
+	Name    string        `validate:"regex=^[a-z][a-z0-9-]*$"`
+}
+
+// Load 在加载完成后校验所有字段，返回的 *ConfigValidationError 列出全部不合法的字段
+err := XPSuperKit.XPConfig().Load(&config, "config.yml")
+ ************************/
+
+// validate 标签中的规则以逗号分隔，regex 规则会使用之后的全部内容，因此必须放在最后
+//
+//	required                 不能为零值，等同于 required:"true"
+//	omitempty                为零值时跳过之后的规则
+//	min=N / max=N / len=N    数值的大小，字符串、切片、map 的长度，time.Duration 可使用 1s 等格式
+//	oneof=a b c              必须为列出的值之一，以空格分隔
+//	regex=PATTERN            字符串必须匹配正则表达式
+//	url / email / ip         字符串格式
+//	duration                 字符串可被 time.ParseDuration 解析
+//	port                     1 到 65535 之间的端口号
+//	eqfield=F / nefield=F    与同一结构体中的字段 F 相等或不相等
+//	gtfield=F / gtefield=F / ltfield=F / ltefield=F  与字段 F 比较大小
+//	required_if=F V          字段 F 的值为 V 时不能为零值
+//	required_with=F          字段 F 不为零值时不能为零值
+const kConfigValidateTag = "validate"
+
+// 配置实现该接口时，Load 在 validate 标签校验通过后调用 Validate，Watch 重新加载时返回错误则拒绝新配置
+type ConfigValidator interface {
+	Validate() error
+}
+
+// 单个字段的校验错误
+type ConfigFieldError struct {
+	Path    string //字段路径，如 Database.Hosts[0].Port
+	Rule    string //未通过的规则
+	Value   interface{}
+	Message string
+}
+
+func (e *ConfigFieldError) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+// 配置校验错误，包含所有不合法的字段
+type ConfigValidationError struct {
+	Errors []*ConfigFieldError
+}
+
+func (e *ConfigValidationError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = "  " + err.Error()
+	}
+	return fmt.Sprintf("config validation failed with %d error(s):\n%s", len(e.Errors), strings.Join(messages, "\n"))
+}
+
+// 按 validate 标签校验配置并汇总全部错误，配置实现 ConfigValidator 时在标签校验通过后调用 Validate
+func ValidateConfig(config interface{}) error {
+	configValue := reflect.Indirect(reflect.ValueOf(config))
+	if configValue.Kind() != reflect.Struct {
+		return ErrorN("invalid config, should be struct")
+	}
+
+	result := &ConfigValidationError{}
+	validateStruct(configValue, "", result)
+
+	if len(result.Errors) > 0 {
+		return result
+	}
+
+	if validator, ok := config.(ConfigValidator); ok {
+		return validator.Validate()
+	}
+
+	return nil
+}
+
+func validateStruct(structValue reflect.Value, path string, result *ConfigValidationError) {
+	structType := structValue.Type()
+
+	for i := 0; i < structType.NumField(); i++ {
+		fieldStruct := structType.Field(i)
+		if fieldStruct.PkgPath != "" {
+			continue
+		}
+
+		field := structValue.Field(i)
+		fieldPath := fieldStruct.Name
+		if path != "" {
+			fieldPath = path + "." + fieldStruct.Name
+		}
+
+		rules := parseValidateRules(fieldStruct.Tag.Get(kConfigValidateTag))
+		if fieldStruct.Tag.Get("required") == "true" {
+			rules = append([]configRule{{name: "required"}}, rules...)
+		}
+
+		// 每个字段只报告第一个未通过的规则
+		for _, rule := range rules {
+			if rule.name == "omitempty" {
+				if isZeroValue(field) {
+					break
+				}
+				continue
+			}
+
+			// 空指针只检查 required 类规则
+			if field.Kind() == reflect.Ptr && field.IsNil() && !strings.HasPrefix(rule.name, "required") {
+				continue
+			}
+
+			if message := rule.check(field, structValue); message != "" {
+				result.Errors = append(result.Errors, &ConfigFieldError{
+					Path:    fieldPath,
+					Rule:    rule.name,
+					Value:   field.Interface(),
+					Message: message,
+				})
+				break
+			}
+		}
+
+		validateNested(field, fieldPath, result)
+	}
+}
+
+func validateNested(field reflect.Value, path string, result *ConfigValidationError) {
+	for field.Kind() == reflect.Ptr || field.Kind() == reflect.Interface {
+		if field.IsNil() {
+			return
+		}
+		field = field.Elem()
+	}
+
+	switch field.Kind() {
+	case reflect.Struct:
+		if field.Type() != reflect.TypeOf(time.Time{}) {
+			validateStruct(field, path, result)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < field.Len(); i++ {
+			validateNested(field.Index(i), fmt.Sprintf("%s[%d]", path, i), result)
+		}
+	case reflect.Map:
+		for _, key := range field.MapKeys() {
+			validateNested(field.MapIndex(key), fmt.Sprintf("%s[%v]", path, key.Interface()), result)
+		}
+	}
+}
+
+type configRule struct {
+	name  string
+	param string
+}
+
+// 解析 validate 标签，regex 规则使用之后的全部内容
+func parseValidateRules(tag string) []configRule {
+	var rules []configRule
+
+	for tag != "" {
+		var item string
+		if strings.HasPrefix(tag, "regex=") {
+			item, tag = tag, ""
+		} else if i := strings.Index(tag, ","); i >= 0 {
+			item, tag = tag[:i], tag[i+1:]
+		} else {
+			item, tag = tag, ""
+		}
+
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		rule := configRule{name: item}
+		if i := strings.Index(item, "="); i >= 0 {
+			rule.name, rule.param = item[:i], item[i+1:]
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules
+}
+
+var validateRegexCache sync.Map
+
+// 校验字段，通过时返回空字符串，否则返回错误信息
+func (rule configRule) check(field, parent reflect.Value) string {
+	value := reflect.Indirect(field)
+
+	switch rule.name {
+	case "required":
+		if isZeroValue(field) {
+			return "is required, but blank"
+		}
+	case "min", "max", "len":
+		return checkBounds(rule, value)
+	case "oneof":
+		options := strings.Fields(rule.param)
+		current := fmt.Sprint(value.Interface())
+		for _, option := range options {
+			if option == current {
+				return ""
+			}
+		}
+		return fmt.Sprintf("must be one of [%s], got %q", strings.Join(options, ", "), current)
+	case "regex":
+		cached, ok := validateRegexCache.Load(rule.param)
+		if !ok {
+			re, err := regexp.Compile(rule.param)
+			if err != nil {
+				return fmt.Sprintf("invalid regex %q: %v", rule.param, err)
+			}
+			cached, _ = validateRegexCache.LoadOrStore(rule.param, re)
+		}
+		if !cached.(*regexp.Regexp).MatchString(fmt.Sprint(value.Interface())) {
+			return fmt.Sprintf("must match %q", rule.param)
+		}
+	case "url":
+		u, err := url.Parse(fmt.Sprint(value.Interface()))
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return "must be an absolute URL"
+		}
+	case "email":
+		str := fmt.Sprint(value.Interface())
+		if addr, err := mail.ParseAddress(str); err != nil || addr.Address != str {
+			return "must be a valid email address"
+		}
+	case "ip":
+		if net.ParseIP(fmt.Sprint(value.Interface())) == nil {
+			return "must be a valid IP address"
+		}
+	case "duration":
+		if value.Kind() != reflect.String {
+			return ""
+		}
+		if _, err := time.ParseDuration(value.String()); err != nil {
+			return "must be a duration such as 300ms, 1.5h or 2h45m"
+		}
+	case "port":
+		port, err := strconv.ParseInt(fmt.Sprint(value.Interface()), 10, 64)
+		if err != nil || port < 1 || port > 65535 {
+			return "must be a port number between 1 and 65535"
+		}
+	case "eqfield", "nefield", "gtfield", "gtefield", "ltfield", "ltefield":
+		return checkFieldComparison(rule, value, parent)
+	case "required_if":
+		parts := strings.SplitN(rule.param, " ", 2)
+		other := parent.FieldByName(parts[0])
+		if !other.IsValid() {
+			return fmt.Sprintf("unknown field %q in rule %s", parts[0], rule.name)
+		}
+		expected := ""
+		if len(parts) == 2 {
+			expected = parts[1]
+		}
+		// 空指针视为条件不满足
+		other = reflect.Indirect(other)
+		if other.IsValid() && fmt.Sprint(other.Interface()) == expected && isZeroValue(field) {
+			return fmt.Sprintf("is required when %s is %q", parts[0], expected)
+		}
+	case "required_with":
+		other := parent.FieldByName(rule.param)
+		if !other.IsValid() {
+			return fmt.Sprintf("unknown field %q in rule %s", rule.param, rule.name)
+		}
+		if !isZeroValue(other) && isZeroValue(field) {
+			return fmt.Sprintf("is required when %s is set", rule.param)
+		}
+	default:
+		return fmt.Sprintf("unknown validation rule %q", rule.name)
+	}
+
+	return ""
+}
+
+func checkBounds(rule configRule, value reflect.Value) string {
+	var (
+		actual, limit float64
+		err           error
+		subject       = "value"
+	)
+
+	switch value.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		subject = "length"
+		if value.Kind() == reflect.String {
+			actual = float64(utf8.RuneCountInString(value.String()))
+		} else {
+			actual = float64(value.Len())
+		}
+		limit, err = strconv.ParseFloat(rule.param, 64)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		actual = float64(value.Int())
+		if value.Type() == reflect.TypeOf(time.Duration(0)) {
+			var d time.Duration
+			if d, err = time.ParseDuration(rule.param); err == nil {
+				limit = float64(d)
+				break
+			}
+		}
+		limit, err = strconv.ParseFloat(rule.param, 64)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		actual = float64(value.Uint())
+		limit, err = strconv.ParseFloat(rule.param, 64)
+	case reflect.Float32, reflect.Float64:
+		actual = value.Float()
+		limit, err = strconv.ParseFloat(rule.param, 64)
+	default:
+		return fmt.Sprintf("rule %s is not supported for %s", rule.name, value.Kind())
+	}
+
+	if err != nil {
+		return fmt.Sprintf("invalid parameter %q in rule %s", rule.param, rule.name)
+	}
+
+	switch {
+	case rule.name == "min" && actual < limit:
+		return fmt.Sprintf("%s must be at least %s", subject, rule.param)
+	case rule.name == "max" && actual > limit:
+		return fmt.Sprintf("%s must be at most %s", subject, rule.param)
+	case rule.name == "len" && actual != limit:
+		return fmt.Sprintf("%s must be exactly %s", subject, rule.param)
+	}
+
+	return ""
+}
+
+func checkFieldComparison(rule configRule, value, parent reflect.Value) string {
+	other := parent.FieldByName(rule.param)
+	if !other.IsValid() {
+		return fmt.Sprintf("unknown field %q in rule %s", rule.param, rule.name)
+	}
+	// 与空指针字段不做比较，与字段自身为空指针时一致
+	other = reflect.Indirect(other)
+	if !other.IsValid() {
+		return ""
+	}
+
+	if rule.name == "eqfield" || rule.name == "nefield" {
+		equal := reflect.DeepEqual(value.Interface(), other.Interface())
+		if rule.name == "eqfield" && !equal {
+			return fmt.Sprintf("must be equal to %s", rule.param)
+		}
+		if rule.name == "nefield" && equal {
+			return fmt.Sprintf("must not be equal to %s", rule.param)
+		}
+		return ""
+	}
+
+	cmp, ok := compareValues(value, other)
+	if !ok {
+		return fmt.Sprintf("can not compare %s with %s", value.Type(), other.Type())
+	}
+
+	switch {
+	case rule.name == "gtfield" && cmp <= 0:
+		return fmt.Sprintf("must be greater than %s", rule.param)
+	case rule.name == "gtefield" && cmp < 0:
+		return fmt.Sprintf("must be greater than or equal to %s", rule.param)
+	case rule.name == "ltfield" && cmp >= 0:
+		return fmt.Sprintf("must be less than %s", rule.param)
+	case rule.name == "ltefield" && cmp > 0:
+		return fmt.Sprintf("must be less than or equal to %s", rule.param)
+	}
+
+	return ""
+}
+
+// 比较两个同类值的大小，支持数值、字符串及 time.Time
+func compareValues(a, b reflect.Value) (int, bool) {
+	if a.Type() != b.Type() {
+		return 0, false
+	}
+
+	var x, y float64
+
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		x, y = float64(a.Int()), float64(b.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		x, y = float64(a.Uint()), float64(b.Uint())
+	case reflect.Float32, reflect.Float64:
+		x, y = a.Float(), b.Float()
+	case reflect.String:
+		return strings.Compare(a.String(), b.String()), true
+	case reflect.Struct:
+		ta, okA := a.Interface().(time.Time)
+		tb, okB := b.Interface().(time.Time)
+		if !okA || !okB {
+			return 0, false
+		}
+		return ta.Compare(tb), true
+	default:
+		return 0, false
+	}
+
+	switch {
+	case x < y:
+		return -1, true
+	case x > y:
+		return 1, true
+	default:
+		return 0, true
+	}
+}
+
+func isZeroValue(v reflect.Value) bool {
+	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
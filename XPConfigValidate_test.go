@@ -0,0 +1,72 @@
+package XPSuperKit
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type validateTestPort struct {
+	Port int `validate:"port"`
+}
+
+type validateTestConfig struct {
+	Env     string        `validate:"oneof=development test production"`
+	Workers int           `validate:"min=1,max=64"`
+	Timeout time.Duration `validate:"min=100ms,max=1m"`
+	Admin   string        `validate:"omitempty,email"`
+	Webhook string        `validate:"required_if=Env production,url"`
+	MinConn int
+	MaxConn int    `validate:"gtefield=MinConn"`
+	Name    string `validate:"regex=^[a-z][a-z0-9-]{0,3}$"`
+	Need    string `required:"true"`
+	Ports   []validateTestPort
+}
+
+func TestValidateConfig(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yml")
+	ioutil.WriteFile(file, []byte("env: production\nworkers: 100\ntimeout: 5\nadmin: bad\nminconn: 5\nmaxconn: 2\nname: Abc\nports:\n - port: 0\n - port: 80\n"), 0644)
+
+	var config validateTestConfig
+	err := NewXPConfig(&XPConfigEnvironment{EnvironmentPrefix: "-"}).Load(&config, file)
+	validationErr, ok := err.(*ConfigValidationError)
+	if !ok {
+		t.Fatalf("expected *ConfigValidationError, got %v", err)
+	}
+
+	var paths []string
+	for _, fieldErr := range validationErr.Errors {
+		paths = append(paths, fieldErr.Path)
+	}
+	if got, want := strings.Join(paths, " "), "Workers Timeout Admin Webhook MaxConn Name Need Ports[0].Port"; got != want {
+		t.Fatalf("failed fields = %q, want %q", got, want)
+	}
+
+	ioutil.WriteFile(file, []byte("env: test\nworkers: 1\ntimeout: 1s\nname: ab-1\nneed: x\nwebhook: http://example.com/hook\n"), 0644)
+	config = validateTestConfig{}
+	if err := NewXPConfig(&XPConfigEnvironment{EnvironmentPrefix: "-"}).Load(&config, file); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidateConfigNilSibling(t *testing.T) {
+	type config struct {
+		MinConn  *int
+		MaxConn  int `validate:"gtefield=MinConn"`
+		Mode     *string
+		CertFile string `validate:"required_if=Mode tls"`
+	}
+
+	if err := ValidateConfig(&config{MaxConn: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	minConn, mode := 5, "tls"
+	err := ValidateConfig(&config{MinConn: &minConn, MaxConn: 1, Mode: &mode})
+	validationErr, ok := err.(*ConfigValidationError)
+	if !ok || len(validationErr.Errors) != 2 {
+		t.Fatalf("expected MaxConn and CertFile errors, got %v", err)
+	}
+}
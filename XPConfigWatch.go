@@ -0,0 +1,232 @@
+package XPSuperKit
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*********调用示例********
+type Config struct {
+	Port int `default:"8080"`
+}
+
+watcher, err := XPSuperKit.XPConfig().Watch(&Config{}, &XPSuperKit.ConfigWatchOption{
+	Interval: 2 * time.Second,
+	OnError:  func(err error) { log.Println("config reload failed:", err) },
+}, "config.yml")
+
+// 每次读取都获取最新的配置，返回的配置不能被修改
+port := watcher.Get().(*Config).Port
+
+unsubscribe := watcher.Subscribe(func(oldConfig, newConfig interface{}) {
+	log.Println("port changed", oldConfig.(*Config).Port, "=>", newConfig.(*Config).Port)
+})
+
+watcher.Stop()
+ ************************/
+
+const kConfigWatchDefaultInterval = 5 * time.Second
+
+// 配置变化回调，oldConfig 及 newConfig 为同类型的结构体指针
+type ConfigChangeHandler func(oldConfig, newConfig interface{})
+
+type ConfigWatchOption struct {
+	Interval time.Duration                  //检查文件变化的间隔，默认 5 秒
+	Validate func(config interface{}) error //额外的校验，返回错误时拒绝新配置
+	OnError  func(err error)                //重新加载或校验失败时的回调，此时继续使用旧配置
+}
+
+// 配置文件监听器，通过轮询文件的修改时间及大小检测变化
+type XPConfigWatcherImpl struct {
+	cfg         *XPConfigImpl
+	files       []string
+	configType  reflect.Type
+	option      ConfigWatchOption
+	current     atomic.Value
+	states      map[string]configFileState
+	reloadLock  sync.Mutex
+	lock        sync.RWMutex
+	subscribers map[int]ConfigChangeHandler
+	nextID      int
+	stop        chan struct{}
+	stopOnce    sync.Once
+}
+
+type configFileState struct {
+	modTime time.Time
+	size    int64
+}
+
+/**
+ * 加载配置并监听配置文件变化
+ * 文件变化后将配置加载至新的结构体，校验通过后替换当前配置并通知订阅者，校验失败时保留当前配置
+ * @param config interface{} 结构体指针，初次加载的目标，之后的配置均为同类型的新结构体
+ * @param opt *ConfigWatchOption 可为 nil
+ * @param files ...string 配置文件，同 Load
+ */
+func (cfg *XPConfigImpl) Watch(config interface{}, opt *ConfigWatchOption, files ...string) (*XPConfigWatcherImpl, error) {
+	configValue := reflect.ValueOf(config)
+	if configValue.Kind() != reflect.Ptr || configValue.Elem().Kind() != reflect.Struct {
+		return nil, errors.New("invalid config, should be pointer to struct")
+	}
+
+	watcher := &XPConfigWatcherImpl{
+		cfg:         cfg,
+		files:       files,
+		configType:  configValue.Elem().Type(),
+		subscribers: make(map[int]ConfigChangeHandler),
+		stop:        make(chan struct{}),
+	}
+
+	if opt != nil {
+		watcher.option = *opt
+	}
+	if watcher.option.Interval <= 0 {
+		watcher.option.Interval = kConfigWatchDefaultInterval
+	}
+
+	watcher.states = watcher.fileStates()
+	if err := watcher.load(config); err != nil {
+		return nil, err
+	}
+	watcher.current.Store(config)
+
+	go watcher.run()
+
+	return watcher, nil
+}
+
+// 当前配置，为 Watch 传入的结构体类型的指针
+func (watcher *XPConfigWatcherImpl) Get() interface{} {
+	return watcher.current.Load()
+}
+
+// 订阅配置变化，返回取消订阅的函数，回调在监听协程中依次执行
+func (watcher *XPConfigWatcherImpl) Subscribe(handler ConfigChangeHandler) (unsubscribe func()) {
+	watcher.lock.Lock()
+	defer watcher.lock.Unlock()
+
+	id := watcher.nextID
+	watcher.nextID++
+	watcher.subscribers[id] = handler
+
+	return func() {
+		watcher.lock.Lock()
+		defer watcher.lock.Unlock()
+		delete(watcher.subscribers, id)
+	}
+}
+
+// 立即重新加载配置，不论文件是否变化，配置内容未变化时不通知订阅者
+func (watcher *XPConfigWatcherImpl) Reload() error {
+	watcher.reloadLock.Lock()
+	defer watcher.reloadLock.Unlock()
+
+	watcher.states = watcher.fileStates()
+	return watcher.reload()
+}
+
+// 停止监听，当前配置仍可通过 Get 获取
+func (watcher *XPConfigWatcherImpl) Stop() {
+	watcher.stopOnce.Do(func() {
+		close(watcher.stop)
+	})
+}
+
+func (watcher *XPConfigWatcherImpl) run() {
+	ticker := time.NewTicker(watcher.option.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-watcher.stop:
+			return
+		case <-ticker.C:
+			watcher.check()
+		}
+	}
+}
+
+func (watcher *XPConfigWatcherImpl) check() {
+	watcher.reloadLock.Lock()
+	defer watcher.reloadLock.Unlock()
+
+	states := watcher.fileStates()
+	if reflect.DeepEqual(states, watcher.states) {
+		return
+	}
+	watcher.states = states
+
+	if err := watcher.reload(); err != nil && watcher.option.OnError != nil {
+		watcher.option.OnError(err)
+	}
+}
+
+func (watcher *XPConfigWatcherImpl) reload() error {
+	config := reflect.New(watcher.configType).Interface()
+	if err := watcher.load(config); err != nil {
+		watcher.cfg.forgetConfigSources(config)
+		return err
+	}
+
+	old := watcher.current.Load()
+	if reflect.DeepEqual(old, config) {
+		watcher.cfg.forgetConfigSources(config)
+		return nil
+	}
+	watcher.current.Store(config)
+
+	watcher.lock.RLock()
+	handlers := make([]ConfigChangeHandler, 0, len(watcher.subscribers))
+	for id := 0; id < watcher.nextID; id++ {
+		if handler, ok := watcher.subscribers[id]; ok {
+			handlers = append(handlers, handler)
+		}
+	}
+	watcher.lock.RUnlock()
+
+	for _, handler := range handlers {
+		handler(old, config)
+	}
+	watcher.cfg.forgetConfigSources(old)
+
+	return nil
+}
+
+func (watcher *XPConfigWatcherImpl) load(config interface{}) error {
+	if err := watcher.cfg.Load(config, watcher.files...); err != nil {
+		return err
+	}
+
+	if watcher.option.Validate != nil {
+		return watcher.option.Validate(config)
+	}
+
+	return nil
+}
+
+// 记录所有可能被加载的文件的状态，包括尚不存在的环境及示例配置文件，文件出现或删除时也会重新加载
+func (watcher *XPConfigWatcherImpl) fileStates() map[string]configFileState {
+	states := make(map[string]configFileState)
+
+	for _, file := range watcher.files {
+		candidates := []string{
+			file,
+			configurationFileWithEnvironment(file, watcher.cfg.GetEnvironment()),
+			configurationFileWithEnvironment(file, "example"),
+			configurationFileWithEnvironment(file, kConfigLocalEnvironment),
+		}
+
+		for _, candidate := range candidates {
+			if info, err := os.Stat(candidate); err == nil && info.Mode().IsRegular() {
+				states[candidate] = configFileState{modTime: info.ModTime(), size: info.Size()}
+			}
+		}
+	}
+
+	return states
+}
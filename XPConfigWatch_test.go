@@ -0,0 +1,143 @@
+package XPSuperKit
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type watchTestConfig struct {
+	Port int `default:"80"`
+	Name string
+}
+
+func (c *watchTestConfig) Validate() error {
+	if c.Port > 65535 {
+		return errors.New("invalid port")
+	}
+	return nil
+}
+
+func watchTestWrite(t *testing.T, file, content string) {
+	if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func watchTestReceive(t *testing.T, changes chan [2]int) [2]int {
+	select {
+	case change := <-changes:
+		return change
+	case <-time.After(2 * time.Second):
+		t.Fatal("no change notification")
+	}
+	return [2]int{}
+}
+
+func TestConfigWatchPolling(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "c.yml")
+	watchTestWrite(t, file, "port: 1000\nname: a\n")
+
+	var failures int32
+	initial := &watchTestConfig{}
+	watcher, err := NewXPConfig(&XPConfigEnvironment{Environment: "prod"}).Watch(initial, &ConfigWatchOption{
+		Interval: 20 * time.Millisecond,
+		OnError:  func(error) { atomic.AddInt32(&failures, 1) },
+	}, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer watcher.Stop()
+
+	if watcher.Get() != initial || initial.Port != 1000 {
+		t.Fatalf("initial config %+v", watcher.Get())
+	}
+
+	changes := make(chan [2]int, 4)
+	watcher.Subscribe(func(oldConfig, newConfig interface{}) {
+		changes <- [2]int{oldConfig.(*watchTestConfig).Port, newConfig.(*watchTestConfig).Port}
+	})
+
+	watchTestWrite(t, file, "port: 2000\nname: bb\n")
+	if change := watchTestReceive(t, changes); change != [2]int{1000, 2000} {
+		t.Fatal(change)
+	}
+	if initial.Port != 1000 {
+		t.Fatal("initial config modified")
+	}
+
+	// 校验失败时保留当前配置
+	watchTestWrite(t, file, "port: 99999\nname: ccc\n")
+	deadline := time.Now().Add(2 * time.Second)
+	for atomic.LoadInt32(&failures) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if atomic.LoadInt32(&failures) == 0 || watcher.Get().(*watchTestConfig).Port != 2000 {
+		t.Fatalf("invalid config not rejected: %+v", watcher.Get())
+	}
+
+	// 新出现的环境配置文件同样会触发重新加载
+	watchTestWrite(t, filepath.Join(dir, "c.prod.yml"), "port: 3000\n")
+	if change := watchTestReceive(t, changes); change != [2]int{2000, 3000} {
+		t.Fatal(change)
+	}
+}
+
+func TestConfigWatchReload(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "c.yml")
+	watchTestWrite(t, file, "port: 1000\n")
+
+	watcher, err := NewXPConfig(nil).Watch(&watchTestConfig{}, &ConfigWatchOption{
+		Interval: time.Hour,
+		Validate: func(config interface{}) error {
+			if config.(*watchTestConfig).Name == "reject" {
+				return errors.New("rejected")
+			}
+			return nil
+		},
+	}, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer watcher.Stop()
+
+	var notified int32
+	unsubscribe := watcher.Subscribe(func(oldConfig, newConfig interface{}) { atomic.AddInt32(&notified, 1) })
+
+	// 内容未变化时不通知订阅者
+	if err := watcher.Reload(); err != nil || atomic.LoadInt32(&notified) != 0 {
+		t.Fatalf("unchanged reload: %v, %d notifications", err, notified)
+	}
+
+	watchTestWrite(t, file, "port: 1000\nname: reject\n")
+	if err := watcher.Reload(); err == nil || watcher.Get().(*watchTestConfig).Name != "" {
+		t.Fatalf("Validate ignored: %v", err)
+	}
+	watchTestWrite(t, file, "port: [")
+	if err := watcher.Reload(); err == nil || watcher.Get().(*watchTestConfig).Port != 1000 {
+		t.Fatalf("invalid YAML accepted: %v", err)
+	}
+
+	watchTestWrite(t, file, "port: 1001\n")
+	if err := watcher.Reload(); err != nil || watcher.Get().(*watchTestConfig).Port != 1001 || atomic.LoadInt32(&notified) != 1 {
+		t.Fatalf("reload: %v, %+v, %d notifications", err, watcher.Get(), notified)
+	}
+
+	unsubscribe()
+	watchTestWrite(t, file, "port: 1002\n")
+	if err := watcher.Reload(); err != nil || atomic.LoadInt32(&notified) != 1 {
+		t.Fatalf("unsubscribed handler called: %v", err)
+	}
+
+	if _, err := NewXPConfig(nil).Watch(watchTestConfig{}, nil, file); err == nil {
+		t.Fatal("non-pointer config accepted")
+	}
+	watchTestWrite(t, file, "port: 99999\n")
+	if _, err := NewXPConfig(nil).Watch(&watchTestConfig{}, nil, file); err == nil {
+		t.Fatal("invalid initial config accepted")
+	}
+}
@@ -0,0 +1,581 @@
+package XPSuperKit
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-yaml/yaml"
+)
+
+/*********调用示例********
+type Config struct {
+	DB struct {
+		Host     string `default:"localhost" description:"database host"`
+		Port     int    `default:"3306"`
+		Password string `secret:"true"`
+	}
+}
+
+cfg := XPSuperKit.XPConfig()
+
+// 将当前配置写回文件，格式由扩展名决定 (.yml/.yaml/.toml/.json)
+err := cfg.Save(&config, "config.yml")
+// db:
+//   # database host (default "localhost")
+//   host: db.internal
+//   # (default "3306")
+//   port: 3306
+//   # secret, set by $XPCONFIG_DB_PASSWORD
+//   password: enc:...   (配置文件中的引用原样写回，明文写为 "")
+
+// 以结构体的默认值生成 config.example.yml，配置文件不存在时 Load 会使用它
+example, err := cfg.GenerateExample(&Config{}, "config.yml")
+ ************************/
+
+var (
+	// 写出配置时不支持的格式
+	ConfigErrUnsupportedFormat = errors.New("config: unsupported format, should be yaml, toml or json")
+)
+
+var configTomlBareKeyRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// 写出的字段，结构体字段 (除 time.Time 等没有导出字段的结构体) 展开为 fields
+type configWriteField struct {
+	key     string
+	comment string
+	value   reflect.Value
+	fields  []*configWriteField
+}
+
+/**
+ * 以 YAML、TOML 或 JSON 格式写出配置，键名使用对应格式的标签，没有标签时与该格式的编码器一致
+ * YAML 及 TOML 中以注释写出 description 标签及 default 标签
+ * secret 标签为 true 或 resolve 的字段不写出明文：Load 时从配置文件读到的 enc:、file://、${VAR} 引用原样写回，其他情况写为零值
+ * @param config interface{} 配置结构体指针
+ * @param w io.Writer
+ * @param format string yaml、yml、toml 或 json，可带 .
+ */
+func (cfg *XPConfigImpl) Write(config interface{}, w io.Writer, format string) error {
+	configValue := reflect.ValueOf(config)
+	if configValue.Kind() != reflect.Ptr || configValue.Elem().Kind() != reflect.Struct {
+		return ErrorN("invalid config, should be pointer to struct")
+	}
+
+	format = strings.TrimPrefix(normalizeConfigExt(format), ".")
+	if format == "yml" {
+		format = "yaml"
+	}
+	if format != "yaml" && format != "toml" && format != "json" {
+		return ConfigErrUnsupportedFormat
+	}
+
+	var prefixes []string
+	if prefix := cfg.getEnvironmentPrefix(config); prefix != "-" {
+		prefixes = []string{prefix}
+	}
+
+	var (
+		buf    bytes.Buffer
+		err    error
+		fields = collectConfigWriteFields(configValue.Elem(), format, false, prefixes, cfg.loadedConfigSources(config), "")
+	)
+	switch format {
+	case "yaml":
+		err = writeConfigYaml(&buf, fields, "")
+	case "toml":
+		err = writeConfigToml(&buf, fields, nil)
+	case "json":
+		err = writeConfigJson(&buf, fields, "")
+		buf.WriteString("\n")
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+/**
+ * 将配置写入文件，格式由扩展名决定，先写入同一目录下唯一的临时文件再替换，监听中的配置不会读到写了一半的文件
+ * @param config interface{} 配置结构体指针
+ * @param file string 文件路径
+ */
+func (cfg *XPConfigImpl) Save(config interface{}, file string) error {
+	var buf bytes.Buffer
+	if err := cfg.Write(config, &buf, path.Ext(file)); err != nil {
+		return err
+	}
+
+	mode := os.FileMode(0644)
+	if fileInfo, err := os.Stat(file); err == nil {
+		mode = fileInfo.Mode().Perm()
+	}
+
+	return writeFileAtomic(file, mode, func(w io.Writer) error {
+		_, err := w.Write(buf.Bytes())
+		return err
+	})
+}
+
+/**
+ * 以结构体的默认值生成示例配置，如 config.yml => config.example.yml，即 Load 找不到配置文件时使用的文件
+ * 只使用 default 标签，不读取环境变量及 config 中的当前值，nil 的结构体指针会被展开以写出全部字段，递归引用自身类型的指针保持为 nil
+ * @param config interface{} 配置结构体指针，仅使用其类型
+ * @param file string 配置文件路径
+ * @return string 示例配置的路径
+ */
+func (cfg *XPConfigImpl) GenerateExample(config interface{}, file string) (string, error) {
+	configType := reflect.TypeOf(config)
+	if configType == nil || configType.Kind() != reflect.Ptr || configType.Elem().Kind() != reflect.Struct {
+		return "", ErrorN("invalid config, should be pointer to struct")
+	}
+
+	example := reflect.New(configType.Elem())
+	if err := applyConfigDefaults(example.Elem(), "", map[reflect.Type]bool{}); err != nil {
+		return "", err
+	}
+
+	exampleFile := configurationFileWithEnvironment(file, "example")
+	if err := cfg.Save(example.Interface(), exampleFile); err != nil {
+		return "", err
+	}
+	return exampleFile, nil
+}
+
+// 写入 default 标签的值，并展开 nil 的结构体指针，visiting 中的类型不再展开，避免递归的结构体无限展开
+func applyConfigDefaults(structValue reflect.Value, path string, visiting map[reflect.Type]bool) error {
+	visiting[structValue.Type()] = true
+	defer delete(visiting, structValue.Type())
+
+	for i := 0; i < structValue.NumField(); i++ {
+		fieldStruct := structValue.Type().Field(i)
+		if fieldStruct.PkgPath != "" {
+			continue
+		}
+
+		field := structValue.Field(i)
+		fieldPath := joinConfigPath(path, fieldStruct.Name)
+		if value := fieldStruct.Tag.Get("default"); value != "" && isZeroValue(field) {
+			if err := setConfigScalar(field, value); err != nil {
+				return fmt.Errorf("failed to parse default value of %v: %v", fieldPath, err)
+			}
+		}
+
+		if elemType := indirectType(field.Type()); elemType.Kind() == reflect.Struct && !visiting[elemType] && hasExportedField(reflect.New(elemType).Elem()) {
+			for field.Kind() == reflect.Ptr {
+				if field.IsNil() {
+					field.Set(reflect.New(field.Type().Elem()))
+				}
+				field = field.Elem()
+			}
+			if err := applyConfigDefaults(field, fieldPath, visiting); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// 按字段顺序收集要写出的字段，prefixes 用于 secret 字段注释中的环境变量名，与 processTags 一致
+// sources 为 Load 记录的来源，用于写回 secret 字段在配置文件中的引用，path 为结构体在配置中的路径
+func collectConfigWriteFields(structValue reflect.Value, format string, secret bool, prefixes []string, sources *configSources, path string) []*configWriteField {
+	var fields []*configWriteField
+
+	for i := 0; i < structValue.NumField(); i++ {
+		fieldStruct := structValue.Type().Field(i)
+		if fieldStruct.PkgPath != "" {
+			continue
+		}
+
+		key, inline, skip := configWriteKey(&fieldStruct, format)
+		if skip {
+			continue
+		}
+
+		field := structValue.Field(i)
+		fieldSecret := secret || isConfigSecretField(&fieldStruct)
+		value := reflect.Indirect(field)
+
+		if value.Kind() == reflect.Struct && hasExportedField(value) {
+			children := collectConfigWriteFields(value, format, fieldSecret, getPrefixForStruct(prefixes, &fieldStruct), sources, joinConfigPath(path, fieldStruct.Name))
+			if inline {
+				fields = append(fields, children...)
+				continue
+			}
+			if children == nil {
+				children = []*configWriteField{}
+			}
+			fields = append(fields, &configWriteField{key: key, comment: configWriteComment(&fieldStruct, "", ""), fields: children})
+			continue
+		}
+
+		envName := ""
+		if fieldSecret {
+			if envName = fieldStruct.Tag.Get("env"); envName == "" {
+				envName = strings.ToUpper(strings.Join(append(append([]string{}, prefixes...), fieldStruct.Name), "_"))
+			}
+			// 不写出明文，写回配置文件中的引用，没有引用时写为零值
+			if reference, ok := configSecretReference(sources, joinConfigPath(path, fieldStruct.Name)); ok && field.Kind() == reflect.String {
+				field = reflect.ValueOf(reference).Convert(field.Type())
+			} else {
+				field = reflect.Zero(field.Type())
+			}
+		}
+
+		fields = append(fields, &configWriteField{
+			key:     key,
+			comment: configWriteComment(&fieldStruct, fieldStruct.Tag.Get("default"), envName),
+			value:   field,
+		})
+	}
+
+	return fields
+}
+
+// 字段最后一次从配置文件中读到的值为 enc:、file:// 或含 ${VAR} 的引用时返回该引用
+func configSecretReference(sources *configSources, path string) (string, bool) {
+	list := sources.get(path)
+	for i := len(list) - 1; i >= 0; i-- {
+		if list[i].Kind != CONFIG_SOURCE_FILE {
+			continue
+		}
+
+		value := reflect.ValueOf(list[i].Value)
+		if value.Kind() != reflect.String {
+			return "", false
+		}
+		raw := value.String()
+		return raw, strings.HasPrefix(raw, kConfigEncryptedPrefix) || strings.HasPrefix(raw, kConfigFilePrefix) || strings.Contains(raw, "${")
+	}
+	return "", false
+}
+
+// 与各格式的编码器一致：yaml 的 inline 选项，json 及 toml 中没有名称的匿名结构体字段展开
+func configWriteKey(fieldStruct *reflect.StructField, format string) (key string, inline bool, skip bool) {
+	if format == "yaml" {
+		return configYamlKey(fieldStruct)
+	}
+
+	tag := fieldStruct.Tag.Get(format)
+	if tag == "-" {
+		return "", false, true
+	}
+
+	if name := strings.Split(tag, ",")[0]; name != "" {
+		return name, false, false
+	}
+	return fieldStruct.Name, fieldStruct.Anonymous && indirectType(fieldStruct.Type).Kind() == reflect.Struct, false
+}
+
+func configWriteComment(fieldStruct *reflect.StructField, defValue, envName string) string {
+	var parts []string
+	if description := fieldStruct.Tag.Get(kConfigDescriptionTag); description != "" {
+		parts = append(parts, description)
+	}
+	if defValue != "" {
+		parts = append(parts, fmt.Sprintf("(default %q)", defValue))
+	}
+	if envName != "" {
+		parts = append(parts, "secret, set by $"+envName)
+	}
+	return strings.Join(parts, " ")
+}
+
+func writeConfigComment(buf *bytes.Buffer, comment, indent string) {
+	if comment == "" {
+		return
+	}
+	for _, line := range strings.Split(comment, "\n") {
+		buf.WriteString(indent + "# " + line + "\n")
+	}
+}
+
+func writeConfigYaml(buf *bytes.Buffer, fields []*configWriteField, indent string) error {
+	for _, field := range fields {
+		writeConfigComment(buf, field.comment, indent)
+
+		if field.fields != nil {
+			if len(field.fields) == 0 {
+				buf.WriteString(indent + field.key + ": {}\n")
+				continue
+			}
+			buf.WriteString(indent + field.key + ":\n")
+			if err := writeConfigYaml(buf, field.fields, indent+"  "); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// go-yaml 为 time.Time 加上 !!timestamp 标签，直接写为 RFC 3339
+		var text string
+		if t, ok := configWriteTime(field.value); ok {
+			text = t.Format(time.RFC3339Nano)
+		} else {
+			data, err := yaml.Marshal(field.value.Interface())
+			if err != nil {
+				return err
+			}
+			text = strings.TrimSuffix(string(data), "\n")
+		}
+
+		// 非空的切片及 map 使用块格式
+		value := reflect.Indirect(field.value)
+		block := (value.Kind() == reflect.Slice || value.Kind() == reflect.Map || value.Kind() == reflect.Array) && value.Len() > 0
+		if !strings.Contains(text, "\n") && !block {
+			buf.WriteString(indent + field.key + ": " + text + "\n")
+			continue
+		}
+
+		buf.WriteString(indent + field.key + ":\n")
+		for _, line := range strings.Split(text, "\n") {
+			buf.WriteString(indent + "  " + line + "\n")
+		}
+	}
+	return nil
+}
+
+func configWriteTime(value reflect.Value) (time.Time, bool) {
+	value = reflect.Indirect(value)
+	if !value.IsValid() {
+		return time.Time{}, false
+	}
+	t, ok := value.Interface().(time.Time)
+	return t, ok
+}
+
+// 嵌套的结构体字段写为 [a.b] 表，其他值 (包括切片及 map 中的结构体) 写为行内的值，nil 的值不写出
+func writeConfigToml(buf *bytes.Buffer, fields []*configWriteField, tablePath []string) error {
+	for _, field := range fields {
+		if field.fields != nil {
+			continue
+		}
+
+		text, ok, err := formatConfigTomlValue(field.value)
+		if err != nil {
+			return fmt.Errorf("%v: %v", strings.Join(append(tablePath, field.key), "."), err)
+		}
+		if !ok {
+			continue
+		}
+
+		writeConfigComment(buf, field.comment, "")
+		buf.WriteString(configTomlKey(field.key) + " = " + text + "\n")
+	}
+
+	for _, field := range fields {
+		if field.fields == nil {
+			continue
+		}
+
+		subPath := append(append([]string{}, tablePath...), field.key)
+		keys := make([]string, len(subPath))
+		for i, key := range subPath {
+			keys[i] = configTomlKey(key)
+		}
+
+		if buf.Len() > 0 {
+			buf.WriteString("\n")
+		}
+		writeConfigComment(buf, field.comment, "")
+		buf.WriteString("[" + strings.Join(keys, ".") + "]\n")
+		if err := writeConfigToml(buf, field.fields, subPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func configTomlKey(key string) string {
+	if configTomlBareKeyRegexp.MatchString(key) {
+		return key
+	}
+	return quoteConfigString(key)
+}
+
+// JSON 字符串，TOML 的基本字符串与其转义兼容
+func quoteConfigString(s string) string {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// 将值格式化为 TOML 的行内值，ok 为 false 表示 nil，TOML 中没有对应的值
+func formatConfigTomlValue(value reflect.Value) (text string, ok bool, err error) {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return "", false, nil
+		}
+		value = value.Elem()
+	}
+
+	switch v := value.Interface().(type) {
+	case time.Time:
+		return v.Format(time.RFC3339Nano), true, nil
+	case time.Duration:
+		return quoteConfigString(v.String()), true, nil
+	}
+	if marshaler, isMarshaler := value.Interface().(encoding.TextMarshaler); isMarshaler {
+		data, err := marshaler.MarshalText()
+		if err != nil {
+			return "", false, err
+		}
+		return quoteConfigString(string(data)), true, nil
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		return quoteConfigString(value.String()), true, nil
+	case reflect.Bool:
+		return strconv.FormatBool(value.Bool()), true, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10), true, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(value.Uint(), 10), true, nil
+	case reflect.Float32, reflect.Float64:
+		f := value.Float()
+		switch {
+		case math.IsNaN(f):
+			return "nan", true, nil
+		case math.IsInf(f, 1):
+			return "inf", true, nil
+		case math.IsInf(f, -1):
+			return "-inf", true, nil
+		}
+		text := strconv.FormatFloat(f, 'g', -1, 64)
+		if !strings.ContainsAny(text, ".eEn") {
+			text += ".0"
+		}
+		return text, true, nil
+	case reflect.Slice, reflect.Array:
+		items := make([]string, 0, value.Len())
+		for i := 0; i < value.Len(); i++ {
+			item, ok, err := formatConfigTomlValue(value.Index(i))
+			if err != nil {
+				return "", false, err
+			}
+			if ok {
+				items = append(items, item)
+			}
+		}
+		return "[" + strings.Join(items, ", ") + "]", true, nil
+	case reflect.Map:
+		keys := make([]string, 0, value.Len())
+		values := make(map[string]reflect.Value, value.Len())
+		for _, key := range value.MapKeys() {
+			name := fmt.Sprint(key.Interface())
+			keys = append(keys, name)
+			values[name] = value.MapIndex(key)
+		}
+		sort.Strings(keys)
+
+		items := make([]string, 0, len(keys))
+		for _, key := range keys {
+			item, ok, err := formatConfigTomlValue(values[key])
+			if err != nil {
+				return "", false, err
+			}
+			if ok {
+				items = append(items, configTomlKey(key)+" = "+item)
+			}
+		}
+		return formatConfigTomlTable(items), true, nil
+	case reflect.Struct:
+		var items []string
+		for _, field := range collectConfigWriteFields(value, "toml", false, nil, nil, "") {
+			item, ok, err := formatConfigTomlStructField(field)
+			if err != nil {
+				return "", false, err
+			}
+			if ok {
+				items = append(items, item)
+			}
+		}
+		return formatConfigTomlTable(items), true, nil
+	}
+
+	return "", false, fmt.Errorf("unsupported type %v", value.Type())
+}
+
+func formatConfigTomlStructField(field *configWriteField) (string, bool, error) {
+	if field.fields == nil {
+		text, ok, err := formatConfigTomlValue(field.value)
+		return configTomlKey(field.key) + " = " + text, ok, err
+	}
+
+	var items []string
+	for _, child := range field.fields {
+		item, ok, err := formatConfigTomlStructField(child)
+		if err != nil {
+			return "", false, err
+		}
+		if ok {
+			items = append(items, item)
+		}
+	}
+	return configTomlKey(field.key) + " = " + formatConfigTomlTable(items), true, nil
+}
+
+func formatConfigTomlTable(items []string) string {
+	if len(items) == 0 {
+		return "{}"
+	}
+	return "{ " + strings.Join(items, ", ") + " }"
+}
+
+// JSON 没有注释，按字段顺序写出，值使用 encoding/json 编码
+func writeConfigJson(buf *bytes.Buffer, fields []*configWriteField, indent string) error {
+	if len(fields) == 0 {
+		buf.WriteString("{}")
+		return nil
+	}
+
+	buf.WriteString("{\n")
+	for i, field := range fields {
+		buf.WriteString(indent + "  " + quoteConfigString(field.key) + ": ")
+
+		if field.fields != nil {
+			if err := writeConfigJson(buf, field.fields, indent+"  "); err != nil {
+				return err
+			}
+		} else {
+			var data bytes.Buffer
+			encoder := json.NewEncoder(&data)
+			encoder.SetEscapeHTML(false)
+			encoder.SetIndent(indent+"  ", "  ")
+			if err := encoder.Encode(field.value.Interface()); err != nil {
+				return err
+			}
+			buf.Write(bytes.TrimSuffix(data.Bytes(), []byte("\n")))
+		}
+
+		if i < len(fields)-1 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n")
+	}
+	buf.WriteString(indent + "}")
+	return nil
+}
@@ -0,0 +1,175 @@
+package XPSuperKit
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type writeTestConfig struct {
+	Name string `default:"app" description:"application name"`
+	DB   struct {
+		Host     string `yaml:"db_host" toml:"db_host" json:"db_host" default:"localhost" description:"database host"`
+		Port     int    `default:"3306"`
+		Password string `secret:"true"`
+	}
+	HTTP *struct {
+		Timeout time.Duration `default:"30s"`
+		MaxBody ByteSize      `default:"10MiB"`
+	}
+	Tags     []string `default:"a,b"`
+	Replicas []struct {
+		Host   string
+		Weight float64
+	}
+	Limits  map[string]int
+	When    time.Time
+	Until   *time.Time
+	Ignored string `yaml:"-" toml:"-" json:"-"`
+}
+
+func TestConfigWriteRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	var c writeTestConfig
+	c.Name = "svc \"x\""
+	c.DB.Host = "db"
+	c.DB.Port = 1
+	c.DB.Password = "s3cr3t"
+	c.HTTP = &struct {
+		Timeout time.Duration `default:"30s"`
+		MaxBody ByteSize      `default:"10MiB"`
+	}{Timeout: 5 * time.Second, MaxBody: 3 * MiB}
+	c.Tags = []string{"x", "y z"}
+	c.Replicas = []struct {
+		Host   string
+		Weight float64
+	}{{"r1", 1}, {"r2", 0.5}}
+	c.Limits = map[string]int{"foo": 1, "a.b": 2}
+	c.When = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	c.Ignored = "ignored"
+
+	cfg := NewXPConfig(&XPConfigEnvironment{EnvironmentPrefix: "WR"})
+	for _, ext := range []string{".yml", ".toml", ".json"} {
+		file := filepath.Join(dir, "config"+ext)
+		if err := cfg.Save(&c, file); err != nil {
+			t.Fatal(ext, err)
+		}
+		data, _ := ioutil.ReadFile(file)
+		if strings.Contains(string(data), "s3cr3t") || strings.Contains(string(data), "ignored") {
+			t.Fatalf("%v: secret or ignored field written:\n%s", ext, data)
+		}
+		var back writeTestConfig
+		if err := cfg.Load(&back, file); err != nil {
+			t.Fatal(ext, err)
+		}
+		want := c
+		want.DB.Password = ""
+		want.Ignored = ""
+		if !reflect.DeepEqual(back, want) {
+			t.Fatalf("%v: loaded %+v, want %+v", ext, back, want)
+		}
+		if ext != ".json" && !strings.Contains(string(data), "# database host (default \"localhost\")") {
+			t.Fatalf("%v: description comment missing:\n%s", ext, data)
+		}
+		if ext != ".json" && !strings.Contains(string(data), "$WR_DB_PASSWORD") {
+			t.Fatalf("%v: secret env comment missing:\n%s", ext, data)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := cfg.Write(&c, &buf, "xml"); err != ConfigErrUnsupportedFormat {
+		t.Fatal(err)
+	}
+
+	example, err := cfg.GenerateExample(&c, filepath.Join(dir, "app.yml"))
+	if err != nil || example != filepath.Join(dir, "app.example.yml") {
+		t.Fatal(example, err)
+	}
+	if data, _ := ioutil.ReadFile(example); !strings.Contains(string(data), "# application name (default \"app\")\nname: app\n") {
+		t.Fatalf("example does not use defaults:\n%s", data)
+	}
+
+	// 配置文件不存在时 Load 使用示例配置
+	var loaded writeTestConfig
+	if err := cfg.Load(&loaded, filepath.Join(dir, "app.yml")); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Name != "app" || loaded.DB.Host != "localhost" || loaded.HTTP == nil || loaded.HTTP.MaxBody != 10*MiB || len(loaded.Tags) != 2 {
+		t.Fatalf("%+v", loaded)
+	}
+}
+
+type writeTestNode struct {
+	Name string `default:"root"`
+	Next *writeTestNode
+}
+
+func TestConfigGenerateExampleRecursive(t *testing.T) {
+	done := make(chan error, 1)
+	go func() {
+		_, err := NewXPConfig(nil).GenerateExample(&writeTestNode{}, filepath.Join(t.TempDir(), "node.yml"))
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("GenerateExample did not return for a recursive struct")
+	}
+}
+
+type writeSecretTestConfig struct {
+	Name     string
+	Password string `secret:"resolve"`
+	Token    string `secret:"resolve"`
+	APIKey   string `secret:"true" yaml:"api_key"`
+	DB       struct {
+		Password string `secret:"resolve"`
+	}
+}
+
+func TestConfigSaveKeepsSecretReferences(t *testing.T) {
+	dir := t.TempDir()
+	masterKey := GenerateConfigMasterKey()
+	encrypted, _ := EncryptConfigValue(masterKey, "p4ss")
+	tokenFile := filepath.Join(dir, "token")
+	ioutil.WriteFile(tokenFile, []byte("tok\n"), 0600)
+
+	file := filepath.Join(dir, "app.yml")
+	ioutil.WriteFile(file, []byte("name: app\npassword: "+encrypted+"\ntoken: file://"+tokenFile+"\napi_key: plain\ndb:\n  password: ${SAVE_TEST_DB_PASSWORD:-db}\n"), 0600)
+
+	cfg := NewXPConfig(&XPConfigEnvironment{EnvironmentPrefix: "-", MasterKey: masterKey})
+	var c writeSecretTestConfig
+	if err := cfg.Load(&c, file); err != nil {
+		t.Fatal(err)
+	}
+	if c.Password != "p4ss" || c.Token != "tok" || c.DB.Password != "db" {
+		t.Fatalf("%+v", c)
+	}
+
+	c.Name = "renamed"
+	if err := cfg.Save(&c, file); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadFile(file)
+	for _, want := range []string{"password: " + encrypted, "token: file://" + tokenFile, "password: ${SAVE_TEST_DB_PASSWORD:-db}", "api_key: \"\""} {
+		if !strings.Contains(string(data), want) {
+			t.Fatalf("missing %q in saved config:\n%s", want, data)
+		}
+	}
+	if strings.Contains(string(data), "p4ss") || strings.Contains(string(data), "plain") {
+		t.Fatalf("plaintext secret written:\n%s", data)
+	}
+
+	var reloaded writeSecretTestConfig
+	if err := cfg.Load(&reloaded, file); err != nil || reloaded.Name != "renamed" || reloaded.Password != "p4ss" || reloaded.Token != "tok" {
+		t.Fatalf("%+v %v", reloaded, err)
+	}
+}
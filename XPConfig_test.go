@@ -0,0 +1,150 @@
+package XPSuperKit
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type layerTestConfig struct {
+	Name   string
+	When   time.Time
+	Tags   []string
+	Labels map[string]string
+	DB     *mergeTestItem
+	Items  []mergeTestItem
+	Debug  *bool
+	Nested map[string]map[string]int
+}
+
+func writeConfigFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestConfigLayeredLoad(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "app.yml")
+	writeConfigFiles(t, dir, map[string]string{
+		"app.yml":       "name: base\nwhen: 2020-01-02T00:00:00Z\ntags: [a, b]\nlabels: {x: '1', y: '2'}\ndb: {host: h, port: 1}\ndebug: true\nnested: {a: {i: 1}}\n",
+		"app.prod.yml":  "tags: []\nlabels: {}\ndb: {port: 2}\nnested: {a: {j: 2}}\n",
+		"app.local.yml": "labels: {y: '3'}\ndebug: false\n",
+	})
+
+	var c layerTestConfig
+	if err := NewXPConfig(&XPConfigEnvironment{Environment: "prod", EnvironmentPrefix: "-"}).Load(&c, file); err != nil {
+		t.Fatal(err)
+	}
+	// 出现的空切片替换之前的值，空 map 不删除已有的键
+	if c.Name != "base" || c.When.Year() != 2020 || c.Tags == nil || len(c.Tags) != 0 {
+		t.Fatalf("%+v", c)
+	}
+	if !reflect.DeepEqual(c.Labels, map[string]string{"x": "1", "y": "3"}) {
+		t.Fatal(c.Labels)
+	}
+	if *c.DB != (mergeTestItem{"h", 2}) || *c.Debug {
+		t.Fatal(c.DB, *c.Debug)
+	}
+	if !reflect.DeepEqual(c.Nested, map[string]map[string]int{"a": {"i": 1, "j": 2}}) {
+		t.Fatal(c.Nested)
+	}
+}
+
+func TestConfigLayeredMergeStrategies(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "app.yml")
+	writeConfigFiles(t, dir, map[string]string{
+		"app.yml":      "tags: [a, b]\nitems: [{host: h, port: 1}, {host: k}]\nlabels: {x: '1'}\n",
+		"app.prod.yml": "tags: [b, c]\nitems: [{host: z}]\nlabels: {y: '2'}\n",
+	})
+
+	for strategy, want := range map[MergeSliceStrategy][]string{
+		MERGE_SLICE_REPLACE:       {"b", "c"},
+		MERGE_SLICE_APPEND:        {"a", "b", "b", "c"},
+		MERGE_SLICE_APPEND_UNIQUE: {"a", "b", "c"},
+		MERGE_SLICE_BY_INDEX:      {"b", "c"},
+	} {
+		var c layerTestConfig
+		if err := NewXPConfig(&XPConfigEnvironment{Environment: "prod", EnvironmentPrefix: "-", MergeSlice: strategy}).Load(&c, file); err != nil {
+			t.Fatal(strategy, err)
+		}
+		if !reflect.DeepEqual(c.Tags, want) {
+			t.Fatalf("%v: %v, want %v", strategy, c.Tags, want)
+		}
+		if strategy == MERGE_SLICE_BY_INDEX && !reflect.DeepEqual(c.Items, []mergeTestItem{{"z", 1}, {"k", 0}}) {
+			t.Fatalf("%v: %v", strategy, c.Items)
+		}
+	}
+
+	var c layerTestConfig
+	if err := NewXPConfig(&XPConfigEnvironment{Environment: "prod", EnvironmentPrefix: "-", MergeMap: MERGE_MAP_REPLACE}).Load(&c, file); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(c.Labels, map[string]string{"y": "2"}) {
+		t.Fatal(c.Labels)
+	}
+}
+
+type zeroOverrideConfig struct {
+	Debug bool
+	Port  int
+	Name  string
+	Rate  float64
+	DB    struct {
+		Host string
+		Port int
+	}
+	Limits map[string]int
+}
+
+func TestConfigLayeredZeroOverride(t *testing.T) {
+	for ext, files := range map[string][2]string{
+		".yml":        {"debug: true\nport: 8080\nname: x\nrate: 0.5\ndb: {host: h, port: 1}\nlimits: {a: 1, b: 2}\n", "debug: false\nport: 0\nname: ''\ndb: {port: 0}\nlimits: {a: 0}\n"},
+		".json":       {`{"debug": true, "port": 8080, "name": "x", "rate": 0.5, "db": {"host": "h", "port": 1}, "limits": {"a": 1, "b": 2}}`, `{"Debug": false, "port": 0, "name": "", "db": {"port": 0}, "limits": {"a": 0}}`},
+		".toml":       {"debug = true\nport = 8080\nname = \"x\"\nrate = 0.5\n[db]\nhost = \"h\"\nport = 1\n[limits]\na = 1\nb = 2\n", "debug = false\nport = 0\nname = \"\"\n[db]\nport = 0\n[limits]\na = 0\n"},
+		".ini":        {"debug = true\nport = 8080\nname = x\nrate = 0.5\n[db]\nhost = h\nport = 1\n[limits]\na = 1\nb = 2\n", "debug = false\nport = 0\nname =\n[db]\nport = 0\n[limits]\na = 0\n"},
+		".properties": {"debug=true\nport=8080\nname=x\nrate=0.5\ndb.host=h\ndb.port=1\nlimits.a=1\nlimits.b=2\n", "debug=false\nport=0\nname=\ndb.port=0\nlimits.a=0\n"},
+	} {
+		dir := t.TempDir()
+		writeConfigFiles(t, dir, map[string]string{"app" + ext: files[0], "app.prod" + ext: files[1]})
+
+		var c zeroOverrideConfig
+		cfg := NewXPConfig(&XPConfigEnvironment{Environment: "prod", EnvironmentPrefix: "-"})
+		if err := cfg.Load(&c, filepath.Join(dir, "app"+ext)); err != nil {
+			t.Fatal(ext, err)
+		}
+		if sources := cfg.Sources(&c, "Debug"); len(sources) != 2 || sources[1].Name != filepath.Join(dir, "app.prod"+ext) {
+			t.Fatalf("%v: %v", ext, sources)
+		}
+		if c.Debug || c.Port != 0 || c.Name != "" || c.Rate != 0.5 || c.DB.Host != "h" || c.DB.Port != 0 {
+			t.Fatalf("%v: zero values not applied: %+v", ext, c)
+		}
+		if !reflect.DeepEqual(c.Limits, map[string]int{"a": 0, "b": 2}) {
+			t.Fatalf("%v: %v", ext, c.Limits)
+		}
+	}
+}
+
+func TestConfigLocalFilesLoadedLast(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFiles(t, dir, map[string]string{
+		"a.yml":       "name: a\n",
+		"b.yml":       "name: b\ntags: [b]\n",
+		"b.local.yml": "name: b-local\n",
+	})
+
+	// a 的优先级高于 b，但本地配置文件 b.local 覆盖两者
+	var c layerTestConfig
+	err := NewXPConfig(&XPConfigEnvironment{EnvironmentPrefix: "-"}).Load(&c, filepath.Join(dir, "a.yml"), filepath.Join(dir, "b.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Name != "b-local" || !reflect.DeepEqual(c.Tags, []string{"b"}) {
+		t.Fatalf("%+v", c)
+	}
+}
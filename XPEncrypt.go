@@ -8,14 +8,18 @@ import (
 	"crypto/md5"
 	"crypto/rand"
 	"crypto/rsa"
-	"crypto/sha1"
-	"crypto/x509"
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"crypto/subtle"
 	"encoding/base64"
-	"encoding/pem"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"math/big"
+
+	"golang.org/x/crypto/chacha20poly1305"
 )
 
 /**
@@ -31,6 +35,16 @@ func Md5String(str string, toUpper bool) string {
 	}
 }
 
+var (
+	EncryptErrInvalidPadding       = errors.New("encrypt: invalid padding")
+	EncryptErrInvalidCiphertext    = errors.New("encrypt: invalid ciphertext")
+	EncryptErrUnsupportedVersion   = errors.New("encrypt: unsupported ciphertext version")
+	EncryptErrUnsupportedAlgorithm = errors.New("encrypt: unsupported algorithm")
+	EncryptErrUnsupportedHash      = errors.New("encrypt: unsupported hash function")
+	EncryptErrKeyTooSmall          = errors.New("encrypt: rsa key too small for the padding scheme")
+	EncryptErrInvalidBlockSize     = errors.New("encrypt: ciphertext is not a multiple of the block size")
+)
+
 /*********************** Padding ********************/
 //PKCS7填充，
 func PKCS7Padding(cipher []byte, blockSize int) []byte {
@@ -39,11 +53,13 @@ func PKCS7Padding(cipher []byte, blockSize int) []byte {
 	return append(cipher, padText...)
 }
 
-//PKCS7反填充，
+//PKCS7反填充，填充不合法时返回 nil
 func PKCS7UnPadding(origData []byte) []byte {
-	length := len(origData)
-	unPadding := int(origData[length-1])
-	return origData[:(length - unPadding)]
+	result, err := PKCS7UnPaddingWithCheck(origData, 0)
+	if err != nil {
+		return nil
+	}
+	return result
 }
 
 //PKCS5填充，
@@ -54,12 +70,45 @@ func PKCS5Padding(cipher []byte, blockSize int) []byte {
 }
 
 
-//PKCS5反填充，
+//PKCS5反填充，填充不合法时返回 nil
 func PKCS5UnPadding(cipher []byte) []byte {
-	length := len(cipher)
-	// 去掉最后一个字节 unPadding 次
-	unPadding := int(cipher[length-1])
-	return cipher[:(length - unPadding)]
+	return PKCS7UnPadding(cipher)
+}
+
+/**
+ * PKCS7 反填充并校验填充是否合法
+ * 校验过程与填充内容无关地耗费固定时间，避免 padding oracle 攻击
+ * @param origData []byte 需要反填充的数据
+ * @param blockSize int 分组长度，为 0 时不校验数据长度与分组长度的关系
+ */
+func PKCS7UnPaddingWithCheck(origData []byte, blockSize int) ([]byte, error) {
+	length := len(origData)
+	if length == 0 || (blockSize > 0 && length % blockSize != 0) {
+		return nil, EncryptErrInvalidPadding
+	}
+
+	padding := int(origData[length-1])
+	maxPadding := length
+	if blockSize > 0 && blockSize < maxPadding {
+		maxPadding = blockSize
+	}
+	good := subtle.ConstantTimeLessOrEq(1, padding) & subtle.ConstantTimeLessOrEq(padding, maxPadding)
+
+	// 始终检查末尾 (最多) 255 个字节，每个处于填充范围内的字节都必须等于填充长度
+	toCheck := 255
+	if toCheck > length {
+		toCheck = length
+	}
+	for i := 0; i < toCheck; i++ {
+		inPadding := subtle.ConstantTimeLessOrEq(i+1, padding)
+		equal := subtle.ConstantTimeByteEq(origData[length-1-i], byte(padding))
+		good &= subtle.ConstantTimeSelect(inPadding, equal, 1)
+	}
+
+	if good != 1 {
+		return nil, EncryptErrInvalidPadding
+	}
+	return origData[:length-padding], nil
 }
 
 //0填充
@@ -79,8 +128,12 @@ func ZeroUnPadding(origData []byte) []byte {
 /*********************** Padding ********************/
 
 /*********************** RSA ********************/
+//1024 位密钥的分段长度，仅为兼容保留，分段函数已根据实际密钥长度计算分段
 const ENCRYPT_KEYSIZE, DECRYPT_KEYSIZE = 117, 128 //1024
 
+//PKCS#1 v1.5 填充占用的字节数
+const kRSAPKCS1v15Overhead = 11
+
 var hashPrefixes = map[crypto.Hash][]byte{
 	crypto.MD5:       {0x30, 0x20, 0x30, 0x0c, 0x06, 0x08, 0x2a, 0x86, 0x48, 0x86, 0xf7, 0x0d, 0x02, 0x05, 0x05, 0x00, 0x04, 0x10},
 	crypto.SHA1:      {0x30, 0x21, 0x30, 0x09, 0x06, 0x05, 0x2b, 0x0e, 0x03, 0x02, 0x1a, 0x05, 0x00, 0x04, 0x14},
@@ -121,17 +174,12 @@ func bytesCombine(pBytes ...[]byte) []byte {
  * @param file_path string 公钥路径 ex: ./conf/keys/public_key.pem
  */
 func GetPublicKey(filePath string) (*rsa.PublicKey, error){
-	var publicKey *rsa.PublicKey
 	pubData, err := ioutil.ReadFile(filePath)
-	//解析证书
-	block, _ := pem.Decode([]byte(pubData))
-	cert, err := x509.ParsePKIXPublicKey(block.Bytes)
 	if err != nil {
-		fmt.Println(err.Error())
-		return publicKey, err
+		return nil, err
 	}
-	publicKey = cert.(*rsa.PublicKey)
-	return publicKey, nil
+	//解析证书
+	return ParseRSAPublicKey(pubData)
 }
 
 /**
@@ -144,13 +192,7 @@ func GetPrivateKey(filePath string) (*rsa.PrivateKey, error) {
 		return nil, err
 	}
 	//解析证书
-	block, _ := pem.Decode(pfxData)
-	privateKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
-	if err != nil {
-		fmt.Println("解析证书错误" + err.Error())
-		return nil, err
-	}
-	return privateKey, nil
+	return ParseRSAPrivateKey(pfxData, nil)
 }
 
 /**
@@ -168,29 +210,114 @@ func PublicEncrypt(pub *rsa.PublicKey, data []byte) ([]byte, error) {
  * 公钥分段加密
  */
 func PublicPartEncrypt(pub *rsa.PublicKey, data []byte) ([]byte, error) {
-	length := len(data)
-	i := 0
-	var finalData []byte
-	for i < length {
-		signData, err := rsa.EncryptPKCS1v15(rand.Reader, pub, subString(data, i, ENCRYPT_KEYSIZE))
-		if err != nil {
-			return nil, err
-		}
-		finalData = bytesCombine(finalData, signData)
-		i += ENCRYPT_KEYSIZE
+	finalData, err := rsaPartEncrypt(pub.Size()-kRSAPKCS1v15Overhead, data, func(part []byte) ([]byte, error) {
+		return rsa.EncryptPKCS1v15(rand.Reader, pub, part)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []byte(base64.StdEncoding.EncodeToString(finalData)), nil
+}
+
+/**
+ * 公钥分段加密(RSA-OAEP)
+ * @param pub *rsa.PublicKey 公钥
+ * @param hash crypto.Hash OAEP 使用的哈希算法，如 crypto.SHA256，解密时必须相同
+ * @param data []byte 明文
+ * @param label []byte OAEP 标签，解密时必须相同，可为 nil
+ * 返回 base64 编码的密文，分段长度根据密钥长度及哈希长度计算
+ */
+func PublicPartEncryptOAEP(pub *rsa.PublicKey, hash crypto.Hash, data, label []byte) ([]byte, error) {
+	if !hash.Available() {
+		return nil, EncryptErrUnsupportedHash
+	}
+
+	finalData, err := rsaPartEncrypt(pub.Size()-2*hash.Size()-2, data, func(part []byte) ([]byte, error) {
+		return rsa.EncryptOAEP(hash.New(), rand.Reader, pub, part, label)
+	})
+	if err != nil {
+		return nil, err
 	}
 	return []byte(base64.StdEncoding.EncodeToString(finalData)), nil
 }
 
 /**
- * 公钥验签
+ * 公钥验签(SHA1withRSA)
  */
 func PublicVerifySign(pub *rsa.PublicKey, src []byte, sign []byte) error {
-	h := sha1.New()
-	h.Write(src)
-	hashed := h.Sum(nil)
+	return PublicVerifySignWithHash(pub, crypto.SHA1, src, sign)
+}
+
+/**
+ * 公钥验签(PKCS#1 v1.5)
+ * @param hash crypto.Hash 签名使用的哈希算法，如 crypto.SHA256、crypto.SHA512
+ */
+func PublicVerifySignWithHash(pub *rsa.PublicKey, hash crypto.Hash, src []byte, sign []byte) error {
+	hashed, err := rsaHash(hash, src)
+	if err != nil {
+		return err
+	}
 
-	return rsa.VerifyPKCS1v15(pub, crypto.SHA1, hashed, sign)
+	return rsa.VerifyPKCS1v15(pub, hash, hashed, sign)
+}
+
+/**
+ * 公钥验签(RSA-PSS)
+ * @param hash crypto.Hash 签名使用的哈希算法
+ * @param opts *rsa.PSSOptions 为 nil 时自动识别盐长度
+ */
+func PublicVerifySignPSS(pub *rsa.PublicKey, hash crypto.Hash, src []byte, sign []byte, opts *rsa.PSSOptions) error {
+	hashed, err := rsaHash(hash, src)
+	if err != nil {
+		return err
+	}
+
+	return rsa.VerifyPSS(pub, hash, hashed, sign, opts)
+}
+
+//计算消息摘要
+func rsaHash(hash crypto.Hash, data []byte) ([]byte, error) {
+	if !hash.Available() {
+		return nil, EncryptErrUnsupportedHash
+	}
+
+	h := hash.New()
+	h.Write(data)
+	return h.Sum(nil), nil
+}
+
+//按 chunkSize 分段处理明文并拼接结果
+func rsaPartEncrypt(chunkSize int, data []byte, encryptFunc func(part []byte) ([]byte, error)) ([]byte, error) {
+	if chunkSize <= 0 {
+		return nil, EncryptErrKeyTooSmall
+	}
+
+	var finalData []byte
+	for i := 0; i < len(data); i += chunkSize {
+		part, err := encryptFunc(subString(data, i, chunkSize))
+		if err != nil {
+			return nil, err
+		}
+		finalData = bytesCombine(finalData, part)
+	}
+	return finalData, nil
+}
+
+//按密钥长度分段处理密文并拼接结果
+func rsaPartDecrypt(keySize int, data []byte, decryptFunc func(part []byte) ([]byte, error)) ([]byte, error) {
+	if len(data)%keySize != 0 {
+		return nil, EncryptErrInvalidCiphertext
+	}
+
+	var finalData []byte
+	for i := 0; i < len(data); i += keySize {
+		part, err := decryptFunc(data[i : i+keySize])
+		if err != nil {
+			return nil, err
+		}
+		finalData = bytesCombine(finalData, part)
+	}
+	return finalData, nil
 }
 
 func pkcs1v15HashInfo(hash crypto.Hash, inLen int) (hashLen int, prefix []byte, err error) {
@@ -277,16 +404,11 @@ func PrivateEncrypt(privt *rsa.PrivateKey, data []byte) ([]byte, error) {
  * 私钥分段加密
  */
 func PrivatePartEncrypt(privt *rsa.PrivateKey, data []byte) ([]byte, error) {
-	length := len(data)
-	i := 0
-	var finalData []byte
-	for i < length {
-		signData, err := rsa.SignPKCS1v15(nil, privt, crypto.Hash(0), subString(data, i, ENCRYPT_KEYSIZE))
-		if err != nil {
-			return nil, err
-		}
-		finalData = bytesCombine(finalData, signData)
-		i += ENCRYPT_KEYSIZE
+	finalData, err := rsaPartEncrypt(privt.Size()-kRSAPKCS1v15Overhead, data, func(part []byte) ([]byte, error) {
+		return rsa.SignPKCS1v15(nil, privt, crypto.Hash(0), part)
+	})
+	if err != nil {
+		return nil, err
 	}
 	return []byte(base64.StdEncoding.EncodeToString(finalData)), nil
 }
@@ -295,14 +417,36 @@ func PrivatePartEncrypt(privt *rsa.PrivateKey, data []byte) ([]byte, error) {
  * 私钥加签(SHA1withRSA)
  */
 func PrivateSign(privt *rsa.PrivateKey, data []byte) ([]byte, error) {
-	h := sha1.New()
-	h.Write(data)
-	hashed := h.Sum(nil)
-	signData, err := rsa.SignPKCS1v15(rand.Reader, privt, crypto.SHA1, hashed)
+	return PrivateSignWithHash(privt, crypto.SHA1, data)
+}
+
+/**
+ * 私钥加签(PKCS#1 v1.5)
+ * @param hash crypto.Hash 签名使用的哈希算法，如 crypto.SHA256、crypto.SHA512
+ */
+func PrivateSignWithHash(privt *rsa.PrivateKey, hash crypto.Hash, data []byte) ([]byte, error) {
+	hashed, err := rsaHash(hash, data)
 	if err != nil {
 		return nil, err
 	}
-	return signData, nil
+	return rsa.SignPKCS1v15(rand.Reader, privt, hash, hashed)
+}
+
+/**
+ * 私钥加签(RSA-PSS)
+ * @param hash crypto.Hash 签名使用的哈希算法
+ * @param opts *rsa.PSSOptions 为 nil 时盐长度与哈希长度相同
+ */
+func PrivateSignPSS(privt *rsa.PrivateKey, hash crypto.Hash, data []byte, opts *rsa.PSSOptions) ([]byte, error) {
+	hashed, err := rsaHash(hash, data)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts == nil {
+		opts = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}
+	}
+	return rsa.SignPSS(rand.Reader, privt, hash, hashed, opts)
 }
 
 /**
@@ -323,33 +467,35 @@ func PrivateEncryptMD5withRSA(privt *rsa.PrivateKey, data []byte) ([]byte, error
  * 私钥解密
  */
 func PrivateDecrypt(privt *rsa.PrivateKey, data []byte) ([]byte, error) {
-	length := len(data)
-	i := 0
-	var finalData []byte
-	for i < length {
-		signData, err := rsa.DecryptPKCS1v15(rand.Reader, privt, subString(data, i, DECRYPT_KEYSIZE))
-		if err != nil {
-			return nil, err
-		}
-		finalData = bytesCombine(finalData, signData)
-		i += DECRYPT_KEYSIZE
+	return rsaPartDecrypt(privt.Size(), data, func(part []byte) ([]byte, error) {
+		return rsa.DecryptPKCS1v15(rand.Reader, privt, part)
+	})
+}
+
+/**
+ * 私钥分段解密(RSA-OAEP)
+ * @param privt *rsa.PrivateKey 私钥
+ * @param hash crypto.Hash 加密时使用的哈希算法
+ * @param data []byte 已 base64 解码的密文
+ * @param label []byte 加密时使用的 OAEP 标签
+ */
+func PrivateDecryptOAEP(privt *rsa.PrivateKey, hash crypto.Hash, data, label []byte) ([]byte, error) {
+	if !hash.Available() {
+		return nil, EncryptErrUnsupportedHash
 	}
-	return finalData, nil
+
+	return rsaPartDecrypt(privt.Size(), data, func(part []byte) ([]byte, error) {
+		return rsa.DecryptOAEP(hash.New(), rand.Reader, privt, part, label)
+	})
 }
 /*********************** RSA ********************/
 
 /*********************** AES ********************/
 func AESEncrypt(origData, key, iv []byte) (string, error) {
-	block, err := aes.NewCipher(key)
+	crypt, err := aesCBCEncrypt(origData, key, iv)
 	if err != nil {
 		return "", err
 	}
-	blockSize := block.BlockSize()
-	origData = PKCS5Padding(origData, blockSize)
-
-	blockMode := cipher.NewCBCEncrypter(block, iv)
-	crypt     := make([]byte, len(origData))
-	blockMode.CryptBlocks(crypt, origData)
 	return base64.StdEncoding.EncodeToString(crypt), nil
 }
 
@@ -358,16 +504,219 @@ func AESDecrypt(crypt string, key, iv []byte) (string, error) {
 	if err != nil {
 		return "",err
 	}
-	block, err := aes.NewCipher(key)
+	origData, err := aesCBCDecrypt(decodeData, key, iv)
 	if err != nil {
 		return "", err
 	}
 
+	return string(origData), nil
+}
+
+//AES-CBC 加密，使用 PKCS5 填充
+func aesCBCEncrypt(origData, key, iv []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	blockSize := block.BlockSize()
+	origData = PKCS5Padding(origData, blockSize)
+
+	blockMode := cipher.NewCBCEncrypter(block, iv)
+	crypt     := make([]byte, len(origData))
+	blockMode.CryptBlocks(crypt, origData)
+	return crypt, nil
+}
+
+//AES-CBC 解密，并去除 PKCS5 填充
+func aesCBCDecrypt(crypt, key, iv []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(crypt) == 0 || len(crypt) % block.BlockSize() != 0 {
+		return nil, EncryptErrInvalidBlockSize
+	}
+
 	blockMode := cipher.NewCBCDecrypter(block, iv)
-	origData := make([]byte, len(decodeData))
-	blockMode.CryptBlocks(origData, decodeData)
-	origData = PKCS5UnPadding(origData)
+	origData := make([]byte, len(crypt))
+	blockMode.CryptBlocks(origData, crypt)
+	return PKCS7UnPaddingWithCheck(origData, block.BlockSize())
+}
 
-	return string(origData), nil
+var aesKeyWrapDefaultIV = []byte{0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6}
+
+/**
+ * AES 密钥包装 (RFC 3394)
+ * @param kek []byte 密钥加密密钥，长度为 16、24 或 32 字节
+ * @param cek []byte 需要包装的密钥，长度为 8 的倍数且不少于 16 字节
+ */
+func AESKeyWrap(kek, cek []byte) ([]byte, error) {
+	if len(cek) < 16 || len(cek) % 8 != 0 {
+		return nil, errors.New("aes: key wrap input must be a multiple of 8 bytes and at least 16 bytes")
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(cek) / 8
+	out := make([]byte, len(cek) + 8)
+	copy(out, aesKeyWrapDefaultIV)
+	copy(out[8:], cek)
+
+	buf := make([]byte, 16)
+	for j := 0; j < 6; j++ {
+		for i := 1; i <= n; i++ {
+			copy(buf, out[:8])
+			copy(buf[8:], out[i*8:(i+1)*8])
+			block.Encrypt(buf, buf)
+
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(out[:8], binary.BigEndian.Uint64(buf[:8]) ^ t)
+			copy(out[i*8:(i+1)*8], buf[8:])
+		}
+	}
+	return out, nil
+}
+
+/**
+ * AES 密钥解包 (RFC 3394)，完整性校验失败时返回错误
+ */
+func AESKeyUnwrap(kek, wrapped []byte) ([]byte, error) {
+	if len(wrapped) < 24 || len(wrapped) % 8 != 0 {
+		return nil, errors.New("aes: wrapped key must be a multiple of 8 bytes and at least 24 bytes")
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(wrapped) / 8 - 1
+	out := make([]byte, len(wrapped))
+	copy(out, wrapped)
+
+	buf := make([]byte, 16)
+	for j := 5; j >= 0; j-- {
+		for i := n; i >= 1; i-- {
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(buf[:8], binary.BigEndian.Uint64(out[:8]) ^ t)
+			copy(buf[8:], out[i*8:(i+1)*8])
+			block.Decrypt(buf, buf)
+
+			copy(out[:8], buf[:8])
+			copy(out[i*8:(i+1)*8], buf[8:])
+		}
+	}
+
+	if subtle.ConstantTimeCompare(out[:8], aesKeyWrapDefaultIV) != 1 {
+		return nil, errors.New("aes: key unwrap integrity check failed")
+	}
+	return out[8:], nil
 }
 /*********************** AES ********************/
+
+/*********************** AEAD ********************/
+type AEADAlgorithm byte
+
+const (
+	AEAD_AES_GCM            AEADAlgorithm = 1 //AES-GCM，密钥长度 16、24 或 32 字节
+	AEAD_CHACHA20_POLY1305  AEADAlgorithm = 2 //ChaCha20-Poly1305，密钥长度 32 字节
+	AEAD_XCHACHA20_POLY1305 AEADAlgorithm = 3 //XChaCha20-Poly1305，密钥长度 32 字节，24 字节随机 nonce
+
+	kAEADVersion1   = 1
+	kAEADHeaderSize = 2
+)
+
+func newAEAD(alg AEADAlgorithm, key []byte) (cipher.AEAD, error) {
+	switch alg {
+	case AEAD_AES_GCM:
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(block)
+	case AEAD_CHACHA20_POLY1305:
+		return chacha20poly1305.New(key)
+	case AEAD_XCHACHA20_POLY1305:
+		return chacha20poly1305.NewX(key)
+	default:
+		return nil, EncryptErrUnsupportedAlgorithm
+	}
+}
+
+/**
+ * AEAD 加密
+ * 返回的密文格式为: 版本(1 字节) | 算法(1 字节) | 随机 nonce | 密文及认证标签
+ * 版本与算法同样受认证保护，解密时无需再指定算法
+ * @param alg AEADAlgorithm 加密算法
+ * @param key []byte 密钥
+ * @param plaintext []byte 明文
+ * @param additionalData []byte 附加认证数据，不会被加密但会被认证，解密时必须提供相同的值，可为 nil
+ */
+func AEADEncrypt(alg AEADAlgorithm, key, plaintext, additionalData []byte) ([]byte, error) {
+	aead, err := newAEAD(alg, key)
+	if err != nil {
+		return nil, err
+	}
+
+	header := []byte{kAEADVersion1, byte(alg)}
+	out := make([]byte, kAEADHeaderSize + aead.NonceSize(), kAEADHeaderSize + aead.NonceSize() + len(plaintext) + aead.Overhead())
+	copy(out, header)
+	if _, err := rand.Read(out[kAEADHeaderSize:]); err != nil {
+		return nil, err
+	}
+
+	nonce := out[kAEADHeaderSize:]
+	return aead.Seal(out, nonce, plaintext, bytesCombine(header, additionalData)), nil
+}
+
+/**
+ * AEAD 解密，算法由密文头部决定
+ * @param key []byte 密钥
+ * @param ciphertext []byte AEADEncrypt 返回的密文
+ * @param additionalData []byte 加密时使用的附加认证数据
+ */
+func AEADDecrypt(key, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < kAEADHeaderSize {
+		return nil, EncryptErrInvalidCiphertext
+	}
+	if ciphertext[0] != kAEADVersion1 {
+		return nil, EncryptErrUnsupportedVersion
+	}
+
+	aead, err := newAEAD(AEADAlgorithm(ciphertext[1]), key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) < kAEADHeaderSize + aead.NonceSize() + aead.Overhead() {
+		return nil, EncryptErrInvalidCiphertext
+	}
+
+	header := ciphertext[:kAEADHeaderSize]
+	nonce := ciphertext[kAEADHeaderSize : kAEADHeaderSize + aead.NonceSize()]
+	plaintext, err := aead.Open(nil, nonce, ciphertext[kAEADHeaderSize + aead.NonceSize():], bytesCombine(header, additionalData))
+	if err != nil {
+		return nil, EncryptErrInvalidCiphertext
+	}
+	return plaintext, nil
+}
+
+//AEAD 加密并返回 base64 编码的密文
+func AEADEncryptToString(alg AEADAlgorithm, key, plaintext, additionalData []byte) (string, error) {
+	crypt, err := AEADEncrypt(alg, key, plaintext, additionalData)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(crypt), nil
+}
+
+//解密 base64 编码的 AEAD 密文
+func AEADDecryptString(crypt string, key, additionalData []byte) ([]byte, error) {
+	decodeData, err := base64.StdEncoding.DecodeString(crypt)
+	if err != nil {
+		return nil, err
+	}
+	return AEADDecrypt(key, decodeData, additionalData)
+}
+/*********************** AEAD ********************/
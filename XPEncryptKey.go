@@ -0,0 +1,453 @@
+package XPSuperKit
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"hash"
+	"io/ioutil"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+/*********调用示例********
+privateKey, err := XPSuperKit.GenerateRSAKey(2048)
+
+// 保存为使用口令加密的 PKCS#8 PEM 文件
+err = XPSuperKit.SavePrivateKeyFile("./conf/keys/private_key.pem", privateKey, XPSuperKit.KEY_FORMAT_PKCS8, []byte("passphrase"))
+err = XPSuperKit.SavePublicKeyFile("./conf/keys/public_key.pem", privateKey.Public(), XPSuperKit.KEY_FORMAT_PKIX)
+
+key, err := XPSuperKit.LoadPrivateKeyFile("./conf/keys/private_key.pem", []byte("passphrase"))
+rsaKey := key.(*rsa.PrivateKey)
+ ************************/
+
+type KeyFormat string
+
+const (
+	KEY_FORMAT_PKCS1 KeyFormat = "PKCS1" //仅支持 RSA 私钥及公钥
+	KEY_FORMAT_PKCS8 KeyFormat = "PKCS8" //私钥，支持 RSA、ECDSA、Ed25519，可使用口令加密
+	KEY_FORMAT_PKIX  KeyFormat = "PKIX"  //公钥，支持 RSA、ECDSA、Ed25519
+
+	kMinRSAKeyBits       = 2048
+	kPBKDF2Iterations    = 600000
+	kPBKDF2MaxIterations = 10 * kPBKDF2Iterations //解密时接受的最大迭代次数，防止构造的密钥文件长时间占用 CPU
+	kPBKDF2SaltSize      = 16
+	kPEMTypeRSAPrivate   = "RSA PRIVATE KEY"
+	kPEMTypeECPrivate    = "EC PRIVATE KEY"
+	kPEMTypePrivate      = "PRIVATE KEY"
+	kPEMTypeEncrypted    = "ENCRYPTED PRIVATE KEY"
+	kPEMTypeRSAPublic    = "RSA PUBLIC KEY"
+	kPEMTypePublic       = "PUBLIC KEY"
+	kPEMTypeCertificate  = "CERTIFICATE"
+)
+
+var (
+	EncryptErrInvalidPEM           = errors.New("encrypt: invalid PEM data")
+	EncryptErrUnsupportedKey       = errors.New("encrypt: unsupported key type")
+	EncryptErrUnsupportedKeyFormat = errors.New("encrypt: unsupported key format")
+	EncryptErrPassphraseRequired   = errors.New("encrypt: private key is encrypted, passphrase required")
+	EncryptErrIncorrectPassphrase  = errors.New("encrypt: incorrect passphrase or corrupted private key")
+
+	oidPBES2      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHMACSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidAES128CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+)
+
+/*********************** 密钥生成 ********************/
+//生成 RSA 私钥，bits 不能小于 2048
+func GenerateRSAKey(bits int) (*rsa.PrivateKey, error) {
+	if bits < kMinRSAKeyBits {
+		return nil, ErrorF("rsa key size must be at least %d bits", kMinRSAKeyBits)
+	}
+	return rsa.GenerateKey(rand.Reader, bits)
+}
+
+// 生成 ECDSA 私钥，curve 为 nil 时使用 P-256
+func GenerateECDSAKey(curve elliptic.Curve) (*ecdsa.PrivateKey, error) {
+	if curve == nil {
+		curve = elliptic.P256()
+	}
+	return ecdsa.GenerateKey(curve, rand.Reader)
+}
+
+// 生成 Ed25519 密钥对
+func GenerateEd25519Key() (ed25519.PublicKey, ed25519.PrivateKey, error) {
+	return ed25519.GenerateKey(rand.Reader)
+}
+
+/*********************** 密钥生成 ********************/
+
+/*********************** 密钥解析 ********************/
+/**
+ * 解析私钥，支持 PEM 或 DER 编码的 PKCS#1、PKCS#8、SEC1 格式及加密的 PKCS#8
+ * @param data []byte 私钥内容
+ * @param passphrase []byte 加密私钥的口令，未加密时可为 nil
+ * @return crypto.PrivateKey *rsa.PrivateKey、*ecdsa.PrivateKey 或 ed25519.PrivateKey
+ */
+func ParsePrivateKey(data []byte, passphrase []byte) (crypto.PrivateKey, error) {
+	der := data
+	pemType := ""
+
+	if block, _ := pem.Decode(data); block != nil {
+		der, pemType = block.Bytes, block.Type
+	}
+
+	switch pemType {
+	case kPEMTypeRSAPrivate:
+		return x509.ParsePKCS1PrivateKey(der)
+	case kPEMTypeECPrivate:
+		return x509.ParseECPrivateKey(der)
+	case kPEMTypePrivate:
+		return x509.ParsePKCS8PrivateKey(der)
+	case kPEMTypeEncrypted:
+		return parseEncryptedPKCS8(der, passphrase)
+	case "":
+		if key, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+			return key, nil
+		}
+		if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+			return key, nil
+		}
+		if key, err := x509.ParseECPrivateKey(der); err == nil {
+			return key, nil
+		}
+		return parseEncryptedPKCS8(der, passphrase)
+	default:
+		return nil, EncryptErrInvalidPEM
+	}
+}
+
+/**
+ * 解析公钥，支持 PEM 或 DER 编码的 PKIX、PKCS#1 格式，以及从证书中提取公钥
+ * @return crypto.PublicKey *rsa.PublicKey、*ecdsa.PublicKey 或 ed25519.PublicKey
+ */
+func ParsePublicKey(data []byte) (crypto.PublicKey, error) {
+	der := data
+	pemType := ""
+
+	if block, _ := pem.Decode(data); block != nil {
+		der, pemType = block.Bytes, block.Type
+	}
+
+	switch pemType {
+	case kPEMTypePublic:
+		return x509.ParsePKIXPublicKey(der)
+	case kPEMTypeRSAPublic:
+		return x509.ParsePKCS1PublicKey(der)
+	case kPEMTypeCertificate:
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, err
+		}
+		return cert.PublicKey, nil
+	case "":
+		if key, err := x509.ParsePKIXPublicKey(der); err == nil {
+			return key, nil
+		}
+		if key, err := x509.ParsePKCS1PublicKey(der); err == nil {
+			return key, nil
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, EncryptErrUnsupportedKeyFormat
+		}
+		return cert.PublicKey, nil
+	default:
+		return nil, EncryptErrInvalidPEM
+	}
+}
+
+// 解析 RSA 私钥
+func ParseRSAPrivateKey(data []byte, passphrase []byte) (*rsa.PrivateKey, error) {
+	key, err := ParsePrivateKey(data, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, EncryptErrUnsupportedKey
+	}
+	return rsaKey, nil
+}
+
+// 解析 RSA 公钥
+func ParseRSAPublicKey(data []byte) (*rsa.PublicKey, error) {
+	key, err := ParsePublicKey(data)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, EncryptErrUnsupportedKey
+	}
+	return rsaKey, nil
+}
+
+// 从文件加载私钥，文件内容格式同 ParsePrivateKey
+func LoadPrivateKeyFile(filePath string, passphrase []byte) (crypto.PrivateKey, error) {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePrivateKey(data, passphrase)
+}
+
+// 从文件加载公钥，文件内容格式同 ParsePublicKey
+func LoadPublicKeyFile(filePath string) (crypto.PublicKey, error) {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePublicKey(data)
+}
+
+/*********************** 密钥解析 ********************/
+
+/*********************** 密钥编码 ********************/
+/**
+ * 将私钥编码为 DER
+ * @param key crypto.PrivateKey *rsa.PrivateKey、*ecdsa.PrivateKey 或 ed25519.PrivateKey
+ * @param format KeyFormat KEY_FORMAT_PKCS1 (仅 RSA) 或 KEY_FORMAT_PKCS8
+ * @param passphrase []byte 不为 nil 时使用 PBES2 (PBKDF2-HMAC-SHA256 + AES-256-CBC) 加密，仅支持 PKCS#8
+ */
+func MarshalPrivateKeyDER(key crypto.PrivateKey, format KeyFormat, passphrase []byte) ([]byte, error) {
+	der, _, err := marshalPrivateKey(key, format, passphrase)
+	return der, err
+}
+
+// 将私钥编码为 PEM，参数同 MarshalPrivateKeyDER
+func MarshalPrivateKeyPEM(key crypto.PrivateKey, format KeyFormat, passphrase []byte) ([]byte, error) {
+	der, pemType, err := marshalPrivateKey(key, format, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: pemType, Bytes: der}), nil
+}
+
+/**
+ * 将公钥编码为 DER
+ * @param key crypto.PublicKey *rsa.PublicKey、*ecdsa.PublicKey 或 ed25519.PublicKey
+ * @param format KeyFormat KEY_FORMAT_PKIX 或 KEY_FORMAT_PKCS1 (仅 RSA)
+ */
+func MarshalPublicKeyDER(key crypto.PublicKey, format KeyFormat) ([]byte, error) {
+	der, _, err := marshalPublicKey(key, format)
+	return der, err
+}
+
+// 将公钥编码为 PEM，参数同 MarshalPublicKeyDER
+func MarshalPublicKeyPEM(key crypto.PublicKey, format KeyFormat) ([]byte, error) {
+	der, pemType, err := marshalPublicKey(key, format)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: pemType, Bytes: der}), nil
+}
+
+// 将私钥以 PEM 格式保存至文件，文件权限为 0600
+func SavePrivateKeyFile(filePath string, key crypto.PrivateKey, format KeyFormat, passphrase []byte) error {
+	data, err := MarshalPrivateKeyPEM(key, format, passphrase)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filePath, data, 0600)
+}
+
+// 将公钥以 PEM 格式保存至文件
+func SavePublicKeyFile(filePath string, key crypto.PublicKey, format KeyFormat) error {
+	data, err := MarshalPublicKeyPEM(key, format)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filePath, data, 0644)
+}
+
+func marshalPrivateKey(key crypto.PrivateKey, format KeyFormat, passphrase []byte) (der []byte, pemType string, err error) {
+	switch format {
+	case KEY_FORMAT_PKCS1:
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, "", EncryptErrUnsupportedKey
+		}
+		if passphrase != nil {
+			return nil, "", EncryptErrUnsupportedKeyFormat
+		}
+		return x509.MarshalPKCS1PrivateKey(rsaKey), kPEMTypeRSAPrivate, nil
+	case KEY_FORMAT_PKCS8:
+		if der, err = x509.MarshalPKCS8PrivateKey(key); err != nil {
+			return nil, "", err
+		}
+		if passphrase == nil {
+			return der, kPEMTypePrivate, nil
+		}
+		if der, err = encryptPKCS8(der, passphrase); err != nil {
+			return nil, "", err
+		}
+		return der, kPEMTypeEncrypted, nil
+	default:
+		return nil, "", EncryptErrUnsupportedKeyFormat
+	}
+}
+
+func marshalPublicKey(key crypto.PublicKey, format KeyFormat) (der []byte, pemType string, err error) {
+	switch format {
+	case KEY_FORMAT_PKCS1:
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return nil, "", EncryptErrUnsupportedKey
+		}
+		return x509.MarshalPKCS1PublicKey(rsaKey), kPEMTypeRSAPublic, nil
+	case KEY_FORMAT_PKIX:
+		if der, err = x509.MarshalPKIXPublicKey(key); err != nil {
+			return nil, "", err
+		}
+		return der, kPEMTypePublic, nil
+	default:
+		return nil, "", EncryptErrUnsupportedKeyFormat
+	}
+}
+
+/*********************** 密钥编码 ********************/
+
+/*********************** 加密的 PKCS#8 (RFC 5958, PBES2 RFC 8018) ********************/
+type pkcs8EncryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                      `asn1:"optional"`
+	PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+func encryptPKCS8(der, passphrase []byte) ([]byte, error) {
+	salt := make([]byte, kPBKDF2SaltSize)
+	iv := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+
+	key := pbkdf2.Key(passphrase, salt, kPBKDF2Iterations, 32, sha256.New)
+	encrypted, err := aesCBCEncrypt(der, key, iv)
+	if err != nil {
+		return nil, err
+	}
+
+	kdfParams, err := asn1.Marshal(pbkdf2Params{
+		Salt:           salt,
+		IterationCount: kPBKDF2Iterations,
+		PRF:            pkix.AlgorithmIdentifier{Algorithm: oidHMACSHA256, Parameters: asn1.NullRawValue},
+	})
+	if err != nil {
+		return nil, err
+	}
+	ivParams, err := asn1.Marshal(iv)
+	if err != nil {
+		return nil, err
+	}
+	schemeParams, err := asn1.Marshal(pbes2Params{
+		KeyDerivationFunc: pkix.AlgorithmIdentifier{Algorithm: oidPBKDF2, Parameters: asn1.RawValue{FullBytes: kdfParams}},
+		EncryptionScheme:  pkix.AlgorithmIdentifier{Algorithm: oidAES256CBC, Parameters: asn1.RawValue{FullBytes: ivParams}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(pkcs8EncryptedPrivateKeyInfo{
+		Algorithm:     pkix.AlgorithmIdentifier{Algorithm: oidPBES2, Parameters: asn1.RawValue{FullBytes: schemeParams}},
+		EncryptedData: encrypted,
+	})
+}
+
+func parseEncryptedPKCS8(der, passphrase []byte) (crypto.PrivateKey, error) {
+	var info pkcs8EncryptedPrivateKeyInfo
+	if rest, err := asn1.Unmarshal(der, &info); err != nil || len(rest) != 0 {
+		return nil, EncryptErrUnsupportedKeyFormat
+	}
+	if !info.Algorithm.Algorithm.Equal(oidPBES2) {
+		return nil, EncryptErrUnsupportedAlgorithm
+	}
+	if passphrase == nil {
+		return nil, EncryptErrPassphraseRequired
+	}
+
+	var scheme pbes2Params
+	if _, err := asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &scheme); err != nil {
+		return nil, EncryptErrUnsupportedKeyFormat
+	}
+	if !scheme.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+		return nil, EncryptErrUnsupportedAlgorithm
+	}
+
+	var kdf pbkdf2Params
+	if _, err := asn1.Unmarshal(scheme.KeyDerivationFunc.Parameters.FullBytes, &kdf); err != nil {
+		return nil, EncryptErrUnsupportedKeyFormat
+	}
+	if kdf.IterationCount < 1 || kdf.IterationCount > kPBKDF2MaxIterations {
+		return nil, EncryptErrUnsupportedKeyFormat
+	}
+
+	var prf func() hash.Hash
+	switch {
+	case len(kdf.PRF.Algorithm) == 0 || kdf.PRF.Algorithm.Equal(oidHMACSHA1):
+		prf = sha1.New
+	case kdf.PRF.Algorithm.Equal(oidHMACSHA256):
+		prf = sha256.New
+	default:
+		return nil, EncryptErrUnsupportedAlgorithm
+	}
+
+	var keySize int
+	switch {
+	case scheme.EncryptionScheme.Algorithm.Equal(oidAES128CBC):
+		keySize = 16
+	case scheme.EncryptionScheme.Algorithm.Equal(oidAES192CBC):
+		keySize = 24
+	case scheme.EncryptionScheme.Algorithm.Equal(oidAES256CBC):
+		keySize = 32
+	default:
+		return nil, EncryptErrUnsupportedAlgorithm
+	}
+
+	var iv []byte
+	if _, err := asn1.Unmarshal(scheme.EncryptionScheme.Parameters.FullBytes, &iv); err != nil || len(iv) != 16 {
+		return nil, EncryptErrUnsupportedKeyFormat
+	}
+
+	key := pbkdf2.Key(passphrase, kdf.Salt, kdf.IterationCount, keySize, prf)
+	decrypted, err := aesCBCDecrypt(info.EncryptedData, key, iv)
+	if err != nil {
+		return nil, EncryptErrIncorrectPassphrase
+	}
+
+	privateKey, err := x509.ParsePKCS8PrivateKey(decrypted)
+	if err != nil {
+		return nil, EncryptErrIncorrectPassphrase
+	}
+	return privateKey, nil
+}
+
+/*********************** 加密的 PKCS#8 ********************/
@@ -0,0 +1,99 @@
+package XPSuperKit
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/asn1"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestKeyMarshalParse(t *testing.T) {
+	rsaKey, err := GenerateRSAKey(2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GenerateRSAKey(1024); err == nil {
+		t.Fatal("1024-bit RSA key generated")
+	}
+	ecKey, _ := GenerateECDSAKey(elliptic.P256())
+	_, edKey, _ := GenerateEd25519Key()
+
+	for _, key := range []interface{}{rsaKey, ecKey, edKey} {
+		for _, format := range []KeyFormat{KEY_FORMAT_PKCS1, KEY_FORMAT_PKCS8} {
+			data, err := MarshalPrivateKeyPEM(key, format, nil)
+			if err != nil {
+				continue // PKCS#1 只支持 RSA
+			}
+			if _, err := ParsePrivateKey(data, nil); err != nil {
+				t.Fatalf("%T %v: %v", key, format, err)
+			}
+		}
+
+		data, err := MarshalPrivateKeyPEM(key, KEY_FORMAT_PKCS8, []byte("pw"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ParsePrivateKey(data, nil); err != EncryptErrPassphraseRequired {
+			t.Fatalf("%T without passphrase: %v", key, err)
+		}
+		if _, err := ParsePrivateKey(data, []byte("bad")); err != EncryptErrIncorrectPassphrase {
+			t.Fatalf("%T with wrong passphrase: %v", key, err)
+		}
+		if _, err := ParsePrivateKey(data, []byte("pw")); err != nil {
+			t.Fatalf("%T: %v", key, err)
+		}
+	}
+
+	data, _ := MarshalPublicKeyPEM(&ecKey.PublicKey, KEY_FORMAT_PKIX)
+	if publicKey, err := ParsePublicKey(data); err != nil {
+		t.Fatal(err)
+	} else if _, ok := publicKey.(*ecdsa.PublicKey); !ok {
+		t.Fatalf("parsed %T", publicKey)
+	}
+
+	dir := t.TempDir()
+	SavePrivateKeyFile(filepath.Join(dir, "private.pem"), rsaKey, KEY_FORMAT_PKCS1, nil)
+	SavePublicKeyFile(filepath.Join(dir, "public.pem"), &rsaKey.PublicKey, KEY_FORMAT_PKCS1)
+	if key, _ := GetPrivateKey(filepath.Join(dir, "private.pem")); key == nil || key.N.Cmp(rsaKey.N) != 0 {
+		t.Fatal("GetPrivateKey")
+	}
+	if key, _ := GetPublicKey(filepath.Join(dir, "public.pem")); key == nil {
+		t.Fatal("GetPublicKey")
+	}
+	if fileInfo, _ := os.Stat(filepath.Join(dir, "private.pem")); fileInfo.Mode().Perm() != 0600 {
+		t.Fatalf("private key file mode %v", fileInfo.Mode())
+	}
+}
+
+func TestEncryptedPKCS8IterationLimit(t *testing.T) {
+	ecKey, _ := GenerateECDSAKey(elliptic.P256())
+	der, err := MarshalPrivateKeyDER(ecKey, KEY_FORMAT_PKCS8, []byte("pw"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 将迭代次数改为 10 亿次
+	var info pkcs8EncryptedPrivateKeyInfo
+	var scheme pbes2Params
+	var kdf pbkdf2Params
+	asn1.Unmarshal(der, &info)
+	asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &scheme)
+	asn1.Unmarshal(scheme.KeyDerivationFunc.Parameters.FullBytes, &kdf)
+	kdf.IterationCount = 1000000000
+	kdfParams, _ := asn1.Marshal(kdf)
+	scheme.KeyDerivationFunc.Parameters = asn1.RawValue{FullBytes: kdfParams}
+	schemeParams, _ := asn1.Marshal(scheme)
+	info.Algorithm.Parameters = asn1.RawValue{FullBytes: schemeParams}
+	crafted, _ := asn1.Marshal(info)
+
+	start := time.Now()
+	if _, err := parseEncryptedPKCS8(crafted, []byte("pw")); err != EncryptErrUnsupportedKeyFormat {
+		t.Fatalf("expected EncryptErrUnsupportedKeyFormat, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("rejecting the key took %v", elapsed)
+	}
+}
@@ -0,0 +1,331 @@
+package XPSuperKit
+
+import (
+	"bufio"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+)
+
+// 流式 AEAD 加解密，基于 STREAM 分段结构
+// 明文被切分为固定长度的分段，每个分段单独加密并认证，因此无需将全部数据读入内存
+// 每个分段的 nonce 为: 随机前缀 | 分段序号(4 字节) | 是否为最后一个分段(1 字节)
+// 分段被重排、删除或截断都会导致解密失败
+//
+// 密文格式为: 版本(1 字节) | 算法(1 字节) | 分段长度(4 字节) | nonce 前缀 | 分段1 | 分段2 | ...
+//
+// 调用示例:
+//
+//	w, err := XPSuperKit.NewEncryptWriter(file, XPSuperKit.AEAD_AES_GCM, key, nil)
+//	io.Copy(w, src)
+//	w.Close()
+//
+//	r, err := XPSuperKit.NewDecryptReader(file, key, nil)
+//	io.Copy(dst, r)
+
+const (
+	kAEADStreamVersion1      = 0x81
+	kAEADStreamHeaderSize    = 6
+	kAEADStreamSegmentSize   = 64 * 1024
+	kAEADStreamMaxSegment    = 16 * 1024 * 1024
+	kAEADStreamNonceTailSize = 5
+)
+
+var (
+	// 密文在最后一个分段之前结束
+	EncryptErrTruncated = errors.New("encrypt: stream truncated")
+	// 分段数量超出上限
+	EncryptErrStreamTooLong = errors.New("encrypt: stream too long")
+)
+
+type aeadStream struct {
+	aead        cipher.AEAD
+	header      []byte
+	noncePrefix []byte
+	counter     uint32
+	exhausted   bool
+}
+
+func (s *aeadStream) nonce(final bool) ([]byte, error) {
+	if s.exhausted {
+		return nil, EncryptErrStreamTooLong
+	}
+
+	nonce := make([]byte, len(s.noncePrefix)+kAEADStreamNonceTailSize)
+	copy(nonce, s.noncePrefix)
+	binary.BigEndian.PutUint32(nonce[len(s.noncePrefix):], s.counter)
+
+	if final {
+		nonce[len(nonce)-1] = 1
+	}
+
+	s.counter++
+	if s.counter == 0 {
+		s.exhausted = true
+	}
+
+	return nonce, nil
+}
+
+type aeadStreamWriter struct {
+	aeadStream
+	w              io.Writer
+	additionalData []byte
+	buf            []byte
+	closed         bool
+}
+
+/**
+ * 创建加密 Writer，写入的明文将被分段加密后写入 w
+ * 必须调用 Close 写入最后一个分段，Close 不会关闭 w
+ * @param w io.Writer 密文输出
+ * @param alg AEADAlgorithm 加密算法
+ * @param key []byte 密钥
+ * @param additionalData []byte 附加认证数据，解密时必须提供相同的值，可为 nil
+ */
+func NewEncryptWriter(w io.Writer, alg AEADAlgorithm, key, additionalData []byte) (io.WriteCloser, error) {
+	aead, err := newAEAD(alg, key)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, kAEADStreamHeaderSize+aead.NonceSize()-kAEADStreamNonceTailSize)
+	header[0] = kAEADStreamVersion1
+	header[1] = byte(alg)
+	binary.BigEndian.PutUint32(header[2:kAEADStreamHeaderSize], kAEADStreamSegmentSize)
+	if _, err := rand.Read(header[kAEADStreamHeaderSize:]); err != nil {
+		return nil, err
+	}
+
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+
+	return &aeadStreamWriter{
+		aeadStream: aeadStream{
+			aead:        aead,
+			header:      header,
+			noncePrefix: header[kAEADStreamHeaderSize:],
+		},
+		w:              w,
+		additionalData: bytesCombine(header, additionalData),
+		buf:            make([]byte, 0, kAEADStreamSegmentSize),
+	}, nil
+}
+
+func (sw *aeadStreamWriter) Write(p []byte) (n int, err error) {
+	if sw.closed {
+		return 0, errors.New("encrypt: write to closed stream")
+	}
+
+	for len(p) > 0 {
+		// 缓冲区已满且仍有数据时才写出，保证最后一个分段在 Close 时写出
+		if len(sw.buf) == cap(sw.buf) {
+			if err = sw.flush(false); err != nil {
+				return
+			}
+		}
+
+		written := copy(sw.buf[len(sw.buf):cap(sw.buf)], p)
+		sw.buf = sw.buf[:len(sw.buf)+written]
+		p = p[written:]
+		n += written
+	}
+
+	return
+}
+
+func (sw *aeadStreamWriter) flush(final bool) error {
+	nonce, err := sw.nonce(final)
+	if err != nil {
+		return err
+	}
+
+	if _, err = sw.w.Write(sw.aead.Seal(nil, nonce, sw.buf, sw.additionalData)); err != nil {
+		return err
+	}
+
+	sw.buf = sw.buf[:0]
+	return nil
+}
+
+func (sw *aeadStreamWriter) Close() error {
+	if sw.closed {
+		return nil
+	}
+
+	sw.closed = true
+	return sw.flush(true)
+}
+
+type aeadStreamReader struct {
+	aeadStream
+	r              *bufio.Reader
+	additionalData []byte
+	segment        []byte
+	plaintext      []byte
+	final          bool
+	err            error //第一次读取失败的错误，之后的 Read 均返回该错误
+}
+
+/**
+ * 创建解密 Reader，从 r 读取 NewEncryptWriter 生成的密文并返回明文
+ * 任何分段认证失败或密文被截断时 Read 返回错误，此前已返回的明文均已通过认证
+ * @param r io.Reader 密文输入
+ * @param key []byte 密钥
+ * @param additionalData []byte 加密时使用的附加认证数据
+ */
+func NewDecryptReader(r io.Reader, key, additionalData []byte) (io.Reader, error) {
+	br := bufio.NewReader(r)
+
+	prefix := make([]byte, kAEADStreamHeaderSize)
+	if _, err := io.ReadFull(br, prefix); err != nil {
+		return nil, EncryptErrInvalidCiphertext
+	}
+	if prefix[0] != kAEADStreamVersion1 {
+		return nil, EncryptErrUnsupportedVersion
+	}
+
+	aead, err := newAEAD(AEADAlgorithm(prefix[1]), key)
+	if err != nil {
+		return nil, err
+	}
+
+	segmentSize := binary.BigEndian.Uint32(prefix[2:])
+	if segmentSize == 0 || segmentSize > kAEADStreamMaxSegment {
+		return nil, EncryptErrInvalidCiphertext
+	}
+
+	header := make([]byte, kAEADStreamHeaderSize+aead.NonceSize()-kAEADStreamNonceTailSize)
+	copy(header, prefix)
+	if _, err := io.ReadFull(br, header[kAEADStreamHeaderSize:]); err != nil {
+		return nil, EncryptErrInvalidCiphertext
+	}
+
+	return &aeadStreamReader{
+		aeadStream: aeadStream{
+			aead:        aead,
+			header:      header,
+			noncePrefix: header[kAEADStreamHeaderSize:],
+		},
+		r:              br,
+		additionalData: bytesCombine(header, additionalData),
+		segment:        make([]byte, int(segmentSize)+aead.Overhead()),
+	}, nil
+}
+
+func (sr *aeadStreamReader) Read(p []byte) (int, error) {
+	for len(sr.plaintext) == 0 {
+		if sr.err != nil {
+			return 0, sr.err
+		}
+
+		if sr.final {
+			return 0, io.EOF
+		}
+
+		// 出错后计数器已前进，保留第一个错误，不再解密之后的分段
+		sr.err = sr.next()
+	}
+
+	n := copy(p, sr.plaintext)
+	sr.plaintext = sr.plaintext[n:]
+	return n, nil
+}
+
+// 读取并解密下一个分段，读到输入末尾的分段视为最后一个分段
+func (sr *aeadStreamReader) next() error {
+	n, err := io.ReadFull(sr.r, sr.segment)
+
+	switch {
+	case err == io.EOF:
+		return EncryptErrTruncated
+	case err == io.ErrUnexpectedEOF:
+		sr.final = true
+	case err != nil:
+		return err
+	default:
+		if _, err := sr.r.Peek(1); err == io.EOF {
+			sr.final = true
+		} else if err != nil {
+			return err
+		}
+	}
+
+	nonce, err := sr.nonce(sr.final)
+	if err != nil {
+		return err
+	}
+
+	if sr.plaintext, err = sr.aead.Open(sr.segment[:0], nonce, sr.segment[:n], sr.additionalData); err != nil {
+		if sr.final {
+			return EncryptErrTruncated
+		}
+		return EncryptErrInvalidCiphertext
+	}
+
+	return nil
+}
+
+//将 src 的全部内容加密后写入 dst
+func EncryptStream(dst io.Writer, src io.Reader, alg AEADAlgorithm, key, additionalData []byte) error {
+	w, err := NewEncryptWriter(dst, alg, key, additionalData)
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(w, src); err != nil {
+		return err
+	}
+
+	return w.Close()
+}
+
+//将 src 的全部内容解密后写入 dst，返回错误时 dst 中可能已写入部分已认证的明文
+func DecryptStream(dst io.Writer, src io.Reader, key, additionalData []byte) error {
+	r, err := NewDecryptReader(src, key, additionalData)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(dst, r)
+	return err
+}
+
+/**
+ * 加密文件
+ * @param srcPath string 明文文件路径
+ * @param dstPath string 密文文件路径
+ */
+func EncryptFile(srcPath, dstPath string, alg AEADAlgorithm, key, additionalData []byte) error {
+	return transformFile(srcPath, dstPath, func(dst io.Writer, src io.Reader) error {
+		return EncryptStream(dst, src, alg, key, additionalData)
+	})
+}
+
+/**
+ * 解密文件，解密全部成功后才会生成 dstPath，不会留下不完整的明文文件
+ * @param srcPath string 密文文件路径
+ * @param dstPath string 明文文件路径
+ */
+func DecryptFile(srcPath, dstPath string, key, additionalData []byte) error {
+	return transformFile(srcPath, dstPath, func(dst io.Writer, src io.Reader) error {
+		return DecryptStream(dst, src, key, additionalData)
+	})
+}
+
+//先写入同一目录下的临时文件，成功后再重命名为 dstPath
+func transformFile(srcPath, dstPath string, transform func(dst io.Writer, src io.Reader) error) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	return writeFileAtomic(dstPath, 0600, func(dst io.Writer) error {
+		return transform(dst, src)
+	})
+}
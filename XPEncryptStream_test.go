@@ -0,0 +1,171 @@
+package XPSuperKit
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func decryptStreamBytes(ciphertext, key, additionalData []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	err := DecryptStream(&buf, bytes.NewReader(ciphertext), key, additionalData)
+	return buf.Bytes(), err
+}
+
+func TestEncryptStreamRoundTrip(t *testing.T) {
+	key := bytes.Repeat([]byte{2}, 32)
+	for _, alg := range []AEADAlgorithm{AEAD_AES_GCM, AEAD_CHACHA20_POLY1305, AEAD_XCHACHA20_POLY1305} {
+		for _, size := range []int{0, 1, kAEADStreamSegmentSize - 1, kAEADStreamSegmentSize, kAEADStreamSegmentSize*2 + 5} {
+			data := make([]byte, size)
+			rand.Read(data)
+
+			var enc bytes.Buffer
+			if err := EncryptStream(&enc, bytes.NewReader(data), alg, key, []byte("ad")); err != nil {
+				t.Fatal(alg, size, err)
+			}
+			if plaintext, err := decryptStreamBytes(enc.Bytes(), key, []byte("ad")); err != nil || !bytes.Equal(plaintext, data) {
+				t.Fatalf("%v/%d: %v", alg, size, err)
+			}
+			if _, err := decryptStreamBytes(enc.Bytes(), key, []byte("other")); err == nil {
+				t.Fatalf("%v/%d: wrong additional data accepted", alg, size)
+			}
+		}
+	}
+}
+
+func TestEncryptStreamTruncationAndTamper(t *testing.T) {
+	key := bytes.Repeat([]byte{2}, 32)
+	for _, alg := range []AEADAlgorithm{AEAD_AES_GCM, AEAD_XCHACHA20_POLY1305} {
+		data := make([]byte, kAEADStreamSegmentSize*3)
+		rand.Read(data)
+		var enc bytes.Buffer
+		if err := EncryptStream(&enc, bytes.NewReader(data), alg, key, nil); err != nil {
+			t.Fatal(err)
+		}
+		ciphertext := enc.Bytes()
+
+		aead, _ := newAEAD(alg, key)
+		headerSize := kAEADStreamHeaderSize + aead.NonceSize() - kAEADStreamNonceTailSize
+		segmentSize := kAEADStreamSegmentSize + aead.Overhead()
+
+		if len(ciphertext) != headerSize+3*segmentSize {
+			t.Fatalf("%v: unexpected ciphertext length %d", alg, len(ciphertext))
+		}
+
+		// 在分段边界截断时每个分段本身都能解密，只能依靠最后分段标记发现截断
+		for i := 0; i < 3; i++ {
+			if _, err := decryptStreamBytes(ciphertext[:headerSize+i*segmentSize], key, nil); err != EncryptErrTruncated {
+				t.Fatalf("%v: truncation after %d segment(s): %v", alg, i, err)
+			}
+		}
+		for _, cut := range []int{headerSize - 1, headerSize + 1, headerSize + segmentSize + 100, len(ciphertext) - 1} {
+			if _, err := decryptStreamBytes(ciphertext[:cut], key, nil); err == nil {
+				t.Fatalf("%v: truncation to %d bytes undetected", alg, cut)
+			}
+		}
+
+		appended := append(append([]byte(nil), ciphertext...), 0)
+		if _, err := decryptStreamBytes(appended, key, nil); err == nil {
+			t.Fatalf("%v: trailing data accepted", alg)
+		}
+
+		reordered := append([]byte(nil), ciphertext...)
+		copy(reordered[headerSize:], ciphertext[headerSize+segmentSize:headerSize+2*segmentSize])
+		copy(reordered[headerSize+segmentSize:], ciphertext[headerSize:headerSize+segmentSize])
+		if _, err := decryptStreamBytes(reordered, key, nil); err != EncryptErrInvalidCiphertext {
+			t.Fatalf("%v: reordered segments: %v", alg, err)
+		}
+
+		for _, i := range []int{0, 1, headerSize - 1, headerSize, headerSize + segmentSize + 7, len(ciphertext) - 1} {
+			tampered := append([]byte(nil), ciphertext...)
+			tampered[i] ^= 0x01
+			if _, err := decryptStreamBytes(tampered, key, nil); err == nil {
+				t.Fatalf("%v: tampering byte %d undetected", alg, i)
+			}
+		}
+	}
+}
+
+func TestEncryptFile(t *testing.T) {
+	dir := t.TempDir()
+	key := bytes.Repeat([]byte{2}, 32)
+	src, enc, dec := filepath.Join(dir, "src"), filepath.Join(dir, "enc"), filepath.Join(dir, "dec")
+	ioutil.WriteFile(src, []byte("file data"), 0600)
+
+	if err := EncryptFile(src, enc, AEAD_AES_GCM, key, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := DecryptFile(enc, dec, key, nil); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := ioutil.ReadFile(dec); string(data) != "file data" {
+		t.Fatalf("%q", data)
+	}
+
+	// 解密失败时不应留下不完整的输出文件
+	failed := filepath.Join(dir, "failed")
+	if err := DecryptFile(enc, failed, bytes.Repeat([]byte{3}, 32), nil); err == nil {
+		t.Fatal("wrong key accepted")
+	}
+	if _, err := os.Stat(failed); !os.IsNotExist(err) {
+		t.Fatal("partial output left behind")
+	}
+}
+
+func TestDecryptReaderStickyError(t *testing.T) {
+	key := bytes.Repeat([]byte{2}, 32)
+	data := make([]byte, kAEADStreamSegmentSize*3)
+	rand.Read(data)
+	var enc bytes.Buffer
+	if err := EncryptStream(&enc, bytes.NewReader(data), AEAD_AES_GCM, key, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// 篡改第一个分段后，之后的 Read 不能继续返回后面分段的明文
+	ciphertext := enc.Bytes()
+	ciphertext[kAEADStreamHeaderSize+12-kAEADStreamNonceTailSize] ^= 0x01
+	r, err := NewDecryptReader(bytes.NewReader(ciphertext), key, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, kAEADStreamSegmentSize)
+	for i := 0; i < 4; i++ {
+		if n, err := r.Read(buf); n != 0 || err != EncryptErrInvalidCiphertext {
+			t.Fatalf("read %d: %d bytes, %v", i, n, err)
+		}
+	}
+}
+
+func TestEncryptFileConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	key := bytes.Repeat([]byte{2}, 32)
+	src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+	ioutil.WriteFile(src, bytes.Repeat([]byte("data"), kAEADStreamSegmentSize), 0600)
+
+	// 已存在的同名 .tmp 文件不应被覆盖
+	ioutil.WriteFile(dst+".tmp", []byte("keep"), 0600)
+
+	errs := make(chan error, 8)
+	for i := 0; i < cap(errs); i++ {
+		go func() { errs <- EncryptFile(src, dst, AEAD_AES_GCM, key, nil) }()
+	}
+	for i := 0; i < cap(errs); i++ {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := DecryptFile(dst, filepath.Join(dir, "out"), key, nil); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := ioutil.ReadFile(dst + ".tmp"); string(data) != "keep" {
+		t.Fatalf("existing .tmp file overwritten: %q", data)
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 4 {
+		t.Fatalf("temporary files left behind: %d files", len(files))
+	}
+}
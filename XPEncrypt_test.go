@@ -0,0 +1,252 @@
+package XPSuperKit
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+)
+
+func TestAEADRoundTripAndTamper(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	ad := []byte("header")
+	for _, alg := range []AEADAlgorithm{AEAD_AES_GCM, AEAD_CHACHA20_POLY1305, AEAD_XCHACHA20_POLY1305} {
+		ciphertext, err := AEADEncrypt(alg, key, []byte("hello"), ad)
+		if err != nil {
+			t.Fatal(alg, err)
+		}
+		if plaintext, err := AEADDecrypt(key, ciphertext, ad); err != nil || string(plaintext) != "hello" {
+			t.Fatalf("%v: %q, %v", alg, plaintext, err)
+		}
+
+		if _, err := AEADDecrypt(key, ciphertext, []byte("other")); err != EncryptErrInvalidCiphertext {
+			t.Fatalf("%v: wrong additional data: %v", alg, err)
+		}
+		if _, err := AEADDecrypt(bytes.Repeat([]byte{2}, 32), ciphertext, ad); err != EncryptErrInvalidCiphertext {
+			t.Fatalf("%v: wrong key: %v", alg, err)
+		}
+
+		// 修改密文任意一个字节均应校验失败
+		for i := range ciphertext {
+			tampered := append([]byte(nil), ciphertext...)
+			tampered[i] ^= 0x01
+			if _, err := AEADDecrypt(key, tampered, ad); err == nil {
+				t.Fatalf("%v: tampering byte %d undetected", alg, i)
+			}
+		}
+		for n := 0; n < len(ciphertext); n++ {
+			if _, err := AEADDecrypt(key, ciphertext[:n], ad); err == nil {
+				t.Fatalf("%v: truncation to %d bytes undetected", alg, n)
+			}
+		}
+	}
+}
+
+func TestPKCS7UnPaddingWithCheck(t *testing.T) {
+	if data, err := PKCS7UnPaddingWithCheck([]byte{1, 2, 2, 2}, 4); err != nil || !bytes.Equal(data, []byte{1, 2}) {
+		t.Fatal(data, err)
+	}
+	for _, bad := range [][]byte{nil, {1, 2, 3, 3}, {1, 2, 3, 0}, {5, 5, 5, 5}, {1, 2, 3}} {
+		if _, err := PKCS7UnPaddingWithCheck(bad, 4); err != EncryptErrInvalidPadding {
+			t.Fatalf("%v: %v", bad, err)
+		}
+	}
+	if PKCS5UnPadding([]byte{9}) != nil || PKCS5UnPadding(nil) != nil {
+		t.Fatal("invalid padding not rejected")
+	}
+
+	key := bytes.Repeat([]byte{1}, 16)
+	crypt, _ := AESEncrypt([]byte("hello"), key, key)
+	if data, err := AESDecrypt(crypt, key, key); err != nil || data != "hello" {
+		t.Fatal(data, err)
+	}
+	if _, err := AESDecrypt("AAAA", key, key); err != EncryptErrInvalidBlockSize {
+		t.Fatalf("short ciphertext: %v", err)
+	}
+}
+
+func TestAESKeyWrapRFC3394(t *testing.T) {
+	kek := mustHex("000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F")
+	data := mustHex("00112233445566778899AABBCCDDEEFF000102030405060708090A0B0C0D0E0F")
+
+	// RFC 3394 第 4 节测试向量
+	for _, c := range []struct {
+		kekSize, dataSize int
+		wrapped           string
+	}{
+		{16, 16, "1FA68B0A8112B447AEF34BD8FB5A7B829D3E862371D2CFE5"},
+		{24, 16, "96778B25AE6CA435F92B5B97C050AED2468AB8A17AD84E5D"},
+		{32, 16, "64E8C3F9CE0F5BA263E9777905818A2A93C8191E7D6E8AE7"},
+		{24, 24, "031D33264E15D33268F24EC260743EDCE1C6C7DDEE725A936BA814915C6762D2"},
+		{32, 24, "A8F9BC1612C68B3FF6E6F4FBE30E71E4769C8B80A32CB8958CD5D17D6B254DA1"},
+		{32, 32, "28C9F404C4B810F4CBCCB35CFB87F8263F5786E2D80ED326CBC7F0E71A99F43BFB988B9B7A02DD21"},
+	} {
+		wrapped, err := AESKeyWrap(kek[:c.kekSize], data[:c.dataSize])
+		if err != nil || !bytes.Equal(wrapped, mustHex(c.wrapped)) {
+			t.Fatalf("KEK %d/data %d: %X, %v", c.kekSize, c.dataSize, wrapped, err)
+		}
+		unwrapped, err := AESKeyUnwrap(kek[:c.kekSize], wrapped)
+		if err != nil || !bytes.Equal(unwrapped, data[:c.dataSize]) {
+			t.Fatalf("KEK %d/data %d: unwrap %X, %v", c.kekSize, c.dataSize, unwrapped, err)
+		}
+
+		wrapped[len(wrapped)-1] ^= 0x01
+		if _, err := AESKeyUnwrap(kek[:c.kekSize], wrapped); err == nil {
+			t.Fatalf("KEK %d/data %d: tampered key unwrapped", c.kekSize, c.dataSize)
+		}
+	}
+
+	if _, err := AESKeyWrap(kek[:16], data[:12]); err == nil {
+		t.Fatal("short key wrapped")
+	}
+	if _, err := AESKeyUnwrap(kek[:16], data[:16]); err == nil {
+		t.Fatal("short wrapped key accepted")
+	}
+}
+
+func rsaTestKeys(t *testing.T, bits ...int) []*rsa.PrivateKey {
+	keys := make([]*rsa.PrivateKey, len(bits))
+	for i, n := range bits {
+		key, err := rsa.GenerateKey(rand.Reader, n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[i] = key
+	}
+	return keys
+}
+
+func TestRSAPartEncryptOAEP(t *testing.T) {
+	keys := rsaTestKeys(t, 2048, 3072)
+	other := rsaTestKeys(t, 2048)[0]
+	label := []byte("label")
+
+	for _, key := range keys {
+		for _, hash := range []crypto.Hash{crypto.SHA1, crypto.SHA256, crypto.SHA512} {
+			// 分段长度由密钥长度及哈希长度决定，在分段边界前后各测试一次
+			chunkSize := key.Size() - 2*hash.Size() - 2
+			for _, n := range []int{0, 1, chunkSize - 1, chunkSize, chunkSize + 1, 2 * chunkSize} {
+				data := bytes.Repeat([]byte{'a'}, n)
+				encoded, err := PublicPartEncryptOAEP(&key.PublicKey, hash, data, label)
+				if err != nil {
+					t.Fatalf("%d bits/%v/%d bytes: %v", key.N.BitLen(), hash, n, err)
+				}
+				ciphertext, _ := base64.StdEncoding.DecodeString(string(encoded))
+				if chunks := (n + chunkSize - 1) / chunkSize; len(ciphertext) != chunks*key.Size() {
+					t.Fatalf("%d bits/%v/%d bytes: %d bytes of ciphertext, want %d chunks", key.N.BitLen(), hash, n, len(ciphertext), chunks)
+				}
+
+				plaintext, err := PrivateDecryptOAEP(key, hash, ciphertext, label)
+				if err != nil || !bytes.Equal(plaintext, data) {
+					t.Fatalf("%d bits/%v/%d bytes: %v", key.N.BitLen(), hash, n, err)
+				}
+				if n == 0 {
+					continue
+				}
+
+				if _, err := PrivateDecryptOAEP(key, hash, ciphertext, nil); err == nil {
+					t.Fatalf("%d bits/%v: wrong label accepted", key.N.BitLen(), hash)
+				}
+				if _, err := PrivateDecryptOAEP(other, hash, ciphertext, label); err == nil {
+					t.Fatalf("%d bits/%v: wrong key accepted", key.N.BitLen(), hash)
+				}
+
+				tampered := append([]byte(nil), ciphertext...)
+				tampered[len(tampered)-1] ^= 0x01
+				if _, err := PrivateDecryptOAEP(key, hash, tampered, label); err == nil {
+					t.Fatalf("%d bits/%v: tampered ciphertext accepted", key.N.BitLen(), hash)
+				}
+				if _, err := PrivateDecryptOAEP(key, hash, ciphertext[:len(ciphertext)-1], label); err != EncryptErrInvalidCiphertext {
+					t.Fatalf("%d bits/%v: truncated ciphertext: %v", key.N.BitLen(), hash, err)
+				}
+			}
+		}
+	}
+
+	small := rsaTestKeys(t, 1024)[0]
+	if _, err := PublicPartEncryptOAEP(&small.PublicKey, crypto.SHA512, []byte("a"), nil); err != EncryptErrKeyTooSmall {
+		t.Fatalf("1024-bit key with SHA-512: %v", err)
+	}
+	if _, err := PublicPartEncryptOAEP(&small.PublicKey, crypto.Hash(0), []byte("a"), nil); err != EncryptErrUnsupportedHash {
+		t.Fatalf("unsupported hash: %v", err)
+	}
+}
+
+func TestRSAPartEncryptPKCS1v15(t *testing.T) {
+	key := rsaTestKeys(t, 2048)[0]
+	chunkSize := key.Size() - kRSAPKCS1v15Overhead
+
+	for _, n := range []int{1, chunkSize, chunkSize + 1} {
+		data := bytes.Repeat([]byte{'a'}, n)
+		encoded, err := PublicPartEncrypt(&key.PublicKey, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ciphertext, _ := base64.StdEncoding.DecodeString(string(encoded))
+		if chunks := (n + chunkSize - 1) / chunkSize; len(ciphertext) != chunks*key.Size() {
+			t.Fatalf("%d bytes: %d bytes of ciphertext, want %d chunks", n, len(ciphertext), chunks)
+		}
+		if plaintext, err := PrivateDecrypt(key, ciphertext); err != nil || !bytes.Equal(plaintext, data) {
+			t.Fatalf("%d bytes: %v", n, err)
+		}
+	}
+}
+
+func TestRSASignWithHash(t *testing.T) {
+	keys := rsaTestKeys(t, 2048, 2048)
+	key, other := keys[0], keys[1]
+	data := []byte("message to sign")
+	tampered := []byte("message to sigN")
+
+	for _, hash := range []crypto.Hash{crypto.SHA1, crypto.SHA256, crypto.SHA512} {
+		sign, err := PrivateSignWithHash(key, hash, data)
+		if err != nil {
+			t.Fatal(hash, err)
+		}
+		if err := PublicVerifySignWithHash(&key.PublicKey, hash, data, sign); err != nil {
+			t.Fatal(hash, err)
+		}
+		if PublicVerifySignWithHash(&key.PublicKey, hash, tampered, sign) == nil ||
+			PublicVerifySignWithHash(&other.PublicKey, hash, data, sign) == nil {
+			t.Fatalf("%v: invalid PKCS#1 v1.5 signature accepted", hash)
+		}
+
+		pss, err := PrivateSignPSS(key, hash, data, nil)
+		if err != nil {
+			t.Fatal(hash, err)
+		}
+		if err := PublicVerifySignPSS(&key.PublicKey, hash, data, pss, nil); err != nil {
+			t.Fatal(hash, err)
+		}
+		if PublicVerifySignPSS(&key.PublicKey, hash, tampered, pss, nil) == nil ||
+			PublicVerifySignPSS(&other.PublicKey, hash, data, pss, nil) == nil {
+			t.Fatalf("%v: invalid PSS signature accepted", hash)
+		}
+
+		sign[0] ^= 0x01
+		pss[0] ^= 0x01
+		if PublicVerifySignWithHash(&key.PublicKey, hash, data, sign) == nil || PublicVerifySignPSS(&key.PublicKey, hash, data, pss, nil) == nil {
+			t.Fatalf("%v: tampered signature accepted", hash)
+		}
+	}
+
+	// SHA1withRSA 与 PublicVerifySignWithHash(crypto.SHA1) 一致
+	sign, err := PrivateSign(key, data)
+	if err != nil || PublicVerifySign(&key.PublicKey, data, sign) != nil || PublicVerifySignWithHash(&key.PublicKey, crypto.SHA1, data, sign) != nil {
+		t.Fatal(err)
+	}
+	if err := PublicVerifySignWithHash(&key.PublicKey, crypto.Hash(0), data, sign); err != EncryptErrUnsupportedHash {
+		t.Fatalf("unsupported hash: %v", err)
+	}
+}
+
+func mustHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
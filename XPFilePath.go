@@ -1,7 +1,9 @@
 package XPSuperKit
 
 import (
+	"bufio"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"os/exec"
@@ -134,4 +136,43 @@ func (p *XPFilePathImpl) ReadFile() (content []byte, err error) {
 		return nil, ErrorN("path object must be of file type")
 	}
 	return nil, ErrorN("path object refers to non-existing entity")
-}
\ No newline at end of file
+}
+func (p *XPFilePathImpl) Open() (*os.File, error) {
+	if p.Exists() {
+		if *p.IsFile() {
+			return os.Open(p.AbsolutePath())
+		}
+		return nil, ErrorN("path object must be of file type")
+	}
+	return nil, ErrorN("path object refers to non-existing entity")
+}
+
+//在 path 所在目录创建唯一的临时文件并由 write 写入，成功后设置权限为 perm 并重命名为 path，失败时删除临时文件
+//并发写入同一路径时互不覆盖临时文件，读取 path 的一方也不会读到写了一半的内容
+func writeFileAtomic(path string, perm os.FileMode, write func(w io.Writer) error) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	bw := bufio.NewWriter(tmp)
+	if err = write(bw); err == nil {
+		err = bw.Flush()
+	}
+	if err == nil {
+		err = tmp.Chmod(perm)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,388 @@
+package XPSuperKit
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// JSON Web Encryption (RFC 7516) 紧凑序列化
+// 格式为 BASE64URL(header).BASE64URL(encrypted key).BASE64URL(iv).BASE64URL(ciphertext).BASE64URL(tag)
+//
+// 调用示例:
+//
+//	jwt := XPSuperKit.XPJwt()
+//	token, err := jwt.SignAndEncrypt(payload, hmacSecret, nil, publicKey, &XPSuperKit.JweEncryptOption{})
+//	header, payload, err := jwt.DecryptAndVerify(token, privateKey, hmacSecret, nil)
+
+// JweKeyAlgorithm represents a supported key management algorithm.
+type JweKeyAlgorithm string
+
+// JweEncryption represents a supported content encryption algorithm.
+type JweEncryption string
+
+const (
+	// RSA-OAEP represents RSAES OAEP using SHA-1 and MGF1 with SHA-1.
+	JweRSAOAEP JweKeyAlgorithm = "RSA-OAEP"
+	// A256KW represents AES Key Wrap using a 256-bit key.
+	JweA256KW JweKeyAlgorithm = "A256KW"
+
+	// A256GCM represents AES GCM using a 256-bit key.
+	JweA256GCM JweEncryption = "A256GCM"
+	// A128CBC-HS256 represents AES_128_CBC_HMAC_SHA_256 authenticated encryption.
+	JweA128CBCHS256 JweEncryption = "A128CBC-HS256"
+)
+
+var (
+	// ErrInvalidJweToken is returned when the formation of the token is not
+	// "XXX.XXX.XXX.XXX.XXX".
+	JweErrInvalidToken = errors.New("jwe: invalid token")
+	// ErrUnsupportedAlgorithm is returned when the "alg" header is not support.
+	JweErrUnsupportedAlgorithm = errors.New("jwe: unsupported key management algorithm")
+	// ErrUnsupportedEncryption is returned when the "enc" header is not support.
+	JweErrUnsupportedEncryption = errors.New("jwe: unsupported content encryption algorithm")
+	// ErrDecryption is returned when the content encryption key can not be
+	// recovered or the authentication tag does not match.
+	JweErrDecryption = errors.New("jwe: decryption failed")
+	// ErrNotNested is returned when a nested JWT is expected but the "cty"
+	// header is not "JWT".
+	JweErrNotNested = errors.New("jwe: content is not a nested jwt")
+
+	jweEncoding = base64.RawURLEncoding
+)
+
+type JweEncryptOption struct {
+	KeyAlgorithm JweKeyAlgorithm //密钥管理算法，默认为 RSA-OAEP
+	Encryption   JweEncryption   //内容加密算法，默认为 A256GCM
+	ContentType  string          //内容类型，嵌套 JWT 时为 "JWT"
+	Header       JwtHeader       //自定义的头，将被合并至 Token 的头部
+}
+
+type jweContentCipher interface {
+	keySize() int
+	ivSize() int
+	seal(cek, iv, plaintext, aad []byte) (ciphertext, tag []byte, err error)
+	open(cek, iv, ciphertext, tag, aad []byte) ([]byte, error)
+}
+
+var jweEncImpMap = map[JweEncryption]jweContentCipher{
+	JweA256GCM:      jweGCM{size: 32},
+	JweA128CBCHS256: jweCBCHMAC{size: 32},
+}
+
+// 使用 key 加密 plaintext，生成 JWE 紧凑序列化的 token
+// 当使用 RSA-OAEP 时，key 为 *rsa.PublicKey
+// 当使用 A256KW   时，key 为 32 字节的 []byte
+// 如果 opt 为 nil，则默认使用 RSA-OAEP 与 A256GCM
+func (jwt *XPJwtImpl) Encrypt(plaintext []byte, key interface{}, opt *JweEncryptOption) ([]byte, error) {
+	if key == nil {
+		return nil, JwtErrEmptySecretOrPrivateKey
+	}
+
+	if opt == nil {
+		opt = &JweEncryptOption{}
+	}
+
+	alg, enc := opt.KeyAlgorithm, opt.Encryption
+
+	if alg == "" {
+		alg = JweRSAOAEP
+	}
+
+	if enc == "" {
+		enc = JweA256GCM
+	}
+
+	contentCipher, ok := jweEncImpMap[enc]
+
+	if !ok {
+		return nil, JweErrUnsupportedEncryption
+	}
+
+	h := map[string]interface{}{
+		"alg": alg,
+		"enc": enc,
+	}
+
+	if opt.ContentType != "" {
+		h["cty"] = opt.ContentType
+	}
+
+	if opt.Header != nil {
+		if err := Map(&h, opt.Header); err != nil {
+			return nil, err
+		}
+	}
+
+	headerJSON, err := json.Marshal(h)
+
+	if err != nil {
+		return nil, err
+	}
+
+	cek := make([]byte, contentCipher.keySize())
+	iv := make([]byte, contentCipher.ivSize())
+
+	if _, err = rand.Read(cek); err != nil {
+		return nil, err
+	}
+
+	if _, err = rand.Read(iv); err != nil {
+		return nil, err
+	}
+
+	encryptedKey, err := jweWrapKey(alg, key, cek)
+
+	if err != nil {
+		return nil, err
+	}
+
+	hBase64 := []byte(jweEncoding.EncodeToString(headerJSON))
+
+	ciphertext, tag, err := contentCipher.seal(cek, iv, plaintext, hBase64)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.Join([][]byte{
+		hBase64,
+		[]byte(jweEncoding.EncodeToString(encryptedKey)),
+		[]byte(jweEncoding.EncodeToString(iv)),
+		[]byte(jweEncoding.EncodeToString(ciphertext)),
+		[]byte(jweEncoding.EncodeToString(tag)),
+	}, periodBytes), nil
+}
+
+// 解密 JWE token，返回 header 和明文
+// 当使用 RSA-OAEP 时，key 为 *rsa.PrivateKey
+// 当使用 A256KW   时，key 为 32 字节的 []byte
+func (jwt *XPJwtImpl) Decrypt(token []byte, key interface{}) (header JwtHeader, plaintext []byte, err error) {
+	segments := bytes.Split(token, periodBytes)
+
+	if len(segments) != 5 {
+		return nil, nil, JweErrInvalidToken
+	}
+
+	decoded := make([][]byte, 5)
+
+	for i, segment := range segments {
+		if decoded[i], err = jweEncoding.DecodeString(string(segment)); err != nil {
+			return nil, nil, JweErrInvalidToken
+		}
+	}
+
+	if err = json.Unmarshal(decoded[0], &header); err != nil {
+		return nil, nil, JweErrInvalidToken
+	}
+
+	alg, _ := header["alg"].(string)
+	enc, _ := header["enc"].(string)
+
+	contentCipher, ok := jweEncImpMap[JweEncryption(enc)]
+
+	if !ok {
+		return nil, nil, JweErrUnsupportedEncryption
+	}
+
+	cek, err := jweUnwrapKey(JweKeyAlgorithm(alg), key, decoded[1])
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(cek) != contentCipher.keySize() || len(decoded[2]) != contentCipher.ivSize() {
+		return nil, nil, JweErrDecryption
+	}
+
+	if plaintext, err = contentCipher.open(cek, decoded[2], decoded[3], decoded[4], segments[0]); err != nil {
+		return nil, nil, JweErrDecryption
+	}
+
+	return
+}
+
+// 先使用 signSecret 签名生成 JWT，再使用 encKey 将其加密为嵌套的 JWE
+func (jwt *XPJwtImpl) SignAndEncrypt(payload JwtPayload, signSecret interface{}, signOpt *JwtSignOption,
+	encKey interface{}, encOpt *JweEncryptOption) ([]byte, error) {
+	signed, err := jwt.Sign(payload, signSecret, signOpt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	nestedOpt := JweEncryptOption{}
+
+	if encOpt != nil {
+		nestedOpt = *encOpt
+	}
+
+	nestedOpt.ContentType = "JWT"
+
+	return jwt.Encrypt(signed, encKey, &nestedOpt)
+}
+
+// 解密嵌套的 JWE，再验证其中的 JWT 并返回内层 JWT 的 header 和 payload
+func (jwt *XPJwtImpl) DecryptAndVerify(token []byte, decKey interface{}, verifySecret interface{},
+	verifyOpt *JwtVerifyOption) (JwtHeader, JwtPayload, error) {
+	header, signed, err := jwt.Decrypt(token, decKey)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if cty, _ := header["cty"].(string); !strings.EqualFold(cty, "JWT") {
+		return nil, nil, JweErrNotNested
+	}
+
+	return jwt.Verify(signed, verifySecret, verifyOpt)
+}
+
+func jweWrapKey(alg JweKeyAlgorithm, key interface{}, cek []byte) ([]byte, error) {
+	switch alg {
+	case JweRSAOAEP:
+		pub, ok := key.(*rsa.PublicKey)
+
+		if !ok {
+			return nil, JwtErrInvalidKeyType
+		}
+
+		return rsa.EncryptOAEP(sha1.New(), rand.Reader, pub, cek, nil)
+	case JweA256KW:
+		kek, ok := key.([]byte)
+
+		if !ok || len(kek) != 32 {
+			return nil, JwtErrInvalidKeyType
+		}
+
+		return AESKeyWrap(kek, cek)
+	default:
+		return nil, JweErrUnsupportedAlgorithm
+	}
+}
+
+func jweUnwrapKey(alg JweKeyAlgorithm, key interface{}, encryptedKey []byte) ([]byte, error) {
+	switch alg {
+	case JweRSAOAEP:
+		priv, ok := key.(*rsa.PrivateKey)
+
+		if !ok {
+			return nil, JwtErrInvalidKeyType
+		}
+
+		cek, err := rsa.DecryptOAEP(sha1.New(), rand.Reader, priv, encryptedKey, nil)
+
+		if err != nil {
+			return nil, JweErrDecryption
+		}
+
+		return cek, nil
+	case JweA256KW:
+		kek, ok := key.([]byte)
+
+		if !ok || len(kek) != 32 {
+			return nil, JwtErrInvalidKeyType
+		}
+
+		cek, err := AESKeyUnwrap(kek, encryptedKey)
+
+		if err != nil {
+			return nil, JweErrDecryption
+		}
+
+		return cek, nil
+	default:
+		return nil, JweErrUnsupportedAlgorithm
+	}
+}
+
+type jweGCM struct {
+	size int
+}
+
+func (g jweGCM) keySize() int { return g.size }
+func (g jweGCM) ivSize() int  { return 12 }
+
+func (g jweGCM) seal(cek, iv, plaintext, aad []byte) ([]byte, []byte, error) {
+	block, err := aes.NewCipher(cek)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sealed := aead.Seal(nil, iv, plaintext, aad)
+	split := len(sealed) - aead.Overhead()
+
+	return sealed[:split], sealed[split:], nil
+}
+
+func (g jweGCM) open(cek, iv, ciphertext, tag, aad []byte) ([]byte, error) {
+	block, err := aes.NewCipher(cek)
+
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return aead.Open(nil, iv, append(append([]byte{}, ciphertext...), tag...), aad)
+}
+
+// AES_CBC_HMAC_SHA2 (RFC 7518 5.2)，cek 前半部分为 MAC 密钥，后半部分为加密密钥
+type jweCBCHMAC struct {
+	size int
+}
+
+func (c jweCBCHMAC) keySize() int { return c.size }
+func (c jweCBCHMAC) ivSize() int  { return 16 }
+
+func (c jweCBCHMAC) tag(macKey, iv, ciphertext, aad []byte) []byte {
+	al := make([]byte, 8)
+	binary.BigEndian.PutUint64(al, uint64(len(aad))*8)
+
+	h := hmac.New(sha256.New, macKey)
+	h.Write(aad)
+	h.Write(iv)
+	h.Write(ciphertext)
+	h.Write(al)
+
+	return h.Sum(nil)[:c.size/2]
+}
+
+func (c jweCBCHMAC) seal(cek, iv, plaintext, aad []byte) ([]byte, []byte, error) {
+	ciphertext, err := aesCBCEncrypt(plaintext, cek[c.size/2:], iv)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ciphertext, c.tag(cek[:c.size/2], iv, ciphertext, aad), nil
+}
+
+func (c jweCBCHMAC) open(cek, iv, ciphertext, tag, aad []byte) ([]byte, error) {
+	if !hmac.Equal(tag, c.tag(cek[:c.size/2], iv, ciphertext, aad)) {
+		return nil, JweErrDecryption
+	}
+
+	return aesCBCDecrypt(ciphertext, cek[c.size/2:], iv)
+}
@@ -0,0 +1,169 @@
+package XPSuperKit
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+)
+
+const jweTestSecret = "0123456789abcdef0123456789abcdef"
+
+type jweTestKey struct {
+	alg      JweKeyAlgorithm
+	encKey   interface{}
+	decKey   interface{}
+	wrongKey interface{}
+}
+
+func jweTestKeys(t *testing.T) []jweTestKey {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kek := bytes.Repeat([]byte{7}, 32)
+
+	return []jweTestKey{
+		{JweRSAOAEP, &priv.PublicKey, priv, other},
+		{JweA256KW, kek, kek, bytes.Repeat([]byte{8}, 32)},
+	}
+}
+
+func TestJweRoundTrip(t *testing.T) {
+	jwt := XPJwt()
+	for _, key := range jweTestKeys(t) {
+		for _, enc := range []JweEncryption{JweA256GCM, JweA128CBCHS256} {
+			for _, plaintext := range [][]byte{{}, []byte("hello world"), bytes.Repeat([]byte{'x'}, 1000)} {
+				token, err := jwt.Encrypt(plaintext, key.encKey, &JweEncryptOption{KeyAlgorithm: key.alg, Encryption: enc})
+				if err != nil {
+					t.Fatal(key.alg, enc, err)
+				}
+
+				header, decrypted, err := jwt.Decrypt(token, key.decKey)
+				if err != nil || !bytes.Equal(decrypted, plaintext) {
+					t.Fatalf("%v/%v: %q, %v", key.alg, enc, decrypted, err)
+				}
+				if header["alg"] != string(key.alg) || header["enc"] != string(enc) {
+					t.Fatalf("%v/%v: header %v", key.alg, enc, header)
+				}
+
+				if _, _, err := jwt.Decrypt(token, key.wrongKey); err != JweErrDecryption {
+					t.Fatalf("%v/%v: wrong key: %v", key.alg, enc, err)
+				}
+			}
+		}
+	}
+}
+
+func TestJweTamper(t *testing.T) {
+	jwt := XPJwt()
+	for _, key := range jweTestKeys(t) {
+		for _, enc := range []JweEncryption{JweA256GCM, JweA128CBCHS256} {
+			token, err := jwt.Encrypt([]byte("hello world"), key.encKey, &JweEncryptOption{KeyAlgorithm: key.alg, Encryption: enc})
+			if err != nil {
+				t.Fatal(err)
+			}
+			segments := bytes.Split(token, periodBytes)
+
+			// 修改或截断五个部分中的任意一个均应失败
+			for i := range segments {
+				decoded, err := jweEncoding.DecodeString(string(segments[i]))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				flipped := append([]byte(nil), decoded...)
+				flipped[len(flipped)/2] ^= 0x01
+				truncated := decoded[:len(decoded)-1]
+
+				for name, part := range map[string][]byte{"tampered": flipped, "truncated": truncated, "empty": nil} {
+					parts := append([][]byte(nil), segments...)
+					parts[i] = []byte(jweEncoding.EncodeToString(part))
+					if _, _, err := jwt.Decrypt(bytes.Join(parts, periodBytes), key.decKey); err == nil {
+						t.Fatalf("%v/%v: %v part %d accepted", key.alg, enc, name, i)
+					}
+				}
+
+				missing := append(append([][]byte(nil), segments[:i]...), segments[i+1:]...)
+				if _, _, err := jwt.Decrypt(bytes.Join(missing, periodBytes), key.decKey); err != JweErrInvalidToken {
+					t.Fatalf("%v/%v: token without part %d: %v", key.alg, enc, i, err)
+				}
+			}
+
+			if _, _, err := jwt.Decrypt(append(token, '.'), key.decKey); err != JweErrInvalidToken {
+				t.Fatalf("%v/%v: token with six parts: %v", key.alg, enc, err)
+			}
+		}
+	}
+}
+
+func TestJweNested(t *testing.T) {
+	jwt := XPJwt()
+	for _, key := range jweTestKeys(t) {
+		token, err := jwt.SignAndEncrypt(JwtPayload{"uid": "1"}, jweTestSecret, &JwtSignOption{Issuer: "me", Expiration: time.Hour},
+			key.encKey, &JweEncryptOption{KeyAlgorithm: key.alg, Encryption: JweA128CBCHS256})
+		if err != nil {
+			t.Fatal(key.alg, err)
+		}
+
+		header, payload, err := jwt.DecryptAndVerify(token, key.decKey, jweTestSecret, &JwtVerifyOption{Issuer: "me"})
+		if err != nil || payload["uid"] != "1" || header["alg"] != string(JwtHS256) {
+			t.Fatalf("%v: %v %v %v", key.alg, header, payload, err)
+		}
+
+		if _, _, err := jwt.DecryptAndVerify(token, key.decKey, "wrong456789abcdef0123456789abcdef", nil); err != JwtErrInvalidSignature {
+			t.Fatalf("%v: wrong verify secret: %v", key.alg, err)
+		}
+		if _, _, err := jwt.DecryptAndVerify(token, key.decKey, jweTestSecret, &JwtVerifyOption{Issuer: "you"}); err != JwtErrInvalidReservedClaim {
+			t.Fatalf("%v: wrong issuer: %v", key.alg, err)
+		}
+		if _, _, err := jwt.DecryptAndVerify(token, key.wrongKey, jweTestSecret, nil); err != JweErrDecryption {
+			t.Fatalf("%v: wrong decryption key: %v", key.alg, err)
+		}
+
+		plain, _ := jwt.Encrypt([]byte("not a jwt"), key.encKey, &JweEncryptOption{KeyAlgorithm: key.alg})
+		if _, _, err := jwt.DecryptAndVerify(plain, key.decKey, jweTestSecret, nil); err != JweErrNotNested {
+			t.Fatalf("%v: non-nested content: %v", key.alg, err)
+		}
+	}
+}
+
+// RFC 7516 附录 A.3：A128KW 与 A128CBC-HS256
+// 本包只提供 A256KW，这里分别用 AESKeyUnwrap 与 A128CBC-HS256 的实现校验 CEK 及认证标签的计算方式
+func TestJweRFC7516A3(t *testing.T) {
+	token := "eyJhbGciOiJBMTI4S1ciLCJlbmMiOiJBMTI4Q0JDLUhTMjU2In0." +
+		"6KB707dM9YTIgHtLvtgWQ8mKwboJW3of9locizkDTHzBC2IlrT1oOQ." +
+		"AxY8DCtDaGlsbGljb3RoZQ." +
+		"KDlTtXchhZTGufMYmOYGS4HffxPSUrfmqCHXaI9wOGY." +
+		"U0m_YmjN04DJvceFICbCVQ"
+	kek, _ := jweEncoding.DecodeString("GawgguFyGrWKav7AX4VKUg")
+	wantCek := []byte{4, 211, 31, 197, 84, 157, 252, 254, 11, 100, 157, 250, 63, 170, 106, 206,
+		107, 124, 212, 45, 111, 107, 9, 219, 200, 177, 0, 240, 143, 156, 44, 207}
+
+	segments := bytes.Split([]byte(token), periodBytes)
+	decoded := make([][]byte, len(segments))
+	for i, segment := range segments {
+		decoded[i], _ = jweEncoding.DecodeString(string(segment))
+	}
+
+	cek, err := AESKeyUnwrap(kek, decoded[1])
+	if err != nil || !bytes.Equal(cek, wantCek) {
+		t.Fatalf("cek %v, %v", cek, err)
+	}
+
+	contentCipher := jweEncImpMap[JweA128CBCHS256]
+	plaintext, err := contentCipher.open(cek, decoded[2], decoded[3], decoded[4], segments[0])
+	if err != nil || string(plaintext) != "Live long and prosper." {
+		t.Fatalf("%q, %v", plaintext, err)
+	}
+
+	ciphertext, tag, err := contentCipher.seal(cek, decoded[2], plaintext, segments[0])
+	if err != nil || !bytes.Equal(ciphertext, decoded[3]) || !bytes.Equal(tag, decoded[4]) {
+		t.Fatalf("seal does not reproduce the RFC ciphertext and tag: %v", err)
+	}
+}
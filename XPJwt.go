@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/base64"
 	"encoding/json"
+	"strings"
 	"time"
 )
 
@@ -21,12 +22,18 @@ type JwtSignOption struct {
 }
 
 type JwtVerifyOption struct {
-	SignType         JwtAlgorithm   //签名算法
-	IngoreExpiration bool           //是否忽略到期时间
-	Audience         string         //接收方
-	Issuer           string         //签发方
-	Subject          string         //所面向的用户
-	Timeout          time.Duration  //检查到期时间时指定的时间容忍值
+	SignType            JwtAlgorithm       //签名算法
+	AllowedAlgorithms   []JwtAlgorithm     //允许的签名算法，为空时仅允许 SignType
+	IngoreExpiration    bool               //是否忽略到期时间
+	Audience            string             //接收方
+	Issuer              string             //签发方
+	Subject             string             //所面向的用户
+	Timeout             time.Duration      //检查到期时间时指定的时间容忍值
+	RevocationStore     JwtRevocationStore //吊销列表，设置后将拒绝 jti 已被吊销的 token
+	PreventReplay       bool               //是否防重放，设置后每个 jti 仅能通过一次验证，需同时设置 RevocationStore
+	TokenUse            string             //token_use claim 的期望值，为空时接受没有 token_use 或为 access 的 token，不接受 refresh token
+	MinRSAKeyBits       int                //RSA 密钥的最小位数，为 0 时默认 2048，小于 0 时不检查
+	MinHMACSecretLength int                //HMAC 密钥的最小字节数，为 0 时默认为哈希算法的输出长度 (RFC 7518 3.2)，小于 0 时不检查
 }
 
 // 根据 payload 和 secret(私钥) 生成 JSON Web Token
@@ -46,33 +53,38 @@ func (jwt *XPJwtImpl) Sign(payload JwtPayload, secret interface{}, opt *JwtSignO
 		return nil, JwtErrEmptySecretOrPrivateKey
 	}
 
-	var headerJSON, payloadJSON, signature []byte
+	if opt.SignType == "" {
+		opt.SignType = JwtHS256
+	}
+
+	var headerJSON, payloadJSON []byte
 
 	if headerJSON, err = marshalHeader(opt); err != nil {
 		return
 	}
 
-	hBase64 := []byte(base64.StdEncoding.EncodeToString(headerJSON))
-
 	if payloadJSON, err = marshalPayload(payload, opt); err != nil {
 		return
 	}
 
-	pBase64 := []byte(base64.StdEncoding.EncodeToString(payloadJSON))
-
-	if opt.SignType == "" {
-		opt.SignType = JwtHS256
-	}
+	return signSegments(headerJSON, payloadJSON, secret, opt.SignType)
+}
 
-	algImp, ok := algImpMap[opt.SignType]
+// 对 header 和 payload 进行编码并签名，返回完整的 token
+func signSegments(headerJSON, payloadJSON []byte, secret interface{}, signType JwtAlgorithm) ([]byte, error) {
+	algImp, ok := algImpMap[signType]
 
 	if !ok {
 		return nil, JwtErrInvalidAlgorithm
 	}
 
-	if signature, err = algImp.sign(bytes.Join([][]byte{hBase64, pBase64},
-		periodBytes), secret); err != nil {
-		return
+	hBase64 := []byte(base64.StdEncoding.EncodeToString(headerJSON))
+	pBase64 := []byte(base64.StdEncoding.EncodeToString(payloadJSON))
+
+	signature, err := algImp.sign(bytes.Join([][]byte{hBase64, pBase64}, periodBytes), secret)
+
+	if err != nil {
+		return nil, err
 	}
 
 	sigBase64 := []byte(base64.StdEncoding.EncodeToString(signature))
@@ -82,28 +94,68 @@ func (jwt *XPJwtImpl) Sign(payload JwtPayload, secret interface{}, opt *JwtSignO
 
 // 验证 token 并返回 header 和 payload
 // 当使用 HMAC 算法时，secret 为 string 或 []byte
-// 当使用 RSA  算法时, secret 为 rsa.PrivateKey
+// 当使用 RSA  算法时, secret 为 *rsa.PublicKey 或 *rsa.PrivateKey
+// token 头部的 alg 必须在 opt.AllowedAlgorithms 中，未设置时必须与 opt.SignType 一致
 // 如果 opt 为 nil，则默认使用 HS256 算法
 func (jwt *XPJwtImpl) Verify(token []byte, secret interface{}, opt *JwtVerifyOption) (header JwtHeader, payload JwtPayload, err error) {
-	var (
-		ok bool
-		ai algorithmImplementation
-	)
-
 	if opt == nil {
 		opt = &JwtVerifyOption{}
 		opt.IngoreExpiration = true
 	}
 
+	if header, payload, err = verifySignature(token, secret, opt); err != nil {
+		return nil, nil, err
+	}
+
+	if !payload.checkStringClaim("aud", opt.Audience) ||
+		!payload.checkStringClaim("iss", opt.Issuer) ||
+		!payload.checkStringClaim("sub", opt.Subject) {
+		return nil, nil, JwtErrInvalidReservedClaim
+	}
+
+	if !payload.checkTokenUse(opt.TokenUse) {
+		return nil, nil, JwtErrInvalidTokenUse
+	}
+
+	if !opt.IngoreExpiration {
+		if ok := payload.checkExpiration(opt.Timeout); !ok {
+			return nil, nil, JwtErrTokenExpired
+		}
+	}
+
+	expiresAt, _ := payload.expTime()
+
+	if err = checkRevocation(opt, payload.jti(), expiresAt); err != nil {
+		return nil, nil, err
+	}
+
+	return
+}
+
+// 校验 token 的签名算法、签名及头部类型，返回解码后的 header 和 payload
+func verifySignature(token []byte, secret interface{}, opt *JwtVerifyOption) (header JwtHeader, payload JwtPayload, err error) {
 	if opt.SignType == "" {
 		opt.SignType = JwtHS256
 	}
 
-	if ai, ok = algImpMap[opt.SignType]; !ok {
-		return nil, nil, JwtErrInvalidAlgorithm
+	if header, _, err = decode(token); err != nil {
+		return nil, nil, JwtErrInvalidToken
 	}
 
-	if header, payload, err = ai.verify(token, secret); err != nil {
+	alg, err := checkAlgorithm(header, opt)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err = algImpMap[alg].checkKey(secret, opt); err != nil {
+		return nil, nil, err
+	}
+
+	if header, payload, err = algImpMap[alg].verify(token, secret); err != nil {
+		if err == JwtErrInvalidKeyType {
+			return nil, nil, err
+		}
 		return nil, nil, JwtErrInvalidSignature
 	}
 
@@ -111,19 +163,41 @@ func (jwt *XPJwtImpl) Verify(token []byte, secret interface{}, opt *JwtVerifyOpt
 		return nil, nil, JwtErrInvalidHeaderType
 	}
 
-	if !payload.checkStringClaim("aud", opt.Audience) ||
-		!payload.checkStringClaim("iss", opt.Issuer) ||
-		!payload.checkStringClaim("sub", opt.Subject) {
-		return nil, nil, JwtErrInvalidReservedClaim
+	return
+}
+
+// 检查 token 头部的 alg 是否被允许，返回用于验证的算法
+// 任何情况下都不接受 "none"，防止通过篡改头部绕过签名验证
+func checkAlgorithm(header JwtHeader, opt *JwtVerifyOption) (JwtAlgorithm, error) {
+	alg, ok := header["alg"].(string)
+
+	if !ok || alg == "" {
+		return "", JwtErrInvalidAlgorithm
 	}
 
-	if !opt.IngoreExpiration {
-		if ok := payload.checkExpiration(opt.Timeout); !ok {
-			return nil, nil, JwtErrTokenExpired
+	if strings.EqualFold(alg, "none") {
+		return "", JwtErrAlgorithmNone
+	}
+
+	allowed := opt.AllowedAlgorithms
+
+	if len(allowed) == 0 {
+		allowed = []JwtAlgorithm{opt.SignType}
+	}
+
+	for _, a := range allowed {
+		if JwtAlgorithm(alg) != a {
+			continue
 		}
+
+		if _, ok := algImpMap[a]; !ok {
+			return "", JwtErrInvalidAlgorithm
+		}
+
+		return a, nil
 	}
 
-	return
+	return "", JwtErrAlgorithmNotAllowed
 }
 
 func marshalHeader(opt *JwtSignOption) ([]byte, error) {
@@ -0,0 +1,256 @@
+package XPSuperKit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+	"time"
+)
+
+// 使用自定义结构体作为 claims 的签名与验证
+//
+// 调用示例:
+//
+//	type UserClaims struct {
+//		XPSuperKit.JwtRegisteredClaims
+//		UserId int64  `json:"uid"`
+//		Role   string `json:"role"`
+//	}
+//
+//	token, err := XPSuperKit.JwtSignClaims(&UserClaims{UserId: 1, Role: "admin"}, secret, &XPSuperKit.JwtSignOption{Expiration: time.Hour})
+//	header, claims, err := XPSuperKit.JwtVerifyClaims[UserClaims](token, secret, &XPSuperKit.JwtVerifyOption{})
+//
+// 注意: 与 JwtPayload 中以签发时间为基准的相对 "exp" 不同，结构体 claims 中的
+// "exp"、"nbf"、"iat" 均为 RFC 7519 规定的绝对时间戳 (NumericDate)
+
+var (
+	// ErrTokenNotValidYet is returned when the "nbf" claim is later than now.
+	JwtErrTokenNotValidYet = errors.New("jwt: token is not valid yet")
+)
+
+// 实现此接口的结构体可作为 claims 使用，嵌入 JwtRegisteredClaims 即可自动实现
+type JwtClaims interface {
+	Registered() *JwtRegisteredClaims
+}
+
+// 约束 PT 为 *T 且实现 JwtClaims，用于在泛型函数中创建和拷贝 claims 结构体
+type JwtClaimsPointer[T any] interface {
+	*T
+	JwtClaims
+}
+
+// RFC 7519 中定义的保留 claims
+type JwtRegisteredClaims struct {
+	Issuer    string          `json:"iss,omitempty"` //签发者
+	Subject   string          `json:"sub,omitempty"` //所面向的用户
+	Audience  JwtAudience     `json:"aud,omitempty"` //接收方
+	ExpiresAt *JwtNumericDate `json:"exp,omitempty"` //过期时间
+	NotBefore *JwtNumericDate `json:"nbf,omitempty"` //生效时间
+	IssuedAt  *JwtNumericDate `json:"iat,omitempty"` //签发时间
+	ID        string          `json:"jti,omitempty"` //唯一标识
+}
+
+func (c *JwtRegisteredClaims) Registered() *JwtRegisteredClaims {
+	return c
+}
+
+// 按照 opt 校验保留 claims
+func (c *JwtRegisteredClaims) validate(opt *JwtVerifyOption) error {
+	if (opt.Audience != "" && !c.Audience.Contains(opt.Audience)) ||
+		(opt.Issuer != "" && c.Issuer != opt.Issuer) ||
+		(opt.Subject != "" && c.Subject != opt.Subject) {
+		return JwtErrInvalidReservedClaim
+	}
+
+	now := time.Now()
+
+	if c.NotBefore != nil && now.Add(opt.Timeout).Before(c.NotBefore.Time) {
+		return JwtErrTokenNotValidYet
+	}
+
+	if !opt.IngoreExpiration {
+		if c.ExpiresAt == nil {
+			return JwtErrPayloadMissingExp
+		}
+
+		if !now.Add(opt.Timeout).Before(c.ExpiresAt.Time) {
+			return JwtErrTokenExpired
+		}
+	}
+
+	return nil
+}
+
+// 以秒为单位的 JSON 数字时间戳
+type JwtNumericDate struct {
+	time.Time
+}
+
+func NewJwtNumericDate(t time.Time) *JwtNumericDate {
+	return &JwtNumericDate{t.Truncate(time.Second)}
+}
+
+func (date JwtNumericDate) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.FormatInt(date.Unix(), 10)), nil
+}
+
+func (date *JwtNumericDate) UnmarshalJSON(b []byte) error {
+	var number json.Number
+
+	if err := json.Unmarshal(b, &number); err != nil {
+		return err
+	}
+
+	f, err := number.Float64()
+
+	if err != nil {
+		return err
+	}
+
+	sec, frac := math.Modf(f)
+	date.Time = time.Unix(int64(sec), int64(frac*1e9))
+
+	return nil
+}
+
+// "aud" 可以是单个字符串或字符串数组，只有一个接收方时序列化为字符串
+type JwtAudience []string
+
+func (a JwtAudience) Contains(audience string) bool {
+	for _, v := range a {
+		if v == audience {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (a JwtAudience) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+
+	return json.Marshal([]string(a))
+}
+
+func (a *JwtAudience) UnmarshalJSON(b []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(b), []byte("[")) {
+		var list []string
+
+		if err := json.Unmarshal(b, &list); err != nil {
+			return err
+		}
+
+		*a = list
+		return nil
+	}
+
+	var single string
+
+	if err := json.Unmarshal(b, &single); err != nil {
+		return err
+	}
+
+	*a = JwtAudience{single}
+	return nil
+}
+
+// 使用自定义 claims 结构体生成 JSON Web Token
+// opt 中的 Issuer、Subject、Audience、Expiration 仅在 claims 中对应字段为空时填充，
+// claims 本身不会被修改
+// 如果 opt 为 nil，则默认使用 HS256 算法
+func JwtSignClaims[T any, PT JwtClaimsPointer[T]](claims PT, secret interface{}, opt *JwtSignOption) (token []byte, err error) {
+	if claims == nil {
+		return nil, JwtErrEmptyPayload
+	}
+
+	if secret == nil {
+		return nil, JwtErrEmptySecretOrPrivateKey
+	}
+
+	if opt == nil {
+		opt = &JwtSignOption{}
+	}
+
+	if opt.SignType == "" {
+		opt.SignType = JwtHS256
+	}
+
+	c := *claims
+	registered := PT(&c).Registered()
+	now := time.Now()
+
+	if registered.IssuedAt == nil {
+		registered.IssuedAt = NewJwtNumericDate(now)
+	}
+	if registered.ExpiresAt == nil && opt.Expiration != 0 {
+		registered.ExpiresAt = NewJwtNumericDate(now.Add(opt.Expiration))
+	}
+	if registered.Issuer == "" {
+		registered.Issuer = opt.Issuer
+	}
+	if registered.Subject == "" {
+		registered.Subject = opt.Subject
+	}
+	if len(registered.Audience) == 0 && opt.Audience != "" {
+		registered.Audience = JwtAudience{opt.Audience}
+	}
+
+	var headerJSON, payloadJSON []byte
+
+	if headerJSON, err = marshalHeader(opt); err != nil {
+		return
+	}
+
+	if payloadJSON, err = json.Marshal(PT(&c)); err != nil {
+		return
+	}
+
+	return signSegments(headerJSON, payloadJSON, secret, opt.SignType)
+}
+
+// 验证 token 并将 payload 直接解码至自定义 claims 结构体
+// 如果 opt 为 nil，则默认使用 HS256 算法并忽略到期时间
+func JwtVerifyClaims[T any, PT JwtClaimsPointer[T]](token []byte, secret interface{}, opt *JwtVerifyOption) (header JwtHeader, claims *T, err error) {
+	if opt == nil {
+		opt = &JwtVerifyOption{}
+		opt.IngoreExpiration = true
+	}
+
+	var payload JwtPayload
+
+	if header, payload, err = verifySignature(token, secret, opt); err != nil {
+		return nil, nil, err
+	}
+
+	if !payload.checkTokenUse(opt.TokenUse) {
+		return nil, nil, JwtErrInvalidTokenUse
+	}
+
+	claims = new(T)
+
+	if err = decodeSegmentInto(bytes.Split(token, periodBytes)[1], claims); err != nil {
+		return nil, nil, err
+	}
+
+	registered := PT(claims).Registered()
+
+	if err = registered.validate(opt); err != nil {
+		return nil, nil, err
+	}
+
+	var expiresAt time.Time
+
+	if registered.ExpiresAt != nil {
+		expiresAt = registered.ExpiresAt.Time
+	}
+
+	if err = checkRevocation(opt, registered.ID, expiresAt); err != nil {
+		return nil, nil, err
+	}
+
+	return
+}
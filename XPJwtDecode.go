@@ -25,15 +25,19 @@ func decode(token []byte) (header map[string]interface{}, payload map[string]int
 }
 
 func decodeSegment(segment []byte) (m map[string]interface{}, err error) {
-	s, err := base64.StdEncoding.DecodeString(string(segment))
-
-	if err != nil {
+	if err = decodeSegmentInto(segment, &m); err != nil {
 		return nil, err
 	}
 
-	if err := json.Unmarshal(s, &m); err != nil {
-		return nil, err
+	return
+}
+
+func decodeSegmentInto(segment []byte, v interface{}) error {
+	s, err := base64.StdEncoding.DecodeString(string(segment))
+
+	if err != nil {
+		return err
 	}
 
-	return
+	return json.Unmarshal(s, v)
 }
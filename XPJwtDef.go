@@ -41,6 +41,14 @@ var (
 	JwtErrInvalidToken = errors.New("jwt: invalid token")
 	// ErrInvalidAlgorithm is returned when the algorithm is not support.
 	JwtErrInvalidAlgorithm = errors.New("jwt: invalid algorithm")
+	// ErrAlgorithmNone is returned when the "alg" header is "none".
+	JwtErrAlgorithmNone = errors.New("jwt: algorithm none is not allowed")
+	// ErrAlgorithmNotAllowed is returned when the "alg" header is not in the
+	// allowed algorithms given in VerifyOption.
+	JwtErrAlgorithmNotAllowed = errors.New("jwt: algorithm not allowed")
+	// ErrWeakKey is returned when the RSA key or HMAC secret given to Verify is
+	// shorter than MinRSAKeyBits or MinHMACSecretLength in VerifyOption.
+	JwtErrWeakKey = errors.New("jwt: key is too short")
 	// ErrInvalidReservedClaim is returned when the reserved claim dose not match
 	// with the given value in VerifyOption.
 	JwtErrInvalidReservedClaim = errors.New("jwt: invalid reserved claim")
@@ -55,9 +63,16 @@ var (
 	algImpMap   = map[JwtAlgorithm]algorithmImplementation{}
 )
 
+// kJwtMinRSAKeyBits is the default minimum modulus size in bits accepted for
+// RSA keys in Verify.
+const kJwtMinRSAKeyBits = 2048
+
 type algorithmImplementation interface {
 	sign(content []byte, key interface{}) ([]byte, error)
 	verify(signing []byte, key interface{}) (JwtHeader, JwtPayload, error)
+	// checkKey returns JwtErrWeakKey when key is shorter than the minimum
+	// given in opt.
+	checkKey(key interface{}, opt *JwtVerifyOption) error
 }
 
 // Header represents a JWT header.
@@ -105,6 +120,14 @@ func (p JwtPayload) checkStringClaim(key, expected string) bool {
 	return expected == received
 }
 
+func (p JwtPayload) jti() string {
+	if v, ok := p["jti"].(string); ok {
+		return v
+	}
+
+	return ""
+}
+
 func (p JwtPayload) iat() (t time.Time, err error) {
 	var (
 		iat float64
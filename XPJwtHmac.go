@@ -18,7 +18,7 @@ type hmacAlgImp struct {
 	hashFunc func() hash.Hash
 }
 
-func (ha hmacAlgImp) sign(content []byte, secret interface{}) ([]byte, error) {
+func hmacSecret(secret interface{}) ([]byte, error) {
 	var s []byte
 
 	switch secret.(type) {
@@ -30,6 +30,21 @@ func (ha hmacAlgImp) sign(content []byte, secret interface{}) ([]byte, error) {
 		return nil, JwtErrInvalidKeyType
 	}
 
+	// PEM 格式的密钥只能用于非对称算法，防止将 RSA 公钥当作 HMAC 密钥使用
+	if bytes.HasPrefix(bytes.TrimSpace(s), []byte("-----BEGIN")) {
+		return nil, JwtErrInvalidKeyType
+	}
+
+	return s, nil
+}
+
+func (ha hmacAlgImp) sign(content []byte, secret interface{}) ([]byte, error) {
+	s, err := hmacSecret(secret)
+
+	if err != nil {
+		return nil, err
+	}
+
 	h := hmac.New(ha.hashFunc, s)
 
 	h.Write(content)
@@ -37,6 +52,27 @@ func (ha hmacAlgImp) sign(content []byte, secret interface{}) ([]byte, error) {
 	return h.Sum(nil), nil
 }
 
+// 密钥长度默认不小于哈希算法的输出长度
+func (ha hmacAlgImp) checkKey(secret interface{}, opt *JwtVerifyOption) error {
+	s, err := hmacSecret(secret)
+
+	if err != nil {
+		return err
+	}
+
+	minLength := opt.MinHMACSecretLength
+
+	if minLength == 0 {
+		minLength = ha.hashFunc().Size()
+	}
+
+	if len(s) < minLength {
+		return JwtErrWeakKey
+	}
+
+	return nil
+}
+
 func (ha hmacAlgImp) verify(token []byte, secret interface{}) (header JwtHeader, payload JwtPayload, err error) {
 	if header, payload, err = decode(token); err != nil {
 		return
@@ -0,0 +1,290 @@
+package XPSuperKit
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 未经验证的 token 解析与诊断
+//
+// 调用示例:
+//
+//	parsed, err := XPSuperKit.XPJwt().Parse(token)
+//	kid, _ := parsed.Header["kid"].(string)
+//
+//	report := XPSuperKit.XPJwt().Inspect(token, secret, opt)
+//	if !report.Valid() {
+//		fmt.Println(report)
+//	}
+
+// 未经验证的 token 各部分
+type JwtParsedToken struct {
+	Header       JwtHeader
+	Payload      JwtPayload
+	Signature    []byte
+	RawHeader    string
+	RawPayload   string
+	RawSignature string
+}
+
+// 解析 token 但不验证签名及任何 claims，仅用于在验证前读取 alg、kid 等信息或调试
+// 解析结果不可信，不能据此做任何授权判断
+func (jwt *XPJwtImpl) Parse(token []byte) (parsed *JwtParsedToken, err error) {
+	segments := bytes.Split(token, periodBytes)
+
+	if len(segments) != 3 {
+		return nil, JwtErrInvalidToken
+	}
+
+	parsed = &JwtParsedToken{
+		RawHeader:    string(segments[0]),
+		RawPayload:   string(segments[1]),
+		RawSignature: string(segments[2]),
+	}
+
+	if parsed.Header, err = decodeSegment(segments[0]); err != nil {
+		return nil, err
+	}
+
+	if parsed.Payload, err = decodeSegment(segments[1]); err != nil {
+		return nil, err
+	}
+
+	if parsed.Signature, err = base64.StdEncoding.DecodeString(parsed.RawSignature); err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+type JwtCheckStatus string
+
+const (
+	JwtCheckPassed  JwtCheckStatus = "PASS"
+	JwtCheckFailed  JwtCheckStatus = "FAIL"
+	JwtCheckWarning JwtCheckStatus = "WARN"
+	JwtCheckSkipped JwtCheckStatus = "SKIP"
+)
+
+// 单项检查结果
+type JwtInspectCheck struct {
+	Name    string
+	Status  JwtCheckStatus
+	Message string
+	Err     error //检查失败时 Verify 将返回的错误
+}
+
+// token 诊断报告
+type JwtInspectReport struct {
+	Token  *JwtParsedToken
+	Checks []JwtInspectCheck
+	Err    error //Verify 将返回的错误，为 nil 时表示验证可以通过
+}
+
+func (r *JwtInspectReport) add(name string, status JwtCheckStatus, err error, format string, args ...interface{}) {
+	r.Checks = append(r.Checks, JwtInspectCheck{
+		Name:    name,
+		Status:  status,
+		Message: fmt.Sprintf(format, args...),
+		Err:     err,
+	})
+
+	if status == JwtCheckFailed && r.Err == nil {
+		r.Err = err
+	}
+}
+
+func (r *JwtInspectReport) Valid() bool {
+	return r.Err == nil
+}
+
+// 生成可读的诊断报告
+func (r *JwtInspectReport) String() string {
+	var b strings.Builder
+
+	b.WriteString("JWT inspection report\n")
+
+	if r.Token != nil {
+		header, _ := json.Marshal(r.Token.Header)
+		payload, _ := json.Marshal(r.Token.Payload)
+		fmt.Fprintf(&b, "Header:    %s\n", header)
+		fmt.Fprintf(&b, "Payload:   %s\n", payload)
+		fmt.Fprintf(&b, "Signature: %d bytes\n", len(r.Token.Signature))
+	}
+
+	for _, check := range r.Checks {
+		fmt.Fprintf(&b, "[%s] %s: %s\n", check.Status, check.Name, check.Message)
+	}
+
+	if r.Err == nil {
+		b.WriteString("Result: Verify would succeed\n")
+	} else {
+		fmt.Fprintf(&b, "Result: Verify would fail with %q\n", r.Err.Error())
+	}
+
+	return b.String()
+}
+
+// 按照 Verify 的流程逐项检查 token 并给出诊断报告，所有检查均会执行，不会在第一个错误处停止
+// 与 Verify 不同，Inspect 不会在 opt.PreventReplay 时消费 jti，也不会修改 opt
+func (jwt *XPJwtImpl) Inspect(token []byte, secret interface{}, opt *JwtVerifyOption) *JwtInspectReport {
+	report := &JwtInspectReport{}
+
+	if opt == nil {
+		opt = &JwtVerifyOption{IngoreExpiration: true}
+	}
+
+	signType := opt.SignType
+
+	if signType == "" {
+		signType = JwtHS256
+	}
+
+	parsed, err := jwt.Parse(token)
+
+	if err != nil {
+		report.add("format", JwtCheckFailed, JwtErrInvalidToken, "token is not three base64 encoded segments: %v", err)
+		return report
+	}
+
+	report.Token = parsed
+	report.add("format", JwtCheckPassed, nil, "token has header, payload and signature segments")
+
+	alg, algErr := checkAlgorithm(parsed.Header, &JwtVerifyOption{SignType: signType, AllowedAlgorithms: opt.AllowedAlgorithms})
+
+	switch {
+	case algErr == JwtErrAlgorithmNone:
+		report.add("algorithm", JwtCheckFailed, algErr, "header alg is \"none\", unsigned tokens are never accepted")
+	case algErr == JwtErrAlgorithmNotAllowed:
+		allowed := opt.AllowedAlgorithms
+
+		if len(allowed) == 0 {
+			allowed = []JwtAlgorithm{signType}
+		}
+
+		report.add("algorithm", JwtCheckFailed, algErr, "header alg %v is not in allowed algorithms %v", parsed.Header["alg"], allowed)
+	case algErr != nil:
+		report.add("algorithm", JwtCheckFailed, algErr, "header alg %v is not supported", parsed.Header["alg"])
+	default:
+		report.add("algorithm", JwtCheckPassed, nil, "header alg is %q", alg)
+	}
+
+	if algErr == nil {
+		err := algImpMap[alg].checkKey(secret, opt)
+
+		if err == nil {
+			_, _, err = algImpMap[alg].verify(token, secret)
+		}
+
+		switch {
+		case err == JwtErrInvalidKeyType:
+			report.add("signature", JwtCheckFailed, err, "key of type %T can not be used with %s", secret, alg)
+		case err == JwtErrWeakKey:
+			report.add("signature", JwtCheckFailed, err, "key is shorter than the minimum required for %s", alg)
+		case err != nil:
+			report.add("signature", JwtCheckFailed, JwtErrInvalidSignature, "signature does not match using %s: %v", alg, err)
+		default:
+			report.add("signature", JwtCheckPassed, nil, "signature is valid using %s", alg)
+		}
+	} else {
+		report.add("signature", JwtCheckSkipped, nil, "algorithm check failed")
+	}
+
+	if parsed.Header.hasValidType() {
+		report.add("type", JwtCheckPassed, nil, "header typ is \"JWT\"")
+	} else {
+		report.add("type", JwtCheckFailed, JwtErrInvalidHeaderType, "header typ is %v, expected \"JWT\"", parsed.Header["typ"])
+	}
+
+	inspectStringClaim(report, parsed.Payload, "aud", opt.Audience)
+	inspectStringClaim(report, parsed.Payload, "iss", opt.Issuer)
+	inspectStringClaim(report, parsed.Payload, "sub", opt.Subject)
+	inspectTokenUse(report, parsed.Payload, opt.TokenUse)
+
+	expiresAt, expErr := parsed.Payload.expTime()
+
+	switch {
+	case opt.IngoreExpiration:
+		report.add("expiration", JwtCheckSkipped, nil, "expiration is ignored")
+	case expErr != nil:
+		report.add("expiration", JwtCheckFailed, JwtErrTokenExpired, "%v", expErr)
+	case !parsed.Payload.checkExpiration(opt.Timeout):
+		report.add("expiration", JwtCheckFailed, JwtErrTokenExpired, "token expired at %s (%s ago, timeout %s)",
+			expiresAt.Format(time.RFC3339), time.Since(expiresAt).Round(time.Second), opt.Timeout)
+	default:
+		report.add("expiration", JwtCheckPassed, nil, "token expires at %s (in %s)",
+			expiresAt.Format(time.RFC3339), time.Until(expiresAt).Round(time.Second))
+	}
+
+	inspectRevocation(report, parsed.Payload.jti(), opt)
+
+	return report
+}
+
+func inspectStringClaim(report *JwtInspectReport, payload JwtPayload, key, expected string) {
+	if expected == "" {
+		report.add(key, JwtCheckSkipped, nil, "no expected value")
+		return
+	}
+
+	if payload.checkStringClaim(key, expected) {
+		report.add(key, JwtCheckPassed, nil, "%q matches", expected)
+		return
+	}
+
+	if v, ok := payload[key]; ok {
+		report.add(key, JwtCheckFailed, JwtErrInvalidReservedClaim, "got %v, expected %q", v, expected)
+	} else {
+		report.add(key, JwtCheckFailed, JwtErrInvalidReservedClaim, "claim is missing, expected %q", expected)
+	}
+}
+
+func inspectTokenUse(report *JwtInspectReport, payload JwtPayload, expected string) {
+	use, ok := payload[kJwtTokenUseClaim]
+
+	switch {
+	case !payload.checkTokenUse(expected) && expected == "":
+		report.add(kJwtTokenUseClaim, JwtCheckFailed, JwtErrInvalidTokenUse, "got %v, expected no token_use or %q", use, kJwtTokenUseAccess)
+	case !payload.checkTokenUse(expected):
+		report.add(kJwtTokenUseClaim, JwtCheckFailed, JwtErrInvalidTokenUse, "got %v, expected %q", use, expected)
+	case !ok:
+		report.add(kJwtTokenUseClaim, JwtCheckPassed, nil, "claim is missing, accepted as an access token")
+	default:
+		report.add(kJwtTokenUseClaim, JwtCheckPassed, nil, "%q matches", use)
+	}
+}
+
+func inspectRevocation(report *JwtInspectReport, jti string, opt *JwtVerifyOption) {
+	if opt.RevocationStore == nil {
+		if opt.PreventReplay {
+			report.add("revocation", JwtCheckFailed, JwtErrEmptyRevocationStore, "PreventReplay requires a RevocationStore")
+		} else {
+			report.add("revocation", JwtCheckSkipped, nil, "no revocation store")
+		}
+		return
+	}
+
+	if jti == "" {
+		report.add("revocation", JwtCheckFailed, JwtErrPayloadMissingJti, "payload has no jti")
+		return
+	}
+
+	revoked, err := opt.RevocationStore.IsRevoked(jti)
+
+	switch {
+	case err != nil:
+		report.add("revocation", JwtCheckFailed, err, "revocation store error: %v", err)
+	case revoked && opt.PreventReplay:
+		report.add("revocation", JwtCheckFailed, JwtErrTokenReplayed, "jti %q has already been used or revoked", jti)
+	case revoked:
+		report.add("revocation", JwtCheckFailed, JwtErrTokenRevoked, "jti %q has been revoked", jti)
+	case opt.PreventReplay:
+		report.add("revocation", JwtCheckPassed, nil, "jti %q is unused, Verify will mark it as used", jti)
+	default:
+		report.add("revocation", JwtCheckPassed, nil, "jti %q is not revoked", jti)
+	}
+}
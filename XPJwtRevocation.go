@@ -0,0 +1,347 @@
+package XPSuperKit
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+/*********调用示例********
+store := XPSuperKit.NewJwtMemoryRevocationStore(nil, 0)
+jwt   := XPSuperKit.XPJwt()
+
+pairOpt := &XPSuperKit.JwtTokenPairOption{RevocationStore: store}
+pair, err := jwt.IssueTokenPair(XPSuperKit.JwtPayload{"uid": 1}, secret, pairOpt)
+
+// 验证 access token 时拒绝已吊销的 token
+header, payload, err := jwt.Verify(pair.AccessToken, secret, &XPSuperKit.JwtVerifyOption{RevocationStore: store})
+
+// 使用 refresh token 换取新的 token 对，旧的 refresh token 将失效
+pair, err = jwt.RefreshTokenPair(pair.RefreshToken, secret, pairOpt)
+
+// 登出，吊销 refresh token 时需设置 TokenUse: "refresh"
+err = jwt.Revoke(pair.AccessToken, secret, &XPSuperKit.JwtVerifyOption{RevocationStore: store})
+ ************************/
+
+const (
+	kJwtTokenUseClaim   = "token_use"
+	kJwtTokenUseAccess  = "access"
+	kJwtTokenUseRefresh = "refresh"
+	kJwtIdLength        = 32
+
+	kJwtDefaultAccessExpiration  = 15 * time.Minute
+	kJwtDefaultRefreshExpiration = 7 * 24 * time.Hour
+	kJwtDefaultRevocationTTL     = 7 * 24 * time.Hour
+	kJwtMinRevocationTTL         = time.Second
+	kJwtDefaultRevocationEntries = 100000
+	kJwtRevocationKeyPrefix      = "jwt:jti:"
+)
+
+var (
+	// ErrPayloadMissingJti is returned when revocation checks require "jti"
+	// but the payload does not carry one.
+	JwtErrPayloadMissingJti = errors.New("jwt: payload missing jti")
+	// ErrTokenRevoked is returned when the "jti" of the token has been revoked.
+	JwtErrTokenRevoked = errors.New("jwt: token revoked")
+	// ErrTokenReplayed is returned when a one-time token is verified again.
+	JwtErrTokenReplayed = errors.New("jwt: token replayed")
+	// ErrEmptyRevocationStore is returned when an operation requires a
+	// revocation store but none is given.
+	JwtErrEmptyRevocationStore = errors.New("jwt: empty revocation store")
+	// ErrInvalidTokenUse is returned when an access token is used as a
+	// refresh token or vice versa.
+	JwtErrInvalidTokenUse = errors.New("jwt: invalid token use")
+	// ErrRevocationStoreFull is returned when the revocation store can not
+	// record a new jti until some of its entries expire.
+	JwtErrRevocationStoreFull = errors.New("jwt: revocation store full")
+)
+
+// token 吊销列表，以 jti 作为 token 的唯一标识
+// expiresAt 为 token 的过期时间，过期后记录可被清除，为零值时由实现决定保留时长
+type JwtRevocationStore interface {
+	// 吊销 jti
+	Revoke(jti string, expiresAt time.Time) error
+	// 判断 jti 是否已被吊销
+	IsRevoked(jti string) (bool, error)
+	// 原子地将 jti 标记为已使用，若 jti 此前已被使用或吊销则返回 false
+	MarkUsed(jti string, expiresAt time.Time) (bool, error)
+}
+
+// 基于 XPMemoryCache 的吊销列表，记录在 token 过期后自动清除
+// 缓存达到容量上限且没有可清除的过期记录时拒绝新的记录并返回 JwtErrRevocationStoreFull，
+// 而不是由 XPMemoryCache 淘汰尚未过期的吊销记录，避免已吊销的 token 重新生效；cache 不应与其他用途共用
+type JwtMemoryRevocationStore struct {
+	cache      *XPMemoryCacheImpl
+	defaultTTL time.Duration
+	lock       *sync.Mutex
+}
+
+// cache 为 nil 时创建一个容量为 100000 的 XPMemoryCache
+// defaultTTL 为没有过期时间的 token 的记录保留时长，为 0 时默认保留 7 天
+func NewJwtMemoryRevocationStore(cache *XPMemoryCacheImpl, defaultTTL time.Duration) *JwtMemoryRevocationStore {
+	if cache == nil {
+		cache = NewMemoryCache(1024, kJwtDefaultRevocationEntries)
+	}
+
+	if defaultTTL <= 0 {
+		defaultTTL = kJwtDefaultRevocationTTL
+	}
+
+	return &JwtMemoryRevocationStore{
+		cache:      cache,
+		defaultTTL: defaultTTL,
+		lock:       new(sync.Mutex),
+	}
+}
+
+// 记录的保留时长，已过期的 token 也至少保留 1 秒
+func (store *JwtMemoryRevocationStore) ttl(expiresAt time.Time) time.Duration {
+	if expiresAt.IsZero() {
+		return store.defaultTTL
+	}
+
+	if ttl := time.Until(expiresAt); ttl > kJwtMinRevocationTTL {
+		return ttl
+	}
+
+	return kJwtMinRevocationTTL
+}
+
+// 调用方需持有 lock，已存在的记录只延长不缩短保留时长
+// 写入新记录前检查容量，不让 XPMemoryCache 淘汰已有的记录
+func (store *JwtMemoryRevocationStore) add(jti string, expiresAt time.Time) error {
+	key, ttl := kJwtRevocationKeyPrefix+jti, store.ttl(expiresAt)
+
+	if entry, ok := store.cache.GetCacheEntry(key); ok {
+		if time.Now().Add(ttl).Unix() <= entry.Expiration {
+			return nil
+		}
+	} else if store.cache.Count() >= store.cache.capacity {
+		store.cache.RemoveExpired()
+
+		if store.cache.Count() >= store.cache.capacity {
+			return JwtErrRevocationStoreFull
+		}
+	}
+
+	return store.cache.Set(key, true, ttl)
+}
+
+func (store *JwtMemoryRevocationStore) Revoke(jti string, expiresAt time.Time) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	return store.add(jti, expiresAt)
+}
+
+func (store *JwtMemoryRevocationStore) IsRevoked(jti string) (bool, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	return store.cache.Contains(kJwtRevocationKeyPrefix + jti), nil
+}
+
+func (store *JwtMemoryRevocationStore) MarkUsed(jti string, expiresAt time.Time) (bool, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	if store.cache.Contains(kJwtRevocationKeyPrefix + jti) {
+		return false, nil
+	}
+
+	if err := store.add(jti, expiresAt); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// 检查 token_use claim，防止 refresh token 被当作 access token 使用
+// expected 为空时接受没有 token_use 的普通 token 及 access token
+func (p JwtPayload) checkTokenUse(expected string) bool {
+	use, _ := p[kJwtTokenUseClaim].(string)
+
+	if expected == "" {
+		return use == "" || use == kJwtTokenUseAccess
+	}
+
+	return use == expected
+}
+
+// 根据 opt 检查 jti 是否已被吊销或重放
+func checkRevocation(opt *JwtVerifyOption, jti string, expiresAt time.Time) error {
+	if opt.RevocationStore == nil {
+		if opt.PreventReplay {
+			return JwtErrEmptyRevocationStore
+		}
+
+		return nil
+	}
+
+	if jti == "" {
+		return JwtErrPayloadMissingJti
+	}
+
+	if opt.PreventReplay {
+		first, err := opt.RevocationStore.MarkUsed(jti, expiresAt)
+
+		if err != nil {
+			return err
+		}
+
+		if !first {
+			return JwtErrTokenReplayed
+		}
+
+		return nil
+	}
+
+	revoked, err := opt.RevocationStore.IsRevoked(jti)
+
+	if err != nil {
+		return err
+	}
+
+	if revoked {
+		return JwtErrTokenRevoked
+	}
+
+	return nil
+}
+
+// 验证 token 后将其 jti 加入 opt.RevocationStore，用于登出等场景
+func (jwt *XPJwtImpl) Revoke(token []byte, secret interface{}, opt *JwtVerifyOption) error {
+	if opt == nil || opt.RevocationStore == nil {
+		return JwtErrEmptyRevocationStore
+	}
+
+	_, payload, err := jwt.Verify(token, secret, opt)
+
+	if err != nil {
+		return err
+	}
+
+	expiresAt, _ := payload.expTime()
+
+	return opt.RevocationStore.Revoke(payload.jti(), expiresAt)
+}
+
+type JwtTokenPair struct {
+	AccessToken  []byte
+	RefreshToken []byte
+}
+
+type JwtTokenPairOption struct {
+	SignType          JwtAlgorithm       //签名算法
+	AccessExpiration  time.Duration      //access token 过期时间，默认 15 分钟
+	RefreshExpiration time.Duration      //refresh token 过期时间，默认 7 天
+	Audience          string             //接收方
+	Issuer            string             //签发者
+	Subject           string             //所面向的用户
+	Timeout           time.Duration      //验证 refresh token 时的时间容忍值
+	RevocationStore   JwtRevocationStore //刷新时用于使旧的 refresh token 失效，调用 RefreshTokenPair 时必须设置
+}
+
+func (opt *JwtTokenPairOption) signOption(expiration time.Duration) *JwtSignOption {
+	return &JwtSignOption{
+		SignType:   opt.SignType,
+		Expiration: expiration,
+		Audience:   opt.Audience,
+		Issuer:     opt.Issuer,
+		Subject:    opt.Subject,
+	}
+}
+
+// 签发一对 access token 与 refresh token，两者均带有唯一的 jti
+// 当使用 HMAC 算法时，secret 为 string 或 []byte
+// 当使用 RSA  算法时, secret 为 rsa.PrivateKey
+func (jwt *XPJwtImpl) IssueTokenPair(payload JwtPayload, secret interface{}, opt *JwtTokenPairOption) (pair *JwtTokenPair, err error) {
+	if payload == nil {
+		return nil, JwtErrEmptyPayload
+	}
+
+	if opt == nil {
+		opt = &JwtTokenPairOption{}
+	}
+
+	accessExpiration, refreshExpiration := opt.AccessExpiration, opt.RefreshExpiration
+
+	if accessExpiration == 0 {
+		accessExpiration = kJwtDefaultAccessExpiration
+	}
+
+	if refreshExpiration == 0 {
+		refreshExpiration = kJwtDefaultRefreshExpiration
+	}
+
+	pair = &JwtTokenPair{}
+
+	if pair.AccessToken, err = jwt.Sign(tokenPairPayload(payload, kJwtTokenUseAccess), secret,
+		opt.signOption(accessExpiration)); err != nil {
+		return nil, err
+	}
+
+	if pair.RefreshToken, err = jwt.Sign(tokenPairPayload(payload, kJwtTokenUseRefresh), secret,
+		opt.signOption(refreshExpiration)); err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+// 使用 refresh token 换取新的 token 对
+// 旧的 refresh token 仅能使用一次，再次使用时返回 JwtErrTokenRevoked 或 JwtErrTokenReplayed
+func (jwt *XPJwtImpl) RefreshTokenPair(refreshToken []byte, secret interface{}, opt *JwtTokenPairOption) (*JwtTokenPair, error) {
+	if opt == nil || opt.RevocationStore == nil {
+		return nil, JwtErrEmptyRevocationStore
+	}
+
+	verifyOpt := &JwtVerifyOption{
+		SignType:        opt.SignType,
+		Audience:        opt.Audience,
+		Issuer:          opt.Issuer,
+		Subject:         opt.Subject,
+		Timeout:         opt.Timeout,
+		RevocationStore: opt.RevocationStore,
+		TokenUse:        kJwtTokenUseRefresh,
+	}
+
+	// Verify 先校验用途再消费 jti，避免 access token 被误用时占用其 jti
+	_, payload, err := jwt.Verify(refreshToken, secret, verifyOpt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt, _ := payload.expTime()
+
+	first, err := opt.RevocationStore.MarkUsed(payload.jti(), expiresAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !first {
+		return nil, JwtErrTokenReplayed
+	}
+
+	return jwt.IssueTokenPair(payload, secret, opt)
+}
+
+// 复制 payload 并替换 token 对相关的保留 claims
+func tokenPairPayload(payload JwtPayload, use string) JwtPayload {
+	result := JwtPayload{}
+
+	for k, v := range payload {
+		switch k {
+		case "iat", "exp", "jti", kJwtTokenUseClaim:
+			continue
+		}
+		result[k] = v
+	}
+
+	result["jti"] = XPString().Random(kJwtIdLength)
+	result[kJwtTokenUseClaim] = use
+
+	return result
+}
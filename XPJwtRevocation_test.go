@@ -0,0 +1,119 @@
+package XPSuperKit
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+const revocationTestSecret = "0123456789abcdef0123456789abcdef"
+
+func TestJwtTokenPairRevocation(t *testing.T) {
+	store := NewJwtMemoryRevocationStore(nil, 0)
+	jwt := XPJwt()
+	pairOpt := &JwtTokenPairOption{RevocationStore: store}
+	verifyOpt := &JwtVerifyOption{RevocationStore: store}
+
+	pair, err := jwt.IssueTokenPair(JwtPayload{"uid": 1}, revocationTestSecret, pairOpt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, payload, err := jwt.Verify(pair.AccessToken, revocationTestSecret, verifyOpt); err != nil || payload["uid"] != float64(1) {
+		t.Fatalf("verify access token: %v %v", payload, err)
+	}
+
+	// refresh token 不能当作 access token 使用，反之亦然
+	if _, _, err := jwt.Verify(pair.RefreshToken, revocationTestSecret, verifyOpt); err != JwtErrInvalidTokenUse {
+		t.Fatalf("refresh token used as access token: %v", err)
+	}
+	if _, _, err := JwtVerifyClaims[JwtRegisteredClaims](pair.RefreshToken, revocationTestSecret, verifyOpt); err != JwtErrInvalidTokenUse {
+		t.Fatalf("refresh token used as typed access token: %v", err)
+	}
+	if report := jwt.Inspect(pair.RefreshToken, revocationTestSecret, verifyOpt); report.Err != JwtErrInvalidTokenUse {
+		t.Fatalf("Inspect accepted refresh token as access token: %v\n%v", report.Err, report)
+	}
+	if report := jwt.Inspect(pair.RefreshToken, revocationTestSecret, &JwtVerifyOption{TokenUse: kJwtTokenUseRefresh}); !report.Valid() {
+		t.Fatalf("Inspect rejected refresh token: %v", report)
+	}
+	if _, err := jwt.RefreshTokenPair(pair.AccessToken, revocationTestSecret, pairOpt); err != JwtErrInvalidTokenUse {
+		t.Fatalf("access token used as refresh token: %v", err)
+	}
+
+	next, err := jwt.RefreshTokenPair(pair.RefreshToken, revocationTestSecret, pairOpt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jwt.RefreshTokenPair(pair.RefreshToken, revocationTestSecret, pairOpt); err != JwtErrTokenRevoked && err != JwtErrTokenReplayed {
+		t.Fatalf("refresh token reused: %v", err)
+	}
+
+	if err := jwt.Revoke(next.AccessToken, revocationTestSecret, verifyOpt); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := jwt.Verify(next.AccessToken, revocationTestSecret, verifyOpt); err != JwtErrTokenRevoked {
+		t.Fatalf("revoked token accepted: %v", err)
+	}
+
+	replayOpt := &JwtVerifyOption{RevocationStore: store, PreventReplay: true}
+	if _, _, err := jwt.Verify(pair.AccessToken, revocationTestSecret, replayOpt); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := jwt.Verify(pair.AccessToken, revocationTestSecret, replayOpt); err != JwtErrTokenReplayed {
+		t.Fatalf("replayed token accepted: %v", err)
+	}
+}
+
+func TestJwtMemoryRevocationStoreFull(t *testing.T) {
+	store := NewJwtMemoryRevocationStore(NewMemoryCache(3, 3), time.Hour)
+
+	for i := 0; i < 3; i++ {
+		if err := store.Revoke(fmt.Sprint("jti-", i), time.Time{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 查询不影响记录的保留，已满时拒绝新记录而不是淘汰已吊销的 jti
+	for i := 0; i < 100; i++ {
+		store.IsRevoked("jti-0")
+	}
+	if err := store.Revoke("jti-3", time.Time{}); err != JwtErrRevocationStoreFull {
+		t.Fatalf("expected JwtErrRevocationStoreFull, got %v", err)
+	}
+	if _, err := store.MarkUsed("jti-4", time.Time{}); err != JwtErrRevocationStoreFull {
+		t.Fatalf("expected JwtErrRevocationStoreFull, got %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if revoked, _ := store.IsRevoked(fmt.Sprint("jti-", i)); !revoked {
+			t.Fatalf("jti-%d was evicted", i)
+		}
+	}
+
+	// 再次吊销已存在的 jti 不受容量限制
+	if err := store.Revoke("jti-1", time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestJwtMemoryRevocationStoreExpiredToken(t *testing.T) {
+	store := NewJwtMemoryRevocationStore(NewMemoryCache(1, 1), time.Hour)
+
+	// 已过期的 token 至少保留 1 秒
+	if err := store.Revoke("expired", time.Now().Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if revoked, _ := store.IsRevoked("expired"); !revoked {
+		t.Fatal("revocation of an expired token was dropped")
+	}
+
+	// XPMemoryCache 的过期时间精确到秒
+	time.Sleep(2*kJwtMinRevocationTTL + 100*time.Millisecond)
+
+	// 过期的记录被清除后可以记录新的 jti
+	if err := store.Revoke("next", time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if revoked, _ := store.IsRevoked("expired"); revoked {
+		t.Fatal("expired entry was not purged")
+	}
+}
@@ -32,24 +32,61 @@ func (ra rsaAlgImp) sign(content []byte, privateKey interface{}) ([]byte, error)
 	return rsa.SignPKCS1v15(rand.Reader, key, ra.hash, h.Sum(nil))
 }
 
-func (ra rsaAlgImp) verify(token []byte, privateKey interface{}) (header JwtHeader, payload JwtPayload, err error) {
-	if header, payload, err = decode(token); err != nil {
-		return
+// key 为 *rsa.PublicKey 或 *rsa.PrivateKey
+func rsaPublicKey(key interface{}) (*rsa.PublicKey, error) {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return k, nil
+	case *rsa.PrivateKey:
+		return &k.PublicKey, nil
 	}
 
-	signatureReceive, err := base64.StdEncoding.DecodeString(string(bytes.Split(token, periodBytes)[2]))
+	return nil, JwtErrInvalidKeyType
+}
+
+// 密钥位数默认不小于 2048
+func (ra rsaAlgImp) checkKey(key interface{}, opt *JwtVerifyOption) error {
+	publicKey, err := rsaPublicKey(key)
 
 	if err != nil {
+		return err
+	}
+
+	minBits := opt.MinRSAKeyBits
+
+	if minBits == 0 {
+		minBits = kJwtMinRSAKeyBits
+	}
+
+	if publicKey.N.BitLen() < minBits {
+		return JwtErrWeakKey
+	}
+
+	return nil
+}
+
+func (ra rsaAlgImp) verify(token []byte, key interface{}) (header JwtHeader, payload JwtPayload, err error) {
+	publicKey, err := rsaPublicKey(key)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if header, payload, err = decode(token); err != nil {
 		return
 	}
 
-	signatureExpect, err := ra.sign(token[0:bytes.LastIndexByte(token, '.')], privateKey)
+	signatureReceive, err := base64.StdEncoding.DecodeString(string(bytes.Split(token, periodBytes)[2]))
 
 	if err != nil {
 		return
 	}
 
-	if !bytes.Equal(signatureReceive, signatureExpect) {
+	h := ra.hash.New()
+
+	h.Write(token[0:bytes.LastIndexByte(token, '.')])
+
+	if err = rsa.VerifyPKCS1v15(publicKey, ra.hash, h.Sum(nil), signatureReceive); err != nil {
 		return nil, nil, JwtErrInvalidSignature
 	}
 
@@ -0,0 +1,228 @@
+package XPSuperKit
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"testing"
+	"time"
+)
+
+const jwtTestSecret = "0123456789abcdef0123456789abcdef"
+
+func jwtTestSegment(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+func TestJwtSignVerify(t *testing.T) {
+	jwt := XPJwt()
+
+	token, err := jwt.Sign(JwtPayload{"uid": 1}, jwtTestSecret, &JwtSignOption{Expiration: time.Hour, Issuer: "me"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, payload, err := jwt.Verify(token, jwtTestSecret, &JwtVerifyOption{Issuer: "me"}); err != nil || payload["uid"] != float64(1) {
+		t.Fatalf("%v %v", payload, err)
+	}
+	if _, _, err := jwt.Verify(token, jwtTestSecret+"x", &JwtVerifyOption{}); err != JwtErrInvalidSignature {
+		t.Fatalf("wrong secret: %v", err)
+	}
+	if _, _, err := jwt.Verify(token, jwtTestSecret, &JwtVerifyOption{Issuer: "you"}); err != JwtErrInvalidReservedClaim {
+		t.Fatalf("wrong issuer: %v", err)
+	}
+
+	expired, _ := jwt.Sign(JwtPayload{"uid": 1}, jwtTestSecret, &JwtSignOption{Expiration: -time.Hour})
+	if _, _, err := jwt.Verify(expired, jwtTestSecret, &JwtVerifyOption{}); err != JwtErrTokenExpired {
+		t.Fatalf("expired token: %v", err)
+	}
+}
+
+func TestJwtAlgorithmConfusion(t *testing.T) {
+	jwt := XPJwt()
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 使用 RSA 公钥的 PEM 作为 HMAC 密钥伪造 HS256 token
+	publicDER, _ := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+	content := jwtTestSegment(`{"alg":"HS256","typ":"JWT"}`) + "." + jwtTestSegment(`{"admin":true}`)
+	mac := hmac.New(sha256.New, publicPEM)
+	mac.Write([]byte(content))
+	forged := []byte(content + "." + base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+
+	if _, _, err := jwt.Verify(forged, &privateKey.PublicKey, &JwtVerifyOption{SignType: JwtRS256}); err != JwtErrAlgorithmNotAllowed {
+		t.Fatalf("HS256 token accepted for RS256: %v", err)
+	}
+	allowBoth := &JwtVerifyOption{AllowedAlgorithms: []JwtAlgorithm{JwtRS256, JwtHS256}}
+	if _, _, err := jwt.Verify(forged, string(publicPEM), allowBoth); err != JwtErrInvalidKeyType {
+		t.Fatalf("PEM key accepted as HMAC secret: %v", err)
+	}
+
+	token, err := jwt.Sign(JwtPayload{"a": 1}, privateKey, &JwtSignOption{SignType: JwtRS256})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := jwt.Verify(token, &privateKey.PublicKey, &JwtVerifyOption{SignType: JwtRS256, IngoreExpiration: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := jwt.Verify(token, jwtTestSecret, &JwtVerifyOption{SignType: JwtRS256, IngoreExpiration: true}); err != JwtErrInvalidKeyType {
+		t.Fatalf("HMAC secret accepted for RS256: %v", err)
+	}
+
+	// "none" 在任何情况下都不被接受
+	for _, alg := range []string{"none", "None", "NONE"} {
+		none := []byte(jwtTestSegment(`{"alg":"`+alg+`","typ":"JWT"}`) + "." + jwtTestSegment(`{"a":1}`) + ".")
+		if _, _, err := jwt.Verify(none, jwtTestSecret, &JwtVerifyOption{AllowedAlgorithms: []JwtAlgorithm{JwtAlgorithm(alg)}}); err != JwtErrAlgorithmNone {
+			t.Fatalf("alg %v accepted: %v", alg, err)
+		}
+		if report := jwt.Inspect(none, jwtTestSecret, nil); report.Err != JwtErrAlgorithmNone {
+			t.Fatalf("Inspect alg %v: %v", alg, report.Err)
+		}
+	}
+}
+
+func TestJwtWeakKeys(t *testing.T) {
+	jwt := XPJwt()
+
+	// 签名时不检查密钥长度，兼容已有的调用方
+	token, err := jwt.Sign(JwtPayload{"a": 1}, "short", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := jwt.Verify(token, "short", &JwtVerifyOption{IngoreExpiration: true}); err != JwtErrWeakKey {
+		t.Fatalf("short HMAC secret: %v", err)
+	}
+	if _, _, err := jwt.Verify(token, "short", &JwtVerifyOption{IngoreExpiration: true, MinHMACSecretLength: -1}); err != nil {
+		t.Fatal(err)
+	}
+	if report := jwt.Inspect(token, "short", nil); report.Err != JwtErrWeakKey {
+		t.Fatalf("Inspect short HMAC secret: %v", report.Err)
+	}
+
+	smallKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err = jwt.Sign(JwtPayload{"a": 1}, smallKey, &JwtSignOption{SignType: JwtRS256})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := jwt.Verify(token, &smallKey.PublicKey, &JwtVerifyOption{SignType: JwtRS256, IngoreExpiration: true}); err != JwtErrWeakKey {
+		t.Fatalf("1024-bit RSA key: %v", err)
+	}
+	if _, _, err := jwt.Verify(token, &smallKey.PublicKey, &JwtVerifyOption{SignType: JwtRS256, IngoreExpiration: true, MinRSAKeyBits: 1024}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type jwtTestClaims struct {
+	JwtRegisteredClaims
+	UserId int64  `json:"uid"`
+	Role   string `json:"role"`
+}
+
+// 使用 jwtTestSecret 对任意 payload 进行 HS256 签名
+func jwtTestSignRaw(payload string) []byte {
+	content := jwtTestSegment(`{"alg":"HS256","typ":"JWT"}`) + "." + jwtTestSegment(payload)
+	mac := hmac.New(sha256.New, []byte(jwtTestSecret))
+	mac.Write([]byte(content))
+	return []byte(content + "." + base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+}
+
+func TestJwtClaimsSignVerify(t *testing.T) {
+	claims := &jwtTestClaims{UserId: 7, Role: "admin"}
+	token, err := JwtSignClaims(claims, jwtTestSecret, &JwtSignOption{Expiration: time.Hour, Audience: "app", Issuer: "me"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.ExpiresAt != nil || claims.Issuer != "" {
+		t.Fatalf("claims modified: %+v", claims)
+	}
+
+	header, verified, err := JwtVerifyClaims[jwtTestClaims](token, jwtTestSecret, &JwtVerifyOption{Audience: "app", Issuer: "me"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verified.UserId != 7 || verified.Role != "admin" || header["alg"] != string(JwtHS256) {
+		t.Fatalf("%v %+v", header, verified)
+	}
+	if verified.ExpiresAt == nil || verified.IssuedAt == nil || len(verified.Audience) != 1 {
+		t.Fatalf("registered claims not filled: %+v", verified.JwtRegisteredClaims)
+	}
+
+	if _, _, err := JwtVerifyClaims[jwtTestClaims](token, jwtTestSecret, &JwtVerifyOption{Audience: "other"}); err != JwtErrInvalidReservedClaim {
+		t.Fatalf("wrong audience: %v", err)
+	}
+	if _, _, err := JwtVerifyClaims[jwtTestClaims](token, "wrong456789abcdef0123456789abcdef", nil); err != JwtErrInvalidSignature {
+		t.Fatalf("wrong secret: %v", err)
+	}
+
+	expired, err := JwtSignClaims(&jwtTestClaims{UserId: 1}, jwtTestSecret, &JwtSignOption{Expiration: -time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := JwtVerifyClaims[jwtTestClaims](expired, jwtTestSecret, &JwtVerifyOption{}); err != JwtErrTokenExpired {
+		t.Fatalf("expired token: %v", err)
+	}
+}
+
+func TestJwtClaimsNotBefore(t *testing.T) {
+	claims := &jwtTestClaims{UserId: 1}
+	claims.NotBefore = NewJwtNumericDate(time.Now().Add(time.Minute))
+
+	token, err := JwtSignClaims(claims, jwtTestSecret, &JwtSignOption{Expiration: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := JwtVerifyClaims[jwtTestClaims](token, jwtTestSecret, &JwtVerifyOption{}); err != JwtErrTokenNotValidYet {
+		t.Fatalf("future nbf: %v", err)
+	}
+	if _, _, err := JwtVerifyClaims[jwtTestClaims](token, jwtTestSecret, &JwtVerifyOption{Timeout: 2 * time.Minute}); err != nil {
+		t.Fatalf("future nbf within timeout: %v", err)
+	}
+
+	claims.NotBefore = NewJwtNumericDate(time.Now().Add(-time.Minute))
+	if token, err = JwtSignClaims(claims, jwtTestSecret, &JwtSignOption{Expiration: time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := JwtVerifyClaims[jwtTestClaims](token, jwtTestSecret, &JwtVerifyOption{}); err != nil {
+		t.Fatalf("past nbf: %v", err)
+	}
+}
+
+func TestJwtClaimsAudience(t *testing.T) {
+	for _, aud := range []string{`"app"`, `["web","app"]`} {
+		token := jwtTestSignRaw(`{"aud":` + aud + `,"uid":3}`)
+
+		_, claims, err := JwtVerifyClaims[jwtTestClaims](token, jwtTestSecret, &JwtVerifyOption{Audience: "app", IngoreExpiration: true})
+		if err != nil || claims.UserId != 3 || !claims.Audience.Contains("app") {
+			t.Fatalf("aud %s: %+v, %v", aud, claims, err)
+		}
+		if _, _, err := JwtVerifyClaims[jwtTestClaims](token, jwtTestSecret, &JwtVerifyOption{Audience: "admin", IngoreExpiration: true}); err != JwtErrInvalidReservedClaim {
+			t.Fatalf("aud %s: wrong audience: %v", aud, err)
+		}
+	}
+
+	// 多个接收方序列化为数组，单个接收方序列化为字符串
+	claims := &jwtTestClaims{}
+	claims.Audience = JwtAudience{"web", "app"}
+	token, err := JwtSignClaims(claims, jwtTestSecret, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, payload, err := XPJwt().Verify(token, jwtTestSecret, nil)
+	if list, ok := payload["aud"].([]interface{}); err != nil || !ok || len(list) != 2 {
+		t.Fatalf("aud array: %v, %v", payload["aud"], err)
+	}
+
+	token, _ = JwtSignClaims(&jwtTestClaims{}, jwtTestSecret, &JwtSignOption{Audience: "app"})
+	if _, payload, _ = XPJwt().Verify(token, jwtTestSecret, nil); payload["aud"] != "app" {
+		t.Fatalf("aud string: %v", payload["aud"])
+	}
+}
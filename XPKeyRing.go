@@ -0,0 +1,519 @@
+package XPSuperKit
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sort"
+	"sync"
+	"time"
+)
+
+/*********调用示例********
+ring, err := XPSuperKit.LoadKeyRingFile("./conf/keys/keyring.json", kek)
+
+// 使用当前活动的主密钥包装随机生成的数据密钥，数据密钥加密数据
+envelope, err := ring.Encrypt(data, nil)
+
+// 根据信封中的主密钥 ID 查找主密钥并解密
+data, err = ring.Decrypt(envelope, nil)
+
+// 轮换主密钥，旧主密钥仍可用于解密
+key, err := ring.Rotate()
+err = ring.SaveFile("./conf/keys/keyring.json", kek)
+
+// 使用新的主密钥重新包装数据密钥，数据本身无需重新加密
+envelope, err = ring.ReWrap(envelope)
+ ************************/
+
+const (
+	KEY_WRAP_AES_KW       = "AES-KW"       //AES 密钥包装 (RFC 3394)，主密钥为 16、24 或 32 字节的 []byte
+	KEY_WRAP_RSA_OAEP_256 = "RSA-OAEP-256" //RSA-OAEP (SHA-256)，主密钥为 *rsa.PrivateKey，仅用于加密时可为 *rsa.PublicKey
+
+	kEnvelopeVersion1 = 1
+	kDataKeySize      = 32
+)
+
+var (
+	EncryptErrKeyNotFound    = errors.New("encrypt: master key not found")
+	EncryptErrNoActiveKey    = errors.New("encrypt: no active master key")
+	EncryptErrKeyRetired     = errors.New("encrypt: master key is retired")
+	EncryptErrInvalidKeyType = errors.New("encrypt: invalid master key type")
+)
+
+// 主密钥
+type MasterKey struct {
+	ID        string      //密钥 ID，写入信封用于解密时查找主密钥
+	Algorithm string      //包装算法，KEY_WRAP_AES_KW 或 KEY_WRAP_RSA_OAEP_256
+	Key       interface{} //密钥内容
+	Retired   bool        //已停用的主密钥只能用于解密
+	CreatedAt time.Time   //创建时间
+}
+
+func (key *MasterKey) wrap(dataKey []byte) ([]byte, error) {
+	switch key.Algorithm {
+	case KEY_WRAP_AES_KW:
+		kek, ok := key.Key.([]byte)
+		if !ok {
+			return nil, EncryptErrInvalidKeyType
+		}
+		return AESKeyWrap(kek, dataKey)
+	case KEY_WRAP_RSA_OAEP_256:
+		var pub *rsa.PublicKey
+		switch k := key.Key.(type) {
+		case *rsa.PublicKey:
+			pub = k
+		case *rsa.PrivateKey:
+			pub = &k.PublicKey
+		default:
+			return nil, EncryptErrInvalidKeyType
+		}
+		return rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, dataKey, nil)
+	default:
+		return nil, EncryptErrUnsupportedAlgorithm
+	}
+}
+
+func (key *MasterKey) unwrap(wrapped []byte) ([]byte, error) {
+	switch key.Algorithm {
+	case KEY_WRAP_AES_KW:
+		kek, ok := key.Key.([]byte)
+		if !ok {
+			return nil, EncryptErrInvalidKeyType
+		}
+		return AESKeyUnwrap(kek, wrapped)
+	case KEY_WRAP_RSA_OAEP_256:
+		priv, ok := key.Key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, EncryptErrInvalidKeyType
+		}
+		return rsa.DecryptOAEP(sha256.New(), rand.Reader, priv, wrapped, nil)
+	default:
+		return nil, EncryptErrUnsupportedAlgorithm
+	}
+}
+
+// 主密钥环，使用活动主密钥包装每条记录的数据密钥，并按信封中的密钥 ID 解密
+type XPKeyRingImpl struct {
+	keys   map[string]*MasterKey
+	active string
+	lock   *sync.RWMutex
+}
+
+func NewKeyRing() *XPKeyRingImpl {
+	return &XPKeyRingImpl{
+		keys: make(map[string]*MasterKey),
+		lock: new(sync.RWMutex),
+	}
+}
+
+//添加主密钥，密钥环中没有活动主密钥时新密钥将成为活动主密钥
+func (ring *XPKeyRingImpl) Add(key *MasterKey) error {
+	if key == nil || key.ID == "" || len(key.ID) > 255 {
+		return ErrorN("master key id must be 1 to 255 bytes")
+	}
+
+	switch key.Algorithm {
+	case KEY_WRAP_AES_KW, KEY_WRAP_RSA_OAEP_256:
+	default:
+		return EncryptErrUnsupportedAlgorithm
+	}
+
+	ring.lock.Lock()
+	defer ring.lock.Unlock()
+
+	if _, ok := ring.keys[key.ID]; ok {
+		return ErrorF("master key %s already exists", key.ID)
+	}
+
+	if key.CreatedAt.IsZero() {
+		key.CreatedAt = time.Now()
+	}
+
+	ring.keys[key.ID] = key
+
+	if ring.active == "" && !key.Retired {
+		ring.active = key.ID
+	}
+
+	return nil
+}
+
+//设置活动主密钥，之后加密的数据都将使用该主密钥
+func (ring *XPKeyRingImpl) SetActive(id string) error {
+	ring.lock.Lock()
+	defer ring.lock.Unlock()
+
+	key, ok := ring.keys[id]
+	if !ok {
+		return EncryptErrKeyNotFound
+	}
+	if key.Retired {
+		return EncryptErrKeyRetired
+	}
+
+	ring.active = id
+	return nil
+}
+
+//停用主密钥，停用后只能用于解密，活动主密钥不能被停用
+func (ring *XPKeyRingImpl) Retire(id string) error {
+	ring.lock.Lock()
+	defer ring.lock.Unlock()
+
+	key, ok := ring.keys[id]
+	if !ok {
+		return EncryptErrKeyNotFound
+	}
+	if ring.active == id {
+		return ErrorF("master key %s is active and can not be retired", id)
+	}
+
+	key.Retired = true
+	return nil
+}
+
+//移除主密钥，使用该主密钥包装的数据将无法再解密
+func (ring *XPKeyRingImpl) Remove(id string) {
+	ring.lock.Lock()
+	defer ring.lock.Unlock()
+
+	delete(ring.keys, id)
+	if ring.active == id {
+		ring.active = ""
+	}
+}
+
+func (ring *XPKeyRingImpl) Get(id string) (*MasterKey, bool) {
+	ring.lock.RLock()
+	defer ring.lock.RUnlock()
+
+	key, ok := ring.keys[id]
+	return key, ok
+}
+
+func (ring *XPKeyRingImpl) Active() (*MasterKey, bool) {
+	ring.lock.RLock()
+	defer ring.lock.RUnlock()
+
+	key, ok := ring.keys[ring.active]
+	return key, ok
+}
+
+//按创建时间排序的全部主密钥
+func (ring *XPKeyRingImpl) Keys() []*MasterKey {
+	ring.lock.RLock()
+	defer ring.lock.RUnlock()
+
+	keys := make([]*MasterKey, 0, len(ring.keys))
+	for _, key := range ring.keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	return keys
+}
+
+//生成新的 AES-256 主密钥并设为活动主密钥，原活动主密钥被停用但仍可用于解密
+func (ring *XPKeyRingImpl) Rotate() (*MasterKey, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+
+	key := &MasterKey{
+		ID:        XPString().Random(16),
+		Algorithm: KEY_WRAP_AES_KW,
+		Key:       secret,
+		CreatedAt: time.Now(),
+	}
+
+	ring.lock.Lock()
+	defer ring.lock.Unlock()
+
+	if previous, ok := ring.keys[ring.active]; ok {
+		previous.Retired = true
+	}
+	ring.keys[key.ID] = key
+	ring.active = key.ID
+
+	return key, nil
+}
+
+/**
+ * 信封加密
+ * 生成随机数据密钥，使用 AES-GCM 加密数据，再用活动主密钥包装数据密钥
+ * 信封格式为: 版本(1 字节) | 密钥 ID 长度(1 字节) | 密钥 ID | 包装后的数据密钥长度(2 字节) | 包装后的数据密钥 | AEAD 密文
+ * @param plaintext []byte 明文
+ * @param additionalData []byte 附加认证数据，解密时必须提供相同的值，可为 nil
+ */
+func (ring *XPKeyRingImpl) Encrypt(plaintext, additionalData []byte) ([]byte, error) {
+	key, ok := ring.Active()
+	if !ok {
+		return nil, EncryptErrNoActiveKey
+	}
+
+	dataKey := make([]byte, kDataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+
+	header, err := envelopeHeader(key, dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	crypt, err := AEADEncrypt(AEAD_AES_GCM, dataKey, plaintext, envelopeAdditionalData(additionalData))
+	if err != nil {
+		return nil, err
+	}
+
+	return bytesCombine(header, crypt), nil
+}
+
+//信封解密，根据信封中的密钥 ID 查找主密钥，已停用的主密钥同样可以解密
+func (ring *XPKeyRingImpl) Decrypt(envelope, additionalData []byte) ([]byte, error) {
+	key, wrapped, header, err := ring.parseEnvelope(envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	dataKey, err := key.unwrap(wrapped)
+	if err != nil {
+		return nil, EncryptErrInvalidCiphertext
+	}
+
+	return AEADDecrypt(dataKey, envelope[len(header):], envelopeAdditionalData(additionalData))
+}
+
+//使用活动主密钥重新包装信封中的数据密钥，数据部分保持不变，无需重新加密
+//用于在主密钥轮换后淘汰旧主密钥
+func (ring *XPKeyRingImpl) ReWrap(envelope []byte) ([]byte, error) {
+	key, wrapped, header, err := ring.parseEnvelope(envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	active, ok := ring.Active()
+	if !ok {
+		return nil, EncryptErrNoActiveKey
+	}
+	if active.ID == key.ID {
+		return envelope, nil
+	}
+
+	dataKey, err := key.unwrap(wrapped)
+	if err != nil {
+		return nil, EncryptErrInvalidCiphertext
+	}
+
+	newHeader, err := envelopeHeader(active, dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytesCombine(newHeader, envelope[len(header):]), nil
+}
+
+// 数据密钥的完整性由主密钥的包装算法保证，数据部分只认证版本号和调用方的附加数据，
+// 因此重新包装数据密钥时无需重新加密数据
+func envelopeAdditionalData(additionalData []byte) []byte {
+	return bytesCombine([]byte{kEnvelopeVersion1}, additionalData)
+}
+
+//返回信封所使用的主密钥 ID
+func EnvelopeKeyID(envelope []byte) (string, error) {
+	if len(envelope) < 2 || envelope[0] != kEnvelopeVersion1 {
+		return "", EncryptErrInvalidCiphertext
+	}
+
+	idLength := int(envelope[1])
+	if len(envelope) < 2+idLength {
+		return "", EncryptErrInvalidCiphertext
+	}
+
+	return string(envelope[2 : 2+idLength]), nil
+}
+
+func envelopeHeader(key *MasterKey, dataKey []byte) ([]byte, error) {
+	wrapped, err := key.wrap(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 0, 4+len(key.ID)+len(wrapped))
+	header = append(header, kEnvelopeVersion1, byte(len(key.ID)))
+	header = append(header, key.ID...)
+	header = append(header, byte(len(wrapped)>>8), byte(len(wrapped)))
+	return append(header, wrapped...), nil
+}
+
+func (ring *XPKeyRingImpl) parseEnvelope(envelope []byte) (key *MasterKey, wrapped, header []byte, err error) {
+	id, err := EnvelopeKeyID(envelope)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	offset := 2 + len(id)
+	if len(envelope) < offset+2 {
+		return nil, nil, nil, EncryptErrInvalidCiphertext
+	}
+
+	wrappedLength := int(binary.BigEndian.Uint16(envelope[offset:]))
+	offset += 2
+	if len(envelope) < offset+wrappedLength {
+		return nil, nil, nil, EncryptErrInvalidCiphertext
+	}
+
+	key, ok := ring.Get(id)
+	if !ok {
+		return nil, nil, nil, EncryptErrKeyNotFound
+	}
+
+	return key, envelope[offset : offset+wrappedLength], envelope[:offset+wrappedLength], nil
+}
+
+/*********************** 本地文件密钥库 ********************/
+var kKeyRingFileAdditionalData = []byte("XPKeyRing")
+
+type keyRingFile struct {
+	Active string            `json:"active"`
+	Keys   []keyRingFileItem `json:"keys"`
+}
+
+type keyRingFileItem struct {
+	ID        string    `json:"id"`
+	Algorithm string    `json:"algorithm"`
+	Key       string    `json:"key"`
+	Retired   bool      `json:"retired"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+/**
+ * 从本地文件加载密钥环
+ * @param filePath string 密钥库文件路径
+ * @param kek []byte 保护密钥库文件的密钥，为 nil 时文件以明文 JSON 保存
+ */
+func LoadKeyRingFile(filePath string, kek []byte) (*XPKeyRingImpl, error) {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if kek != nil {
+		if data, err = AEADDecrypt(kek, data, kKeyRingFileAdditionalData); err != nil {
+			return nil, err
+		}
+	}
+
+	var file keyRingFile
+	if err = json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	ring := NewKeyRing()
+	for _, item := range file.Keys {
+		key := &MasterKey{
+			ID:        item.ID,
+			Algorithm: item.Algorithm,
+			Retired:   item.Retired,
+			CreatedAt: item.CreatedAt,
+		}
+
+		switch item.Algorithm {
+		case KEY_WRAP_AES_KW:
+			if key.Key, err = base64.StdEncoding.DecodeString(item.Key); err != nil {
+				return nil, err
+			}
+		case KEY_WRAP_RSA_OAEP_256:
+			block, _ := pem.Decode([]byte(item.Key))
+			if block == nil {
+				return nil, ErrorF("master key %s: invalid PEM data", item.ID)
+			}
+			if block.Type == "PUBLIC KEY" {
+				key.Key, err = x509.ParsePKIXPublicKey(block.Bytes)
+			} else {
+				key.Key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if err = ring.Add(key); err != nil {
+			return nil, err
+		}
+	}
+
+	if file.Active != "" {
+		if err = ring.SetActive(file.Active); err != nil {
+			return nil, err
+		}
+	}
+
+	return ring, nil
+}
+
+//将密钥环保存至本地文件，文件权限为 0600，先写入同一目录下的临时文件再替换
+func (ring *XPKeyRingImpl) SaveFile(filePath string, kek []byte) error {
+	file := keyRingFile{}
+	if active, ok := ring.Active(); ok {
+		file.Active = active.ID
+	}
+
+	for _, key := range ring.Keys() {
+		item := keyRingFileItem{
+			ID:        key.ID,
+			Algorithm: key.Algorithm,
+			Retired:   key.Retired,
+			CreatedAt: key.CreatedAt,
+		}
+
+		switch k := key.Key.(type) {
+		case []byte:
+			item.Key = base64.StdEncoding.EncodeToString(k)
+		case *rsa.PrivateKey:
+			der, err := x509.MarshalPKCS8PrivateKey(k)
+			if err != nil {
+				return err
+			}
+			item.Key = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+		case *rsa.PublicKey:
+			der, err := x509.MarshalPKIXPublicKey(k)
+			if err != nil {
+				return err
+			}
+			item.Key = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+		default:
+			return EncryptErrInvalidKeyType
+		}
+
+		file.Keys = append(file.Keys, item)
+	}
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if kek != nil {
+		if data, err = AEADEncrypt(AEAD_AES_GCM, kek, data, kKeyRingFileAdditionalData); err != nil {
+			return err
+		}
+	}
+
+	return writeFileAtomic(filePath, 0600, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+/*********************** 本地文件密钥库 ********************/
@@ -0,0 +1,163 @@
+package XPSuperKit
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func keyRingTestRing(t *testing.T) *XPKeyRingImpl {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ring := NewKeyRing()
+	if err := ring.Add(&MasterKey{ID: "rsa", Algorithm: KEY_WRAP_RSA_OAEP_256, Key: priv}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ring.Add(&MasterKey{ID: "aes", Algorithm: KEY_WRAP_AES_KW, Key: bytes.Repeat([]byte{1}, 32)}); err != nil {
+		t.Fatal(err)
+	}
+	return ring
+}
+
+func TestKeyRingEncryptDecrypt(t *testing.T) {
+	ring := keyRingTestRing(t)
+	ad := []byte("ad")
+
+	for _, id := range []string{"rsa", "aes"} {
+		if err := ring.SetActive(id); err != nil {
+			t.Fatal(err)
+		}
+
+		envelope, err := ring.Encrypt([]byte("hello"), ad)
+		if err != nil {
+			t.Fatal(id, err)
+		}
+		if keyID, err := EnvelopeKeyID(envelope); err != nil || keyID != id {
+			t.Fatalf("%v: key id %q, %v", id, keyID, err)
+		}
+		if plaintext, err := ring.Decrypt(envelope, ad); err != nil || string(plaintext) != "hello" {
+			t.Fatalf("%v: %q, %v", id, plaintext, err)
+		}
+
+		if _, err := ring.Decrypt(envelope, nil); err == nil {
+			t.Fatalf("%v: wrong additional data accepted", id)
+		}
+		tampered := append([]byte(nil), envelope...)
+		tampered[len(tampered)-1] ^= 0x01
+		if _, err := ring.Decrypt(tampered, ad); err == nil {
+			t.Fatalf("%v: tampered envelope accepted", id)
+		}
+		if _, err := ring.Decrypt(envelope[:len(envelope)/2], ad); err == nil {
+			t.Fatalf("%v: truncated envelope accepted", id)
+		}
+	}
+
+	envelope, _ := ring.Encrypt([]byte("hello"), nil)
+	ring.Remove("aes")
+	if _, err := ring.Decrypt(envelope, nil); err != EncryptErrKeyNotFound {
+		t.Fatalf("removed key: %v", err)
+	}
+	if _, err := ring.Encrypt([]byte("hello"), nil); err != EncryptErrNoActiveKey {
+		t.Fatalf("no active key: %v", err)
+	}
+}
+
+func TestKeyRingRotateReWrap(t *testing.T) {
+	ring := keyRingTestRing(t)
+
+	old, err := ring.Encrypt([]byte("one"), []byte("ad"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := ring.Rotate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if previous, _ := ring.Get("rsa"); !previous.Retired {
+		t.Fatal("previous active key not retired")
+	}
+	if active, _ := ring.Active(); active.ID != key.ID {
+		t.Fatalf("active key %v, want %v", active.ID, key.ID)
+	}
+	if err := ring.SetActive("rsa"); err != EncryptErrKeyRetired {
+		t.Fatalf("activate retired key: %v", err)
+	}
+
+	// 停用的主密钥仍可解密，重新包装后使用新的主密钥
+	if plaintext, err := ring.Decrypt(old, []byte("ad")); err != nil || string(plaintext) != "one" {
+		t.Fatalf("%q, %v", plaintext, err)
+	}
+
+	rewrapped, err := ring.ReWrap(old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keyID, _ := EnvelopeKeyID(rewrapped); keyID != key.ID {
+		t.Fatalf("rewrapped key id %q", keyID)
+	}
+	ring.Remove("rsa")
+	if plaintext, err := ring.Decrypt(rewrapped, []byte("ad")); err != nil || string(plaintext) != "one" {
+		t.Fatalf("%q, %v", plaintext, err)
+	}
+
+	if same, err := ring.ReWrap(rewrapped); err != nil || !bytes.Equal(same, rewrapped) {
+		t.Fatalf("rewrap with active key: %v", err)
+	}
+}
+
+func TestKeyRingSaveLoadFile(t *testing.T) {
+	ring := keyRingTestRing(t)
+	first, _ := ring.Encrypt([]byte("one"), nil)
+	key, _ := ring.Rotate()
+	second, _ := ring.Encrypt([]byte("two"), nil)
+
+	dir := t.TempDir()
+	kek := bytes.Repeat([]byte{9}, 32)
+
+	for _, k := range [][]byte{kek, nil} {
+		path := filepath.Join(dir, "keyring.json")
+		if err := ring.SaveFile(path, k); err != nil {
+			t.Fatal(err)
+		}
+		if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+			t.Fatalf("file mode %v, %v", info.Mode(), err)
+		}
+
+		loaded, err := LoadKeyRingFile(path, k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if active, _ := loaded.Active(); active.ID != key.ID {
+			t.Fatalf("active key %v, want %v", active.ID, key.ID)
+		}
+		if previous, _ := loaded.Get("rsa"); !previous.Retired {
+			t.Fatal("retired flag not saved")
+		}
+		if plaintext, err := loaded.Decrypt(first, nil); err != nil || string(plaintext) != "one" {
+			t.Fatalf("%q, %v", plaintext, err)
+		}
+		if plaintext, err := loaded.Decrypt(second, nil); err != nil || string(plaintext) != "two" {
+			t.Fatalf("%q, %v", plaintext, err)
+		}
+	}
+
+	path := filepath.Join(dir, "keyring.json")
+	if err := ring.SaveFile(path, kek); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadKeyRingFile(path, bytes.Repeat([]byte{8}, 32)); err == nil {
+		t.Fatal("wrong kek accepted")
+	}
+
+	// 保存时不留下临时文件
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Fatalf("%d files in directory", len(entries))
+	}
+}
@@ -194,6 +194,29 @@ func (memoryCache *XPMemoryCacheImpl) Remove(key string) (interface{},bool) {
 	return nil, false
 }
 
+//清除全部已过期的缓存项，返回清除的数量
+func (memoryCache *XPMemoryCacheImpl) RemoveExpired() int {
+	if memoryCache.bucket == nil {
+		return 0
+	}
+
+	memoryCache.rwMutex.Lock()
+	defer memoryCache.rwMutex.Unlock()
+
+	count := 0
+	now   := time.Now().Unix()
+	for el := memoryCache.bucket.lruList.Front(); el != nil; {
+		next := el.Next()
+		if item, ok := el.Value.(*CacheEntry); ok && now > item.Expiration {
+			memoryCache.pushExpiredCacheEntry(el, true)
+			count++
+		}
+		el = next
+	}
+
+	return count
+}
+
 func (memoryCache *XPMemoryCacheImpl) Clear() {
 	memoryCache.rwMutex.Lock()
 	defer memoryCache.rwMutex.Unlock()
@@ -21,13 +21,22 @@ var (
 // and dst must be a pointer to struct.
 // It won't merge unexported (private) fields and will do recursively any exported field.
 func Merge(dst, src interface{}) error {
-	return merge(dst, src, false)
+	return merge(dst, src, &MergeConfig{})
 }
 
 // MergeWithOverwrite will do the same as Merge except that non-empty dst attributes will be overriden by
 // non-empty src attribute values.
 func MergeWithOverwrite(dst, src interface{}) error {
-	return merge(dst, src, true)
+	return merge(dst, src, &MergeConfig{Overwrite: true})
+}
+
+// MergeWithConfig will do the same as Merge using the slice and map strategies of config.
+// A nil config behaves like Merge.
+func MergeWithConfig(dst, src interface{}, config *MergeConfig) error {
+	if config == nil {
+		config = &MergeConfig{}
+	}
+	return merge(dst, src, config)
 }
 
 // Map sets fields' values in dst from src.
@@ -51,6 +60,33 @@ func MapWithOverwrite(dst, src interface{}) error {
 	return _map(dst, src, true)
 }
 
+// How slices are merged when both dst and src are non-empty.
+type MergeSliceStrategy int
+
+const (
+	MERGE_SLICE_REPLACE       MergeSliceStrategy = iota // src replaces dst, only when overwriting or dst is empty
+	MERGE_SLICE_APPEND                                  // src elements are appended to dst
+	MERGE_SLICE_APPEND_UNIQUE                           // src elements not already in dst are appended
+	MERGE_SLICE_BY_INDEX                                // elements at the same index are merged, extra src elements are appended
+)
+
+// How maps are merged when both dst and src are non-empty.
+type MergeMapStrategy int
+
+const (
+	MERGE_MAP_DEEP    MergeMapStrategy = iota // keys are merged recursively
+	MERGE_MAP_REPLACE                         // src replaces dst as a whole, only when overwriting or dst is empty
+)
+
+type MergeConfig struct {
+	Overwrite bool
+	Slice     MergeSliceStrategy
+	Map       MergeMapStrategy
+	// When overwriting, non-nil src pointers to structs are merged field by field into non-nil dst
+	// pointers instead of replacing them.
+	DeepPointers bool
+}
+
 // During deepMerge, must keep track of checks that are
 // in progress.  The comparison algorithm assumes that all
 // checks in progress are true when it reencounters them.
@@ -80,6 +116,18 @@ func isEmptyValue(v reflect.Value) bool {
 	return false
 }
 
+func hasExportedField(dst reflect.Value) (exported bool) {
+	for i, n := 0, dst.NumField(); i < n; i++ {
+		field := dst.Type().Field(i)
+		if field.Anonymous && dst.Field(i).Kind() == reflect.Struct {
+			exported = exported || hasExportedField(dst.Field(i))
+		} else {
+			exported = exported || len(field.PkgPath) == 0
+		}
+	}
+	return
+}
+
 func resolveValues(dst, src interface{}) (vDst, vSrc reflect.Value, err error) {
 	if dst == nil || src == nil {
 		err = kMergeErrNilArguments
@@ -118,7 +166,7 @@ func deeper(dst, src reflect.Value, visited map[uintptr]*visit, depth int) (err
 	return // TODO refactor
 }
 
-func deepMerge(dst, src reflect.Value, visited map[uintptr]*visit, depth int, overwrite bool) (err error) {
+func deepMerge(dst, src reflect.Value, visited map[uintptr]*visit, depth int, config *MergeConfig) (err error) {
 	if !src.IsValid() {
 		return
 	}
@@ -137,67 +185,170 @@ func deepMerge(dst, src reflect.Value, visited map[uintptr]*visit, depth int, ov
 	}
 	switch dst.Kind() {
 	case reflect.Struct:
+		// structs such as time.Time can't be merged field by field
+		if !hasExportedField(dst) {
+			if dst.CanSet() && !isZeroValue(src) && (config.Overwrite || isZeroValue(dst)) {
+				dst.Set(src)
+			}
+			break
+		}
 		for i, n := 0, dst.NumField(); i < n; i++ {
-			if err = deepMerge(dst.Field(i), src.Field(i), visited, depth+1, overwrite); err != nil {
+			if err = deepMerge(dst.Field(i), src.Field(i), visited, depth+1, config); err != nil {
 				return
 			}
 		}
 	case reflect.Map:
+		if isEmptyValue(src) {
+			break
+		}
+		if config.Map == MERGE_MAP_REPLACE {
+			if dst.CanSet() && (config.Overwrite || isEmptyValue(dst)) {
+				dst.Set(src)
+			}
+			break
+		}
+		if dst.IsNil() {
+			if !dst.CanSet() {
+				break
+			}
+			dst.Set(reflect.MakeMap(dst.Type()))
+		}
 		for _, key := range src.MapKeys() {
 			srcElement := src.MapIndex(key)
-			if !srcElement.IsValid() {
+			if !srcElement.IsValid() || isEmptyValue(srcElement) {
 				continue
 			}
 			dstElement := dst.MapIndex(key)
-			switch srcElement.Kind() {
-			case reflect.Chan, reflect.Func, reflect.Map, reflect.Ptr, reflect.Interface, reflect.Slice:
-				if srcElement.IsNil() {
-					continue
-				}
-				fallthrough
-			default:
-				if !srcElement.CanInterface() {
-					continue
-				}
-				switch reflect.TypeOf(srcElement.Interface()).Kind() {
-				case reflect.Struct:
-					fallthrough
-				case reflect.Ptr:
-					fallthrough
-				case reflect.Map:
-					if err = deepMerge(dstElement, srcElement, visited, depth+1, overwrite); err != nil {
-						return
-					}
-				}
-			}
-			if !isEmptyValue(srcElement) && (overwrite || (!dstElement.IsValid() || isEmptyValue(dst))) {
-				if dst.IsNil() {
-					dst.Set(reflect.MakeMap(dst.Type()))
-				}
+			if !dstElement.IsValid() {
 				dst.SetMapIndex(key, srcElement)
+				continue
 			}
+			var merged reflect.Value
+			if merged, err = mergeMapElement(dstElement, srcElement, visited, depth+1, config); err != nil {
+				return
+			}
+			dst.SetMapIndex(key, merged)
+		}
+	case reflect.Slice:
+		if !dst.CanSet() || isEmptyValue(src) {
+			break
+		}
+		var merged reflect.Value
+		if merged, err = mergeSlices(dst, src, visited, depth+1, config); err != nil {
+			return
 		}
+		dst.Set(merged)
 	case reflect.Ptr:
+		// with DeepPointers, pointers to structs are merged field by field, other pointers are
+		// replaced as a whole so that an explicit zero value such as *bool false still overrides dst
+		if config.DeepPointers && !src.IsNil() && !dst.IsNil() && dst.Elem().Kind() == reflect.Struct {
+			err = deepMerge(dst.Elem(), src.Elem(), visited, depth+1, config)
+			break
+		}
 		fallthrough
 	case reflect.Interface:
 		if src.IsNil() {
 			break
-		} else if dst.IsNil() || overwrite {
-			if dst.CanSet() && (overwrite || isEmptyValue(dst)) {
+		} else if dst.IsNil() || config.Overwrite {
+			if dst.CanSet() && (config.Overwrite || isEmptyValue(dst)) {
 				dst.Set(src)
 			}
-		} else if err = deepMerge(dst.Elem(), src.Elem(), visited, depth+1, overwrite); err != nil {
+		} else if err = deepMerge(dst.Elem(), src.Elem(), visited, depth+1, config); err != nil {
 			return
 		}
 	default:
-		if dst.CanSet() && !isEmptyValue(src) && (overwrite || isEmptyValue(dst)) {
+		if dst.CanSet() && !isEmptyValue(src) && (config.Overwrite || isEmptyValue(dst)) {
 			dst.Set(src)
 		}
 	}
 	return
 }
 
-func merge(dst, src interface{}, overwrite bool) error {
+// Merges two values of the same map key. Map values are not addressable, so the
+// merged value is returned and set back into the map by the caller.
+func mergeMapElement(dstElement, srcElement reflect.Value, visited map[uintptr]*visit, depth int, config *MergeConfig) (reflect.Value, error) {
+	d, s := dstElement, srcElement
+	if d.Kind() == reflect.Interface {
+		d = d.Elem()
+	}
+	if s.Kind() == reflect.Interface {
+		s = s.Elem()
+	}
+
+	if !d.IsValid() || !s.IsValid() || d.Type() != s.Type() {
+		if config.Overwrite {
+			return srcElement, nil
+		}
+		return dstElement, nil
+	}
+
+	switch d.Kind() {
+	case reflect.Map:
+		if config.Map == MERGE_MAP_DEEP && !d.IsNil() {
+			// maps are references, d is merged in place
+			return dstElement, deepMerge(d, s, visited, depth, config)
+		}
+	case reflect.Slice:
+		return mergeSlices(d, s, visited, depth, config)
+	case reflect.Struct:
+		merged := reflect.New(d.Type()).Elem()
+		merged.Set(d)
+		return merged, deepMerge(merged, s, visited, depth, config)
+	case reflect.Ptr:
+		if config.DeepPointers && !d.IsNil() && !s.IsNil() && d.Elem().Kind() == reflect.Struct {
+			return dstElement, deepMerge(d.Elem(), s.Elem(), visited, depth, config)
+		}
+	}
+
+	if config.Overwrite {
+		return srcElement, nil
+	}
+	return dstElement, nil
+}
+
+// Returns a new slice combining dst and src according to config.Slice, dst and src are not modified.
+func mergeSlices(dst, src reflect.Value, visited map[uintptr]*visit, depth int, config *MergeConfig) (reflect.Value, error) {
+	if isEmptyValue(src) {
+		return dst, nil
+	}
+	if isEmptyValue(dst) {
+		return src, nil
+	}
+
+	switch config.Slice {
+	case MERGE_SLICE_APPEND:
+		return reflect.AppendSlice(reflect.AppendSlice(reflect.MakeSlice(dst.Type(), 0, dst.Len()+src.Len()), dst), src), nil
+	case MERGE_SLICE_APPEND_UNIQUE:
+		merged := reflect.AppendSlice(reflect.MakeSlice(dst.Type(), 0, dst.Len()+src.Len()), dst)
+		for i := 0; i < src.Len(); i++ {
+			found := false
+			for j := 0; j < merged.Len() && !found; j++ {
+				found = reflect.DeepEqual(merged.Index(j).Interface(), src.Index(i).Interface())
+			}
+			if !found {
+				merged = reflect.Append(merged, src.Index(i))
+			}
+		}
+		return merged, nil
+	case MERGE_SLICE_BY_INDEX:
+		merged := reflect.AppendSlice(reflect.MakeSlice(dst.Type(), 0, dst.Len()+src.Len()), dst)
+		for i := 0; i < src.Len(); i++ {
+			if i >= merged.Len() {
+				merged = reflect.Append(merged, src.Index(i))
+			} else if err := deepMerge(merged.Index(i), src.Index(i), visited, depth+1, config); err != nil {
+				return dst, err
+			}
+		}
+		return merged, nil
+	default:
+		if config.Overwrite {
+			return src, nil
+		}
+		return dst, nil
+	}
+}
+
+func merge(dst, src interface{}, config *MergeConfig) error {
 	var (
 		vDst, vSrc reflect.Value
 		err        error
@@ -208,7 +359,7 @@ func merge(dst, src interface{}, overwrite bool) error {
 	if vDst.Type() != vSrc.Type() {
 		return kMergeErrDifferentArgumentsTypes
 	}
-	return deepMerge(vDst, vSrc, make(map[uintptr]*visit), 0, overwrite)
+	return deepMerge(vDst, vSrc, make(map[uintptr]*visit), 0, config)
 }
 
 func changeInitialCase(s string, mapper func(rune) rune) string {
@@ -285,7 +436,7 @@ func deepMap(dst, src reflect.Value, visited map[uintptr]*visit, depth int, over
 				continue
 			}
 			if srcKind == dstKind {
-				if err = deepMerge(dstElement, srcElement, visited, depth+1, overwrite); err != nil {
+				if err = deepMerge(dstElement, srcElement, visited, depth+1, &MergeConfig{Overwrite: overwrite}); err != nil {
 					return
 				}
 			} else {
@@ -313,7 +464,7 @@ func _map(dst, src interface{}, overwrite bool) error {
 	// To be friction-less, we redirect equal-type arguments
 	// to deepMerge. Only because arguments can be anything.
 	if vSrc.Kind() == vDst.Kind() {
-		return deepMerge(vDst, vSrc, make(map[uintptr]*visit), 0, overwrite)
+		return deepMerge(vDst, vSrc, make(map[uintptr]*visit), 0, &MergeConfig{Overwrite: overwrite})
 	}
 	switch vSrc.Kind() {
 	case reflect.Struct:
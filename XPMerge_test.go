@@ -0,0 +1,96 @@
+package XPSuperKit
+
+import (
+	"reflect"
+	"testing"
+)
+
+type mergeTestItem struct {
+	Host string
+	Port int
+}
+
+type mergeTestConfig struct {
+	Name   string
+	Tags   []string
+	Items  []mergeTestItem
+	Labels map[string]string
+	Nested map[string]map[string]int
+	Sub    *mergeTestItem
+}
+
+func TestMergeSliceStrategies(t *testing.T) {
+	for strategy, want := range map[MergeSliceStrategy][]string{
+		MERGE_SLICE_REPLACE:       {"b", "c"},
+		MERGE_SLICE_APPEND:        {"a", "b", "b", "c"},
+		MERGE_SLICE_APPEND_UNIQUE: {"a", "b", "c"},
+		MERGE_SLICE_BY_INDEX:      {"b", "c"},
+	} {
+		tags := []string{"a", "b"}
+		dst := mergeTestConfig{Tags: tags, Items: []mergeTestItem{{"h", 1}, {"k", 0}}}
+		src := mergeTestConfig{Tags: []string{"b", "c"}, Items: []mergeTestItem{{Host: "z"}}}
+		if err := MergeWithConfig(&dst, &src, &MergeConfig{Overwrite: true, Slice: strategy}); err != nil {
+			t.Fatal(strategy, err)
+		}
+		if !reflect.DeepEqual(dst.Tags, want) {
+			t.Fatalf("%v: %v, want %v", strategy, dst.Tags, want)
+		}
+		if !reflect.DeepEqual(tags, []string{"a", "b"}) || !reflect.DeepEqual(src.Tags, []string{"b", "c"}) {
+			t.Fatalf("%v: input slices modified", strategy)
+		}
+		if strategy == MERGE_SLICE_BY_INDEX && !reflect.DeepEqual(dst.Items, []mergeTestItem{{"z", 1}, {"k", 0}}) {
+			t.Fatalf("%v: %v", strategy, dst.Items)
+		}
+	}
+
+	// 不覆盖时 MERGE_SLICE_REPLACE 保留 dst 中的非空切片
+	dst := mergeTestConfig{Tags: []string{"a"}}
+	if err := Merge(&dst, &mergeTestConfig{Tags: []string{"b"}}); err != nil || !reflect.DeepEqual(dst.Tags, []string{"a"}) {
+		t.Fatal(dst.Tags, err)
+	}
+	dst = mergeTestConfig{}
+	if err := Merge(&dst, &mergeTestConfig{Tags: []string{"b"}}); err != nil || !reflect.DeepEqual(dst.Tags, []string{"b"}) {
+		t.Fatal(dst.Tags, err)
+	}
+}
+
+func TestMergeMapStrategies(t *testing.T) {
+	dst := mergeTestConfig{Labels: map[string]string{"a": "1", "b": "1"}, Nested: map[string]map[string]int{"x": {"i": 1}}}
+	src := mergeTestConfig{Labels: map[string]string{"b": "2"}, Nested: map[string]map[string]int{"x": {"j": 2}}}
+	if err := MergeWithOverwrite(&dst, &src); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(dst.Labels, map[string]string{"a": "1", "b": "2"}) {
+		t.Fatal(dst.Labels)
+	}
+	if !reflect.DeepEqual(dst.Nested, map[string]map[string]int{"x": {"i": 1, "j": 2}}) {
+		t.Fatal(dst.Nested)
+	}
+
+	dst = mergeTestConfig{Labels: map[string]string{"a": "1"}}
+	if err := MergeWithConfig(&dst, &src, &MergeConfig{Overwrite: true, Map: MERGE_MAP_REPLACE}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(dst.Labels, map[string]string{"b": "2"}) {
+		t.Fatal(dst.Labels)
+	}
+}
+
+func TestMergePointer(t *testing.T) {
+	// 覆盖时非空的指针整体替换 dst
+	dst := mergeTestConfig{Name: "base", Sub: &mergeTestItem{Host: "h", Port: 1}}
+	if err := MergeWithOverwrite(&dst, &mergeTestConfig{Sub: &mergeTestItem{Port: 2}}); err != nil {
+		t.Fatal(err)
+	}
+	if dst.Name != "base" || *dst.Sub != (mergeTestItem{Port: 2}) {
+		t.Fatalf("%+v %+v", dst, dst.Sub)
+	}
+
+	dst = mergeTestConfig{Name: "base", Sub: &mergeTestItem{Host: "h", Port: 1}}
+	if err := MergeWithConfig(&dst, &mergeTestConfig{Sub: &mergeTestItem{Port: 2}}, &MergeConfig{Overwrite: true, DeepPointers: true}); err != nil {
+		t.Fatal(err)
+	}
+	if dst.Name != "base" || *dst.Sub != (mergeTestItem{"h", 2}) {
+		t.Fatalf("%+v %+v", dst, dst.Sub)
+	}
+}
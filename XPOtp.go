@@ -0,0 +1,269 @@
+package XPSuperKit
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*********调用示例********
+otp := XPSuperKit.NewOtp(&XPSuperKit.OtpOption{Issuer: "Admin"})
+
+// 为用户生成密钥，并生成二维码内容供认证器 App 扫描
+secret := XPSuperKit.GenerateOtpSecret(0)
+uri, err := otp.TOTPURI(secret, "alice@example.com")
+
+// 验证用户输入的验证码，保存 step 并拒绝 step 不大于已保存值的验证码以防止重放
+ok, step, err := otp.VerifyTOTP(secret, code, time.Now())
+ ************************/
+
+type OtpAlgorithm string
+
+const (
+	OTP_SHA1   OtpAlgorithm = "SHA1"
+	OTP_SHA256 OtpAlgorithm = "SHA256"
+	OTP_SHA512 OtpAlgorithm = "SHA512"
+
+	kOtpDefaultDigits       = 6
+	kOtpDefaultPeriod       = 30
+	kOtpDefaultSkew         = 1
+	kOtpDefaultSecretLength = 32 //32 个 base32 字符，160 位
+	kOtpBase32Alphabet      = "ABCDEFGHIJKLMNOPQRSTUVWXYZ234567"
+)
+
+var (
+	// 密钥不是合法的 base32 字符串
+	OtpErrInvalidSecret = errors.New("otp: invalid base32 secret")
+	// 验证码位数不在 6 到 8 之间
+	OtpErrInvalidDigits = errors.New("otp: digits must be between 6 and 8")
+	// 不支持的哈希算法
+	OtpErrUnsupportedAlgorithm = errors.New("otp: unsupported algorithm")
+)
+
+var otpDigitsPower = []uint32{1, 10, 100, 1000, 10000, 100000, 1000000, 10000000, 100000000}
+
+// 一次性密码参数，未设置的字段使用默认值
+type OtpOption struct {
+	Algorithm OtpAlgorithm //哈希算法，默认为 OTP_SHA1，大部分认证器 App 只支持 SHA1
+	Digits    int          //验证码位数，默认 6
+	Period    uint         //TOTP 时间步长(秒)，默认 30
+	Skew      uint         //TOTP 验证时允许前后偏差的时间步数；HOTP 验证时向后查找的计数器个数，默认 1
+	NoSkew    bool         //为 true 时不允许偏差，只接受当前时间步或计数器的验证码，忽略 Skew
+	Issuer    string       //签发者，写入 otpauth URI
+}
+
+// RFC 4226 HOTP 及 RFC 6238 TOTP
+type XPOtpImpl struct {
+	option OtpOption
+}
+
+// 创建一次性密码生成及验证器，opt 为 nil 时使用默认参数
+func NewOtp(opt *OtpOption) *XPOtpImpl {
+	option := OtpOption{}
+	if opt != nil {
+		option = *opt
+	}
+
+	if option.Algorithm == "" {
+		option.Algorithm = OTP_SHA1
+	}
+	if option.Digits == 0 {
+		option.Digits = kOtpDefaultDigits
+	}
+	if option.Period == 0 {
+		option.Period = kOtpDefaultPeriod
+	}
+	if option.NoSkew {
+		option.Skew = 0
+	} else if option.Skew == 0 {
+		option.Skew = kOtpDefaultSkew
+	}
+
+	return &XPOtpImpl{option: option}
+}
+
+// 生成 base32 编码的随机密钥，length 为 0 时生成 32 个字符 (160 位)
+func GenerateOtpSecret(length int) string {
+	if length <= 0 {
+		length = kOtpDefaultSecretLength
+	}
+	return XPString().RandomWithSeed(length, kOtpBase32Alphabet)
+}
+
+/**
+ * 生成 HOTP 验证码
+ * @param secret string base32 编码的密钥，忽略大小写、空格及填充
+ * @param counter uint64 计数器
+ */
+func (otp *XPOtpImpl) HOTP(secret string, counter uint64) (string, error) {
+	key, err := decodeOtpSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	return otp.generate(key, counter)
+}
+
+/**
+ * 验证 HOTP 验证码，依次尝试 counter 到 counter+Skew
+ * @return next uint64 验证通过时为匹配的计数器加 1，应保存用于下一次验证
+ */
+func (otp *XPOtpImpl) VerifyHOTP(secret, code string, counter uint64) (ok bool, next uint64, err error) {
+	key, err := decodeOtpSecret(secret)
+	if err != nil {
+		return false, counter, err
+	}
+
+	for i := uint64(0); i <= uint64(otp.option.Skew); i++ {
+		match, err := otp.match(key, counter+i, code)
+		if err != nil {
+			return false, counter, err
+		}
+		if match {
+			return true, counter + i + 1, nil
+		}
+	}
+
+	return false, counter, nil
+}
+
+// 生成 t 时刻的 TOTP 验证码
+func (otp *XPOtpImpl) TOTP(secret string, t time.Time) (string, error) {
+	return otp.HOTP(secret, otp.step(t))
+}
+
+/**
+ * 验证 TOTP 验证码，允许前后 Skew 个时间步的偏差
+ * @return step uint64 匹配的时间步，调用方应保存并拒绝不大于该值的时间步以防止验证码重放
+ */
+func (otp *XPOtpImpl) VerifyTOTP(secret, code string, t time.Time) (ok bool, step uint64, err error) {
+	key, err := decodeOtpSecret(secret)
+	if err != nil {
+		return false, 0, err
+	}
+
+	current := otp.step(t)
+	steps := []uint64{current}
+	for i := uint64(1); i <= uint64(otp.option.Skew); i++ {
+		if current >= i {
+			steps = append(steps, current-i)
+		}
+		steps = append(steps, current+i)
+	}
+
+	for _, step = range steps {
+		match, err := otp.match(key, step, code)
+		if err != nil {
+			return false, 0, err
+		}
+		if match {
+			return true, step, nil
+		}
+	}
+
+	return false, 0, nil
+}
+
+// 生成 TOTP 的 otpauth:// URI，可用于生成二维码
+func (otp *XPOtpImpl) TOTPURI(secret, accountName string) (string, error) {
+	params := url.Values{}
+	params.Set("period", strconv.FormatUint(uint64(otp.option.Period), 10))
+	return otp.uri("totp", secret, accountName, params)
+}
+
+// 生成 HOTP 的 otpauth:// URI，counter 为初始计数器
+func (otp *XPOtpImpl) HOTPURI(secret, accountName string, counter uint64) (string, error) {
+	params := url.Values{}
+	params.Set("counter", strconv.FormatUint(counter, 10))
+	return otp.uri("hotp", secret, accountName, params)
+}
+
+func (otp *XPOtpImpl) uri(otpType, secret, accountName string, params url.Values) (string, error) {
+	key, err := decodeOtpSecret(secret)
+	if err != nil {
+		return "", err
+	}
+
+	label := accountName
+	if otp.option.Issuer != "" {
+		label = otp.option.Issuer + ":" + accountName
+		params.Set("issuer", otp.option.Issuer)
+	}
+
+	params.Set("secret", base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(key))
+	params.Set("algorithm", string(otp.option.Algorithm))
+	params.Set("digits", strconv.Itoa(otp.option.Digits))
+
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     otpType,
+		Path:     "/" + label,
+		RawQuery: strings.Replace(params.Encode(), "+", "%20", -1),
+	}
+
+	return u.String(), nil
+}
+
+func (otp *XPOtpImpl) step(t time.Time) uint64 {
+	return uint64(t.Unix()) / uint64(otp.option.Period)
+}
+
+func (otp *XPOtpImpl) match(key []byte, counter uint64, code string) (bool, error) {
+	expected, err := otp.generate(key, counter)
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1, nil
+}
+
+// RFC 4226 5.3: HMAC 后动态截取 31 位并取模
+func (otp *XPOtpImpl) generate(key []byte, counter uint64) (string, error) {
+	if otp.option.Digits < 6 || otp.option.Digits > 8 {
+		return "", OtpErrInvalidDigits
+	}
+
+	var hashFunc func() hash.Hash
+	switch otp.option.Algorithm {
+	case OTP_SHA1:
+		hashFunc = sha1.New
+	case OTP_SHA256:
+		hashFunc = sha256.New
+	case OTP_SHA512:
+		hashFunc = sha512.New
+	default:
+		return "", OtpErrUnsupportedAlgorithm
+	}
+
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+
+	mac := hmac.New(hashFunc, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", otp.option.Digits, value%otpDigitsPower[otp.option.Digits]), nil
+}
+
+func decodeOtpSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.Replace(secret, " ", "", -1))
+	secret = strings.TrimRight(secret, "=")
+
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil || len(key) == 0 {
+		return nil, OtpErrInvalidSecret
+	}
+	return key, nil
+}
@@ -0,0 +1,63 @@
+package XPSuperKit
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOtpRFCVectors(t *testing.T) {
+	// RFC 4226 Appendix D
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	for i, want := range []string{"755224", "287082", "359152", "969429", "338314"} {
+		if code, err := XPOtp().HOTP(secret, uint64(i)); err != nil || code != want {
+			t.Fatalf("HOTP(%d) = %v, %v, want %v", i, code, err, want)
+		}
+	}
+
+	// RFC 6238 Appendix B，T = 59
+	secret256 := base32.StdEncoding.EncodeToString([]byte("12345678901234567890123456789012"))
+	secret512 := base32.StdEncoding.EncodeToString([]byte("1234567890123456789012345678901234567890123456789012345678901234"))
+	for _, c := range []struct {
+		algorithm OtpAlgorithm
+		secret    string
+		want      string
+	}{{OTP_SHA1, secret, "94287082"}, {OTP_SHA256, secret256, "46119246"}, {OTP_SHA512, secret512, "90693936"}} {
+		if code, _ := NewOtp(&OtpOption{Algorithm: c.algorithm, Digits: 8}).TOTP(c.secret, time.Unix(59, 0)); code != c.want {
+			t.Fatalf("%v TOTP = %v, want %v", c.algorithm, code, c.want)
+		}
+	}
+
+	if ok, next, _ := XPOtp().VerifyHOTP(secret, "287082", 0); !ok || next != 2 {
+		t.Fatalf("VerifyHOTP = %v, %v", ok, next)
+	}
+}
+
+func TestOtpSkew(t *testing.T) {
+	secret := GenerateOtpSecret(0)
+	now := time.Unix(1700000015, 0)
+	previous, _ := XPOtp().TOTP(secret, now.Add(-30*time.Second))
+
+	// 设置了其他参数时 Skew 同样默认为 1
+	for _, otp := range []*XPOtpImpl{XPOtp(), NewOtp(nil), NewOtp(&OtpOption{Issuer: "Admin"})} {
+		if ok, _, _ := otp.VerifyTOTP(secret, previous, now); !ok {
+			t.Fatal("code from the previous time step rejected")
+		}
+	}
+
+	if ok, _, _ := NewOtp(&OtpOption{NoSkew: true}).VerifyTOTP(secret, previous, now); ok {
+		t.Fatal("code from the previous time step accepted with NoSkew")
+	}
+	current, _ := XPOtp().TOTP(secret, now)
+	if ok, _, _ := NewOtp(&OtpOption{NoSkew: true}).VerifyTOTP(secret, current, now); !ok {
+		t.Fatal("current code rejected with NoSkew")
+	}
+}
+
+func TestOtpURI(t *testing.T) {
+	uri, err := NewOtp(&OtpOption{Issuer: "My Co"}).TOTPURI(GenerateOtpSecret(0), "alice@example.com")
+	if err != nil || !strings.HasPrefix(uri, "otpauth://totp/") || !strings.Contains(uri, "issuer=My") {
+		t.Fatalf("%v %v", uri, err)
+	}
+}
@@ -0,0 +1,335 @@
+package XPSuperKit
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+/*********调用示例********
+hasher := XPSuperKit.XPPassword()
+
+// 生成 PHC 格式的哈希，如 $argon2id$v=19$m=65536,t=3,p=4$c2FsdA$aGFzaA
+encoded, err := hasher.Hash(password)
+
+// 验证密码，验证通过后检查参数是否需要升级
+ok, err := hasher.Verify(password, encoded)
+if ok && hasher.NeedsRehash(encoded) {
+	encoded, err = hasher.Hash(password)
+}
+
+// 指定算法及参数
+hasher = XPSuperKit.NewPasswordHasher(&XPSuperKit.PasswordHashOption{
+	Algorithm: XPSuperKit.PASSWORD_SCRYPT,
+	Cost:      16,
+})
+ ************************/
+
+type PasswordAlgorithm string
+
+const (
+	PASSWORD_ARGON2ID      PasswordAlgorithm = "argon2id"
+	PASSWORD_SCRYPT        PasswordAlgorithm = "scrypt"
+	PASSWORD_PBKDF2_SHA256 PasswordAlgorithm = "pbkdf2-sha256"
+)
+
+const (
+	kPasswordSaltLength = 16
+	kPasswordKeyLength  = 32
+
+	kArgon2DefaultTime        = 3
+	kArgon2DefaultMemory      = 64 * 1024
+	kArgon2DefaultParallelism = 4
+
+	kScryptDefaultCost        = 17
+	kScryptDefaultBlockSize   = 8
+	kScryptDefaultParallelism = 1
+
+	kPBKDF2DefaultIterations = 600000
+)
+
+var (
+	// 哈希字符串不是合法的 PHC 格式或参数无效
+	PasswordErrInvalidHash = errors.New("password: invalid encoded hash")
+	// 哈希使用了不支持的算法
+	PasswordErrUnsupportedAlgorithm = errors.New("password: unsupported algorithm")
+	// argon2 版本与当前实现不一致
+	PasswordErrIncompatibleVersion = errors.New("password: incompatible argon2 version")
+)
+
+// 密码哈希参数，未设置的字段使用对应算法的默认值
+type PasswordHashOption struct {
+	Algorithm   PasswordAlgorithm //哈希算法，默认为 PASSWORD_ARGON2ID
+	Time        uint32            //argon2id 迭代次数，默认 3
+	Memory      uint32            //argon2id 内存大小(KiB)，默认 64 MiB
+	Cost        int               //scrypt 的 log2(N)，默认 17
+	BlockSize   int               //scrypt 的 r，默认 8
+	Parallelism int               //argon2id 及 scrypt 的并行度，默认分别为 4 和 1
+	Iterations  int               //pbkdf2 迭代次数，默认 600000
+	SaltLength  int               //盐长度，默认 16 字节
+	KeyLength   int               //哈希长度，默认 32 字节
+}
+
+// 解析后的哈希，params 只包含对应算法使用的参数
+type passwordHash struct {
+	algorithm PasswordAlgorithm
+	params    PasswordHashOption
+	salt      []byte
+	key       []byte
+}
+
+type XPPasswordImpl struct {
+	option PasswordHashOption
+}
+
+/**
+ * 创建密码哈希器
+ * @param opt *PasswordHashOption 哈希参数，为 nil 时使用 argon2id 及默认参数
+ */
+func NewPasswordHasher(opt *PasswordHashOption) *XPPasswordImpl {
+	option := PasswordHashOption{}
+	if opt != nil {
+		option = *opt
+	}
+
+	if option.Algorithm == "" {
+		option.Algorithm = PASSWORD_ARGON2ID
+	}
+
+	return &XPPasswordImpl{option: option.withDefaults()}
+}
+
+// 只保留算法使用的参数，并为未设置的参数填充默认值
+func (opt PasswordHashOption) withDefaults() PasswordHashOption {
+	result := PasswordHashOption{
+		Algorithm:  opt.Algorithm,
+		SaltLength: opt.SaltLength,
+		KeyLength:  opt.KeyLength,
+	}
+
+	if result.SaltLength <= 0 {
+		result.SaltLength = kPasswordSaltLength
+	}
+	if result.KeyLength <= 0 {
+		result.KeyLength = kPasswordKeyLength
+	}
+
+	switch opt.Algorithm {
+	case PASSWORD_ARGON2ID:
+		result.Time, result.Memory, result.Parallelism = opt.Time, opt.Memory, opt.Parallelism
+		if result.Time == 0 {
+			result.Time = kArgon2DefaultTime
+		}
+		if result.Memory == 0 {
+			result.Memory = kArgon2DefaultMemory
+		}
+		if result.Parallelism <= 0 || result.Parallelism > 255 {
+			result.Parallelism = kArgon2DefaultParallelism
+		}
+	case PASSWORD_SCRYPT:
+		result.Cost, result.BlockSize, result.Parallelism = opt.Cost, opt.BlockSize, opt.Parallelism
+		if result.Cost <= 0 {
+			result.Cost = kScryptDefaultCost
+		}
+		if result.BlockSize <= 0 {
+			result.BlockSize = kScryptDefaultBlockSize
+		}
+		if result.Parallelism <= 0 {
+			result.Parallelism = kScryptDefaultParallelism
+		}
+	case PASSWORD_PBKDF2_SHA256:
+		result.Iterations = opt.Iterations
+		if result.Iterations <= 0 {
+			result.Iterations = kPBKDF2DefaultIterations
+		}
+	}
+
+	return result
+}
+
+/**
+ * 计算密码哈希，每次调用使用新的随机盐
+ * @param password string 密码
+ * 返回 PHC 格式的哈希字符串，包含算法、参数、盐及哈希值
+ */
+func (p *XPPasswordImpl) Hash(password string) (string, error) {
+	salt := make([]byte, p.option.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	h := &passwordHash{algorithm: p.option.Algorithm, params: p.option, salt: salt}
+
+	key, err := h.derive(password, p.option.KeyLength)
+	if err != nil {
+		return "", err
+	}
+	h.key = key
+
+	return h.String(), nil
+}
+
+/**
+ * 验证密码，哈希值使用常量时间比较
+ * @param password string 密码
+ * @param encoded string Hash 生成的哈希字符串，可以使用与当前哈希器不同的算法及参数
+ * 密码不匹配时返回 false, nil，哈希字符串无效时返回错误
+ */
+func (p *XPPasswordImpl) Verify(password, encoded string) (bool, error) {
+	h, err := parsePasswordHash(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	key, err := h.derive(password, len(h.key))
+	if err != nil {
+		return false, err
+	}
+
+	return subtle.ConstantTimeCompare(key, h.key) == 1, nil
+}
+
+// 哈希使用的算法或参数与当前哈希器不一致时返回 true，应在验证通过后使用 Hash 重新计算并保存
+func (p *XPPasswordImpl) NeedsRehash(encoded string) bool {
+	h, err := parsePasswordHash(encoded)
+	if err != nil {
+		return true
+	}
+
+	h.params.SaltLength = len(h.salt)
+	h.params.KeyLength = len(h.key)
+
+	return h.params != p.option
+}
+
+func (h *passwordHash) derive(password string, keyLength int) ([]byte, error) {
+	switch h.algorithm {
+	case PASSWORD_ARGON2ID:
+		return argon2.IDKey([]byte(password), h.salt, h.params.Time, h.params.Memory, uint8(h.params.Parallelism), uint32(keyLength)), nil
+	case PASSWORD_SCRYPT:
+		return scrypt.Key([]byte(password), h.salt, 1<<uint(h.params.Cost), h.params.BlockSize, h.params.Parallelism, keyLength)
+	case PASSWORD_PBKDF2_SHA256:
+		return pbkdf2.Key([]byte(password), h.salt, h.params.Iterations, keyLength, sha256.New), nil
+	default:
+		return nil, PasswordErrUnsupportedAlgorithm
+	}
+}
+
+// 编码为 PHC 格式: $<算法>[$v=<版本>]$<参数>$<盐>$<哈希>
+func (h *passwordHash) String() string {
+	var params string
+
+	switch h.algorithm {
+	case PASSWORD_ARGON2ID:
+		params = fmt.Sprintf("v=%d$m=%d,t=%d,p=%d", argon2.Version, h.params.Memory, h.params.Time, h.params.Parallelism)
+	case PASSWORD_SCRYPT:
+		params = fmt.Sprintf("ln=%d,r=%d,p=%d", h.params.Cost, h.params.BlockSize, h.params.Parallelism)
+	case PASSWORD_PBKDF2_SHA256:
+		params = fmt.Sprintf("i=%d", h.params.Iterations)
+	}
+
+	return strings.Join([]string{
+		"",
+		string(h.algorithm),
+		params,
+		base64.RawStdEncoding.EncodeToString(h.salt),
+		base64.RawStdEncoding.EncodeToString(h.key),
+	}, "$")
+}
+
+func parsePasswordHash(encoded string) (*passwordHash, error) {
+	fields := strings.Split(encoded, "$")
+	if len(fields) < 5 || fields[0] != "" {
+		return nil, PasswordErrInvalidHash
+	}
+
+	h := &passwordHash{algorithm: PasswordAlgorithm(fields[1])}
+	fields = fields[2:]
+
+	var names []string
+	switch h.algorithm {
+	case PASSWORD_ARGON2ID:
+		if len(fields) != 4 {
+			return nil, PasswordErrInvalidHash
+		}
+		if fields[0] != fmt.Sprintf("v=%d", argon2.Version) {
+			return nil, PasswordErrIncompatibleVersion
+		}
+		fields = fields[1:]
+		names = []string{"m", "t", "p"}
+	case PASSWORD_SCRYPT:
+		names = []string{"ln", "r", "p"}
+	case PASSWORD_PBKDF2_SHA256:
+		names = []string{"i"}
+	default:
+		return nil, PasswordErrUnsupportedAlgorithm
+	}
+
+	if len(fields) != 3 {
+		return nil, PasswordErrInvalidHash
+	}
+
+	values, err := parsePasswordParams(fields[0], names)
+	if err != nil {
+		return nil, err
+	}
+
+	switch h.algorithm {
+	case PASSWORD_ARGON2ID:
+		if values[2] > 255 || uint64(values[0]) > math.MaxUint32 || uint64(values[1]) > math.MaxUint32 {
+			return nil, PasswordErrInvalidHash
+		}
+		h.params.Memory, h.params.Time, h.params.Parallelism = uint32(values[0]), uint32(values[1]), values[2]
+	case PASSWORD_SCRYPT:
+		if values[0] >= 63 {
+			return nil, PasswordErrInvalidHash
+		}
+		h.params.Cost, h.params.BlockSize, h.params.Parallelism = values[0], values[1], values[2]
+	case PASSWORD_PBKDF2_SHA256:
+		h.params.Iterations = values[0]
+	}
+	h.params.Algorithm = h.algorithm
+
+	if h.salt, err = base64.RawStdEncoding.DecodeString(fields[1]); err != nil {
+		return nil, PasswordErrInvalidHash
+	}
+	if h.key, err = base64.RawStdEncoding.DecodeString(fields[2]); err != nil || len(h.key) == 0 {
+		return nil, PasswordErrInvalidHash
+	}
+
+	return h, nil
+}
+
+// 按顺序解析 name=value 形式的参数，所有参数都必须是正整数
+func parsePasswordParams(s string, names []string) ([]int, error) {
+	pairs := strings.Split(s, ",")
+	if len(pairs) != len(names) {
+		return nil, PasswordErrInvalidHash
+	}
+
+	values := make([]int, len(names))
+	for i, pair := range pairs {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || kv[0] != names[i] {
+			return nil, PasswordErrInvalidHash
+		}
+
+		v, err := strconv.Atoi(kv[1])
+		if err != nil || v <= 0 {
+			return nil, PasswordErrInvalidHash
+		}
+		values[i] = v
+	}
+
+	return values, nil
+}
@@ -0,0 +1,125 @@
+package XPSuperKit
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+var passwordTestOptions = []*PasswordHashOption{
+	{Algorithm: PASSWORD_ARGON2ID, Memory: 1024, Time: 1, Parallelism: 1},
+	{Algorithm: PASSWORD_SCRYPT, Cost: 10},
+	{Algorithm: PASSWORD_PBKDF2_SHA256, Iterations: 1000},
+}
+
+func TestPasswordHashVerify(t *testing.T) {
+	for _, opt := range passwordTestOptions {
+		hasher := NewPasswordHasher(opt)
+
+		encoded, err := hasher.Hash("secret")
+		if err != nil {
+			t.Fatal(opt.Algorithm, err)
+		}
+		if !strings.HasPrefix(encoded, "$"+string(opt.Algorithm)+"$") {
+			t.Fatalf("%v: %v", opt.Algorithm, encoded)
+		}
+		if again, _ := hasher.Hash("secret"); again == encoded {
+			t.Fatalf("%v: salt reused", opt.Algorithm)
+		}
+
+		if ok, err := hasher.Verify("secret", encoded); !ok || err != nil {
+			t.Fatalf("%v: %v, %v", opt.Algorithm, ok, err)
+		}
+		if ok, err := hasher.Verify("Secret", encoded); ok || err != nil {
+			t.Fatalf("%v: wrong password: %v, %v", opt.Algorithm, ok, err)
+		}
+
+		// 哈希中包含算法及参数，使用其他参数的哈希器同样可以验证
+		if ok, err := XPPassword().Verify("secret", encoded); !ok || err != nil {
+			t.Fatalf("%v: default hasher: %v, %v", opt.Algorithm, ok, err)
+		}
+	}
+}
+
+// RFC 7914 第 11、12 节的 scrypt 及 PBKDF2-HMAC-SHA256 测试向量
+func TestPasswordVerifyKnownAnswer(t *testing.T) {
+	salt := func(s string) string { return base64.RawStdEncoding.EncodeToString([]byte(s)) }
+	key := func(s string) string { return base64.RawStdEncoding.EncodeToString(mustHex(s)) }
+
+	for _, c := range []struct{ password, encoded string }{
+		{"passwd", "$pbkdf2-sha256$i=1$" + salt("salt") + "$" +
+			key("55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783")},
+		{"password", "$scrypt$ln=10,r=8,p=16$" + salt("NaCl") + "$" +
+			key("fdbabe1c9d3472007856e7190d01e9fe7c6ad7cbc8237830e77376634b3731622eaf30d92e22a3886ff109279d9830dac727afb94a83ee6d8360cbdfa2cc0640")},
+	} {
+		if ok, err := XPPassword().Verify(c.password, c.encoded); !ok || err != nil {
+			t.Fatalf("%v: %v, %v", c.encoded, ok, err)
+		}
+	}
+}
+
+func TestPasswordNeedsRehash(t *testing.T) {
+	for _, opt := range passwordTestOptions {
+		hasher := NewPasswordHasher(opt)
+		encoded, _ := hasher.Hash("secret")
+
+		if hasher.NeedsRehash(encoded) {
+			t.Fatalf("%v: same parameters need rehash", opt.Algorithm)
+		}
+		if !XPPassword().NeedsRehash(encoded) {
+			t.Fatalf("%v: weaker parameters do not need rehash", opt.Algorithm)
+		}
+	}
+
+	stronger := *passwordTestOptions[0]
+	stronger.Time++
+	encoded, _ := NewPasswordHasher(passwordTestOptions[0]).Hash("secret")
+	if !NewPasswordHasher(&stronger).NeedsRehash(encoded) {
+		t.Fatal("changed argon2id time does not need rehash")
+	}
+
+	longerKey := *passwordTestOptions[2]
+	longerKey.KeyLength = 64
+	encoded, _ = NewPasswordHasher(passwordTestOptions[2]).Hash("secret")
+	if !NewPasswordHasher(&longerKey).NeedsRehash(encoded) {
+		t.Fatal("changed key length does not need rehash")
+	}
+
+	if !XPPassword().NeedsRehash("not a hash") {
+		t.Fatal("invalid hash does not need rehash")
+	}
+
+	encoded, _ = NewPasswordHasher(&PasswordHashOption{Memory: 1024, Time: 1}).Hash("x")
+	if !strings.HasPrefix(encoded, "$argon2id$v=19$m=1024,t=1,p=4$") {
+		t.Fatal(encoded)
+	}
+}
+
+func TestPasswordParseRejects(t *testing.T) {
+	valid, _ := NewPasswordHasher(passwordTestOptions[0]).Hash("secret")
+	fields := strings.Split(valid, "$")
+	saltAndKey := fields[4] + "$" + fields[5]
+
+	for encoded, want := range map[string]error{
+		"":      PasswordErrInvalidHash,
+		"plain": PasswordErrInvalidHash,
+		"argon2id$v=19$m=1,t=1,p=1$" + saltAndKey: PasswordErrInvalidHash,
+		"$md5$x$y$z": PasswordErrUnsupportedAlgorithm,
+		"$argon2id$v=16$m=1024,t=1,p=1$" + saltAndKey:      PasswordErrIncompatibleVersion,
+		"$argon2id$v=19$m=1024,t=1$" + saltAndKey:          PasswordErrInvalidHash,
+		"$argon2id$v=19$t=1,m=1024,p=1$" + saltAndKey:      PasswordErrInvalidHash,
+		"$argon2id$v=19$m=1024,t=0,p=1$" + saltAndKey:      PasswordErrInvalidHash,
+		"$argon2id$v=19$m=1024,t=1,p=256$" + saltAndKey:    PasswordErrInvalidHash,
+		"$argon2id$v=19$m=1024,t=1,p=1$" + fields[4]:       PasswordErrInvalidHash,
+		"$argon2id$v=19$m=1024,t=1,p=1$!!$" + fields[5]:    PasswordErrInvalidHash,
+		"$argon2id$v=19$m=1024,t=1,p=1$" + fields[4] + "$": PasswordErrInvalidHash,
+		"$scrypt$ln=x,r=8,p=1$" + saltAndKey:               PasswordErrInvalidHash,
+		"$scrypt$ln=63,r=8,p=1$" + saltAndKey:              PasswordErrInvalidHash,
+		"$pbkdf2-sha256$i=-1$" + saltAndKey:                PasswordErrInvalidHash,
+		"$pbkdf2-sha256$i=1,x=1$" + saltAndKey:             PasswordErrInvalidHash,
+	} {
+		if ok, err := XPPassword().Verify("secret", encoded); ok || err != want {
+			t.Fatalf("%q: %v, %v, want %v", encoded, ok, err, want)
+		}
+	}
+}
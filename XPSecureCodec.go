@@ -0,0 +1,219 @@
+package XPSuperKit
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+/*********调用示例********
+codec, err := XPSuperKit.NewSecureCodec(&XPSuperKit.SecureCodecOption{MaxAge: 24 * time.Hour},
+	XPSuperKit.SecureCodecKey{HashKey: hashKey, BlockKey: blockKey})
+
+// name 用于区分用途，如 cookie 名称，不同 name 生成的 token 不能互相替换
+token, err := codec.Encode("session", session)
+
+err = codec.Decode("session", token, &session)
+
+// 轮换密钥: 新密钥放在最前面用于编码，旧密钥仍可用于解码
+codec, err = XPSuperKit.NewSecureCodec(opt, newKey, oldKey)
+ ************************/
+
+const (
+	kSecureCodecVersion1   = 1
+	kSecureCodecHeaderSize = 9 //版本(1 字节) | 时间戳(8 字节)
+	kSecureCodecMinHashKey = 32
+	kSecureCodecMaxLength  = 4096
+	kSecureCodecClockSkew  = time.Minute
+)
+
+var (
+	// token 格式错误、签名不匹配或无法解密
+	SecureCodecErrInvalidToken = errors.New("securecodec: invalid token")
+	// token 已超过 MaxAge
+	SecureCodecErrExpired = errors.New("securecodec: token expired")
+	// 编码后的 token 超过 MaxLength
+	SecureCodecErrTooLong = errors.New("securecodec: token too long")
+	// 未提供密钥或密钥长度不符合要求
+	SecureCodecErrInvalidKey = errors.New("securecodec: hash key must be at least 32 bytes, block key must be 16, 24 or 32 bytes")
+)
+
+// 编解码密钥
+type SecureCodecKey struct {
+	HashKey  []byte //HMAC-SHA256 签名密钥，不少于 32 字节
+	BlockKey []byte //AES-GCM 加密密钥，16、24 或 32 字节，为 nil 时只签名不加密
+}
+
+type SecureCodecOption struct {
+	MaxAge    time.Duration //token 有效期，为 0 时不检查
+	MaxLength int           //编码后 token 的最大长度，默认 4096，与浏览器的 cookie 长度限制一致，为负数时不限制
+}
+
+// 签名及可选加密的 token 编解码器
+// token 格式为 base64url(版本 | 时间戳 | 数据 | HMAC)，数据为 JSON 编码的值，设置 BlockKey 时为其 AES-GCM 密文
+type XPSecureCodecImpl struct {
+	keys      []SecureCodecKey
+	maxAge    time.Duration
+	maxLength int
+}
+
+/**
+ * 创建编解码器
+ * @param opt *SecureCodecOption 可为 nil
+ * @param keys ...SecureCodecKey 第一个密钥用于编码，所有密钥按顺序尝试用于解码
+ */
+func NewSecureCodec(opt *SecureCodecOption, keys ...SecureCodecKey) (*XPSecureCodecImpl, error) {
+	if len(keys) == 0 {
+		return nil, SecureCodecErrInvalidKey
+	}
+
+	for _, key := range keys {
+		if len(key.HashKey) < kSecureCodecMinHashKey {
+			return nil, SecureCodecErrInvalidKey
+		}
+		if key.BlockKey != nil {
+			if _, err := newAEAD(AEAD_AES_GCM, key.BlockKey); err != nil {
+				return nil, SecureCodecErrInvalidKey
+			}
+		}
+	}
+
+	codec := &XPSecureCodecImpl{
+		keys:      keys,
+		maxLength: kSecureCodecMaxLength,
+	}
+
+	if opt != nil {
+		codec.maxAge = opt.MaxAge
+		if opt.MaxLength != 0 {
+			codec.maxLength = opt.MaxLength
+		}
+	}
+
+	return codec, nil
+}
+
+// 生成随机密钥，HashKey 为 32 字节，encrypt 为 true 时同时生成 32 字节的 BlockKey
+func GenerateSecureCodecKey(encrypt bool) (SecureCodecKey, error) {
+	key := SecureCodecKey{HashKey: make([]byte, 32)}
+	if _, err := rand.Read(key.HashKey); err != nil {
+		return key, err
+	}
+
+	if encrypt {
+		key.BlockKey = make([]byte, 32)
+		if _, err := rand.Read(key.BlockKey); err != nil {
+			return key, err
+		}
+	}
+
+	return key, nil
+}
+
+/**
+ * 编码
+ * @param name string token 用途，如 cookie 名称，解码时必须相同
+ * @param value interface{} 任意可 JSON 编码的值
+ */
+func (codec *XPSecureCodecImpl) Encode(name string, value interface{}) (string, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	key := codec.keys[0]
+	if key.BlockKey != nil {
+		if data, err = AEADEncrypt(AEAD_AES_GCM, key.BlockKey, data, []byte(name)); err != nil {
+			return "", err
+		}
+	}
+
+	b := make([]byte, kSecureCodecHeaderSize, kSecureCodecHeaderSize+len(data)+sha256.Size)
+	b[0] = kSecureCodecVersion1
+	binary.BigEndian.PutUint64(b[1:], uint64(time.Now().Unix()))
+	b = append(b, data...)
+	b = append(b, secureCodecMAC(key.HashKey, name, b)...)
+
+	token := base64.RawURLEncoding.EncodeToString(b)
+	if codec.maxLength > 0 && len(token) > codec.maxLength {
+		return "", SecureCodecErrTooLong
+	}
+
+	return token, nil
+}
+
+/**
+ * 解码，验证签名及有效期后将值解码至 dst
+ * @param name string 编码时使用的 name
+ * @param token string Encode 生成的 token
+ * @param dst interface{} 指针，同 json.Unmarshal
+ */
+func (codec *XPSecureCodecImpl) Decode(name, token string, dst interface{}) error {
+	_, err := codec.DecodeWithTime(name, token, dst)
+	return err
+}
+
+// 同 Decode，同时返回 token 的签发时间
+func (codec *XPSecureCodecImpl) DecodeWithTime(name, token string, dst interface{}) (time.Time, error) {
+	if codec.maxLength > 0 && len(token) > codec.maxLength {
+		return time.Time{}, SecureCodecErrTooLong
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(b) < kSecureCodecHeaderSize+sha256.Size || b[0] != kSecureCodecVersion1 {
+		return time.Time{}, SecureCodecErrInvalidToken
+	}
+
+	signed, mac := b[:len(b)-sha256.Size], b[len(b)-sha256.Size:]
+
+	var key *SecureCodecKey
+	for i := range codec.keys {
+		if hmac.Equal(mac, secureCodecMAC(codec.keys[i].HashKey, name, signed)) {
+			key = &codec.keys[i]
+			break
+		}
+	}
+	if key == nil {
+		return time.Time{}, SecureCodecErrInvalidToken
+	}
+
+	issuedAt := time.Unix(int64(binary.BigEndian.Uint64(signed[1:kSecureCodecHeaderSize])), 0)
+	age := time.Since(issuedAt)
+	if age < -kSecureCodecClockSkew {
+		return time.Time{}, SecureCodecErrInvalidToken
+	}
+	if codec.maxAge > 0 && age > codec.maxAge {
+		return time.Time{}, SecureCodecErrExpired
+	}
+
+	data := signed[kSecureCodecHeaderSize:]
+	if key.BlockKey != nil {
+		if data, err = AEADDecrypt(key.BlockKey, data, []byte(name)); err != nil {
+			return time.Time{}, SecureCodecErrInvalidToken
+		}
+	}
+
+	if err = json.Unmarshal(data, dst); err != nil {
+		return time.Time{}, err
+	}
+
+	return issuedAt, nil
+}
+
+// HMAC-SHA256(hashKey, len(name) | name | data)，name 参与签名，防止 token 被用于其他用途
+func secureCodecMAC(hashKey []byte, name string, data []byte) []byte {
+	h := hmac.New(sha256.New, hashKey)
+
+	var size [4]byte
+	binary.BigEndian.PutUint32(size[:], uint32(len(name)))
+	h.Write(size[:])
+	h.Write([]byte(name))
+	h.Write(data)
+
+	return h.Sum(nil)
+}
@@ -0,0 +1,189 @@
+package XPSuperKit
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"strings"
+	"testing"
+	"time"
+)
+
+type secureCodecTestValue struct {
+	UserId int64  `json:"uid"`
+	Name   string `json:"name"`
+}
+
+func secureCodecTestKeys(t *testing.T) []SecureCodecKey {
+	signed, err := GenerateSecureCodecKey(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := GenerateSecureCodecKey(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []SecureCodecKey{signed, encrypted}
+}
+
+// 修改 token 的签发时间并使用 key 重新签名
+func secureCodecTestResign(t *testing.T, key SecureCodecKey, name, token string, issuedAt time.Time) string {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := b[:len(b)-sha256.Size]
+	binary.BigEndian.PutUint64(signed[1:kSecureCodecHeaderSize], uint64(issuedAt.Unix()))
+	return base64.RawURLEncoding.EncodeToString(append(signed, secureCodecMAC(key.HashKey, name, signed)...))
+}
+
+func TestSecureCodecRoundTrip(t *testing.T) {
+	for _, key := range secureCodecTestKeys(t) {
+		codec, err := NewSecureCodec(&SecureCodecOption{MaxAge: time.Hour}, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		token, err := codec.Encode("session", secureCodecTestValue{UserId: 7, Name: "bob"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var value secureCodecTestValue
+		issuedAt, err := codec.DecodeWithTime("session", token, &value)
+		if err != nil || value.UserId != 7 || value.Name != "bob" {
+			t.Fatalf("encrypt %v: %+v, %v", key.BlockKey != nil, value, err)
+		}
+		if time.Since(issuedAt) > time.Minute {
+			t.Fatalf("issued at %v", issuedAt)
+		}
+
+		// 签名数据中的明文只在未加密时可见
+		raw, _ := base64.RawURLEncoding.DecodeString(token)
+		if visible := strings.Contains(string(raw), `"bob"`); visible != (key.BlockKey == nil) {
+			t.Fatalf("encrypt %v: plaintext visible %v", key.BlockKey != nil, visible)
+		}
+	}
+}
+
+func TestSecureCodecTamper(t *testing.T) {
+	for _, key := range secureCodecTestKeys(t) {
+		codec, _ := NewSecureCodec(nil, key)
+		token, _ := codec.Encode("session", secureCodecTestValue{UserId: 7})
+		raw, _ := base64.RawURLEncoding.DecodeString(token)
+
+		var value secureCodecTestValue
+		for i := range raw {
+			tampered := append([]byte(nil), raw...)
+			tampered[i] ^= 0x01
+			if err := codec.Decode("session", base64.RawURLEncoding.EncodeToString(tampered), &value); err != SecureCodecErrInvalidToken {
+				t.Fatalf("encrypt %v: tampering byte %d: %v", key.BlockKey != nil, i, err)
+			}
+		}
+		for _, n := range []int{0, 1, kSecureCodecHeaderSize, len(raw) - 1} {
+			if err := codec.Decode("session", base64.RawURLEncoding.EncodeToString(raw[:n]), &value); err != SecureCodecErrInvalidToken {
+				t.Fatalf("encrypt %v: truncation to %d bytes: %v", key.BlockKey != nil, n, err)
+			}
+		}
+		if err := codec.Decode("session", token+"!", &value); err != SecureCodecErrInvalidToken {
+			t.Fatalf("invalid base64: %v", err)
+		}
+
+		// name 参与签名，token 不能用于其他用途
+		if err := codec.Decode("csrf", token, &value); err != SecureCodecErrInvalidToken {
+			t.Fatalf("encrypt %v: wrong name: %v", key.BlockKey != nil, err)
+		}
+
+		other, _ := NewSecureCodec(nil, secureCodecTestKeys(t)[1])
+		if err := other.Decode("session", token, &value); err != SecureCodecErrInvalidToken {
+			t.Fatalf("encrypt %v: wrong key: %v", key.BlockKey != nil, err)
+		}
+	}
+}
+
+func TestSecureCodecExpiry(t *testing.T) {
+	for _, key := range secureCodecTestKeys(t) {
+		codec, _ := NewSecureCodec(&SecureCodecOption{MaxAge: time.Hour}, key)
+		token, _ := codec.Encode("session", 1)
+
+		var value int
+		if err := codec.Decode("session", secureCodecTestResign(t, key, "session", token, time.Now().Add(-30*time.Minute)), &value); err != nil || value != 1 {
+			t.Fatalf("encrypt %v: within max age: %v", key.BlockKey != nil, err)
+		}
+		expired := secureCodecTestResign(t, key, "session", token, time.Now().Add(-2*time.Hour))
+		if err := codec.Decode("session", expired, &value); err != SecureCodecErrExpired {
+			t.Fatalf("encrypt %v: expired token: %v", key.BlockKey != nil, err)
+		}
+		if err := codec.Decode("session", secureCodecTestResign(t, key, "session", token, time.Now().Add(time.Hour)), &value); err != SecureCodecErrInvalidToken {
+			t.Fatalf("encrypt %v: token from the future: %v", key.BlockKey != nil, err)
+		}
+
+		// MaxAge 为 0 时不检查有效期
+		unlimited, _ := NewSecureCodec(nil, key)
+		if err := unlimited.Decode("session", expired, &value); err != nil {
+			t.Fatalf("encrypt %v: no max age: %v", key.BlockKey != nil, err)
+		}
+	}
+}
+
+func TestSecureCodecKeyRotation(t *testing.T) {
+	keys := secureCodecTestKeys(t)
+	current, previous := keys[1], keys[0]
+
+	old, _ := NewSecureCodec(nil, previous)
+	oldToken, _ := old.Encode("session", "old")
+
+	rotated, err := NewSecureCodec(nil, current, previous)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var value string
+	if err := rotated.Decode("session", oldToken, &value); err != nil || value != "old" {
+		t.Fatalf("token signed with previous key: %q, %v", value, err)
+	}
+
+	// 新 token 使用第一个密钥编码，旧的编解码器无法解码
+	newToken, _ := rotated.Encode("session", "new")
+	if err := old.Decode("session", newToken, &value); err != SecureCodecErrInvalidToken {
+		t.Fatalf("old codec decoded new token: %v", err)
+	}
+	if current, _ := NewSecureCodec(nil, current); current.Decode("session", newToken, &value) != nil || value != "new" {
+		t.Fatalf("new token: %q", value)
+	}
+
+	// 移除旧密钥后旧 token 失效
+	retired, _ := NewSecureCodec(nil, current)
+	if err := retired.Decode("session", oldToken, &value); err != SecureCodecErrInvalidToken {
+		t.Fatalf("retired key: %v", err)
+	}
+}
+
+func TestSecureCodecOptions(t *testing.T) {
+	for _, key := range []SecureCodecKey{
+		{HashKey: []byte("short")},
+		{HashKey: make([]byte, 32), BlockKey: make([]byte, 10)},
+	} {
+		if _, err := NewSecureCodec(nil, key); err != SecureCodecErrInvalidKey {
+			t.Fatalf("%d/%d byte keys: %v", len(key.HashKey), len(key.BlockKey), err)
+		}
+	}
+	if _, err := NewSecureCodec(nil); err != SecureCodecErrInvalidKey {
+		t.Fatalf("no keys: %v", err)
+	}
+
+	keys := secureCodecTestKeys(t)
+	codec, _ := NewSecureCodec(nil, keys[0])
+	if _, err := codec.Encode("session", strings.Repeat("x", 5000)); err != SecureCodecErrTooLong {
+		t.Fatalf("long value: %v", err)
+	}
+	if err := codec.Decode("session", strings.Repeat("x", 5000), new(string)); err != SecureCodecErrTooLong {
+		t.Fatalf("long token: %v", err)
+	}
+
+	unlimited, _ := NewSecureCodec(&SecureCodecOption{MaxLength: -1}, keys[0])
+	token, err := unlimited.Encode("session", strings.Repeat("x", 5000))
+	var value string
+	if err != nil || unlimited.Decode("session", token, &value) != nil || len(value) != 5000 {
+		t.Fatalf("unlimited length: %v", err)
+	}
+}
@@ -0,0 +1,363 @@
+package XPSuperKit
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+	"hash/crc32"
+	"hash/crc64"
+	"hash/fnv"
+	"io"
+	"math/bits"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/sha3"
+)
+
+/*********调用示例********
+str := XPSuperKit.XPString()
+
+sum, err := str.Hash(XPSuperKit.HASH_SHA256, "hello")
+
+// 对文件内容流式计算摘要并以 base64 输出
+sum, err = str.HashFile(XPSuperKit.HASH_BLAKE2B_256, "./data/archive.tar", XPSuperKit.HASH_ENCODING_BASE64)
+
+// 对任意 io.Reader 计算摘要
+raw, err := str.HashReader(XPSuperKit.HASH_XXHASH64, resp.Body)
+ ************************/
+
+type HashAlgorithm string
+
+const (
+	HASH_MD5         HashAlgorithm = "MD5"
+	HASH_SHA1        HashAlgorithm = "SHA1"
+	HASH_SHA224      HashAlgorithm = "SHA224"
+	HASH_SHA256      HashAlgorithm = "SHA256"
+	HASH_SHA384      HashAlgorithm = "SHA384"
+	HASH_SHA512      HashAlgorithm = "SHA512"
+	HASH_SHA512_256  HashAlgorithm = "SHA512/256"
+	HASH_SHA3_224    HashAlgorithm = "SHA3-224"
+	HASH_SHA3_256    HashAlgorithm = "SHA3-256"
+	HASH_SHA3_384    HashAlgorithm = "SHA3-384"
+	HASH_SHA3_512    HashAlgorithm = "SHA3-512"
+	HASH_BLAKE2B_256 HashAlgorithm = "BLAKE2b-256"
+	HASH_BLAKE2B_384 HashAlgorithm = "BLAKE2b-384"
+	HASH_BLAKE2B_512 HashAlgorithm = "BLAKE2b-512"
+
+	// 以下为非加密哈希，只能用于校验和分桶，不能用于安全场景
+	HASH_XXHASH64  HashAlgorithm = "XXH64"
+	HASH_FNV32     HashAlgorithm = "FNV-32"
+	HASH_FNV32A    HashAlgorithm = "FNV-32a"
+	HASH_FNV64     HashAlgorithm = "FNV-64"
+	HASH_FNV64A    HashAlgorithm = "FNV-64a"
+	HASH_FNV128A   HashAlgorithm = "FNV-128a"
+	HASH_CRC32     HashAlgorithm = "CRC32"
+	HASH_CRC64_ISO HashAlgorithm = "CRC64-ISO"
+	HASH_CRC64     HashAlgorithm = "CRC64-ECMA"
+)
+
+type HashEncoding string
+
+const (
+	HASH_ENCODING_HEX        HashEncoding = "hex"
+	HASH_ENCODING_HEX_UPPER  HashEncoding = "HEX"
+	HASH_ENCODING_BASE64     HashEncoding = "base64"
+	HASH_ENCODING_BASE64_URL HashEncoding = "base64url" //不带填充
+	HASH_ENCODING_BASE32     HashEncoding = "base32"
+	HASH_ENCODING_RAW        HashEncoding = "raw" //原始字节，仅适用于需要 string 类型的场景
+)
+
+var (
+	crc64ISOTable  = crc64.MakeTable(crc64.ISO)
+	crc64ECMATable = crc64.MakeTable(crc64.ECMA)
+)
+
+// 创建对应算法的 hash.Hash
+func NewHash(alg HashAlgorithm) (hash.Hash, error) {
+	switch alg {
+	case HASH_MD5:
+		return md5.New(), nil
+	case HASH_SHA1:
+		return sha1.New(), nil
+	case HASH_SHA224:
+		return sha256.New224(), nil
+	case HASH_SHA256:
+		return sha256.New(), nil
+	case HASH_SHA384:
+		return sha512.New384(), nil
+	case HASH_SHA512:
+		return sha512.New(), nil
+	case HASH_SHA512_256:
+		return sha512.New512_256(), nil
+	case HASH_SHA3_224:
+		return sha3.New224(), nil
+	case HASH_SHA3_256:
+		return sha3.New256(), nil
+	case HASH_SHA3_384:
+		return sha3.New384(), nil
+	case HASH_SHA3_512:
+		return sha3.New512(), nil
+	case HASH_BLAKE2B_256:
+		return blake2b.New256(nil)
+	case HASH_BLAKE2B_384:
+		return blake2b.New384(nil)
+	case HASH_BLAKE2B_512:
+		return blake2b.New512(nil)
+	case HASH_XXHASH64:
+		return NewXXHash64(0), nil
+	case HASH_FNV32:
+		return fnv.New32(), nil
+	case HASH_FNV32A:
+		return fnv.New32a(), nil
+	case HASH_FNV64:
+		return fnv.New64(), nil
+	case HASH_FNV64A:
+		return fnv.New64a(), nil
+	case HASH_FNV128A:
+		return fnv.New128a(), nil
+	case HASH_CRC32:
+		return crc32.NewIEEE(), nil
+	case HASH_CRC64_ISO:
+		return crc64.New(crc64ISOTable), nil
+	case HASH_CRC64:
+		return crc64.New(crc64ECMATable), nil
+	default:
+		return nil, ErrorF("unsupported hash algorithm %q", alg)
+	}
+}
+
+/**
+ * 计算字符串的摘要
+ * @param alg HashAlgorithm 哈希算法
+ * @param str string 需要计算的字符串
+ * @param encoding ...HashEncoding 输出编码，默认为小写 hex
+ */
+func (s *XPStringImpl) Hash(alg HashAlgorithm, str string, encoding ...HashEncoding) (string, error) {
+	sum, err := s.HashBytes(alg, []byte(str))
+	if err != nil {
+		return "", err
+	}
+	return s.EncodeHash(sum, encoding...)
+}
+
+// 计算字节数组的摘要，返回原始字节
+func (s *XPStringImpl) HashBytes(alg HashAlgorithm, data []byte) ([]byte, error) {
+	h, err := NewHash(alg)
+	if err != nil {
+		return nil, err
+	}
+	h.Write(data)
+	return h.Sum(nil), nil
+}
+
+// 流式计算 io.Reader 全部内容的摘要，返回原始字节
+func (s *XPStringImpl) HashReader(alg HashAlgorithm, r io.Reader) ([]byte, error) {
+	h, err := NewHash(alg)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = io.Copy(h, r); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+/**
+ * 流式计算文件内容的摘要，不会将文件全部读入内存
+ * @param filePath string 文件路径
+ * @param encoding ...HashEncoding 输出编码，默认为小写 hex
+ */
+func (s *XPStringImpl) HashFile(alg HashAlgorithm, filePath string, encoding ...HashEncoding) (string, error) {
+	path, err := XPFilePath(filePath)
+	if err != nil {
+		return "", err
+	}
+
+	file, err := path.Open()
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	sum, err := s.HashReader(alg, file)
+	if err != nil {
+		return "", err
+	}
+	return s.EncodeHash(sum, encoding...)
+}
+
+// 按指定编码输出摘要，默认为小写 hex
+func (s *XPStringImpl) EncodeHash(sum []byte, encoding ...HashEncoding) (string, error) {
+	enc := HASH_ENCODING_HEX
+	if len(encoding) > 0 {
+		enc = encoding[0]
+	}
+
+	switch enc {
+	case HASH_ENCODING_HEX:
+		return hex.EncodeToString(sum), nil
+	case HASH_ENCODING_HEX_UPPER:
+		return strings.ToUpper(hex.EncodeToString(sum)), nil
+	case HASH_ENCODING_BASE64:
+		return base64.StdEncoding.EncodeToString(sum), nil
+	case HASH_ENCODING_BASE64_URL:
+		return base64.RawURLEncoding.EncodeToString(sum), nil
+	case HASH_ENCODING_BASE32:
+		return base32.StdEncoding.EncodeToString(sum), nil
+	case HASH_ENCODING_RAW:
+		return string(sum), nil
+	default:
+		return "", ErrorF("unsupported hash encoding %q", enc)
+	}
+}
+
+func (s *XPStringImpl) SHA256(str string) string {
+	sum := sha256.Sum256([]byte(str))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *XPStringImpl) SHA512(str string) string {
+	sum := sha512.Sum512([]byte(str))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *XPStringImpl) CRC64(str string) uint64 {
+	return crc64.Checksum([]byte(str), crc64ECMATable)
+}
+
+func (s *XPStringImpl) XXHash64(str string) uint64 {
+	h := NewXXHash64(0)
+	h.Write([]byte(str))
+	return h.Sum64()
+}
+
+/*********************** XXH64 ********************/
+
+const (
+	kXXH64Prime1 uint64 = 11400714785074694791
+	kXXH64Prime2 uint64 = 14029467366897019727
+	kXXH64Prime3 uint64 = 1609587929392839161
+	kXXH64Prime4 uint64 = 9650029242287828579
+	kXXH64Prime5 uint64 = 2870177450012600261
+)
+
+// xxHash 64 位实现，实现 hash.Hash64
+type XXHash64 struct {
+	seed  uint64
+	v     [4]uint64
+	total uint64
+	buf   [32]byte
+	n     int
+}
+
+// 创建 XXH64，seed 为种子
+func NewXXHash64(seed uint64) *XXHash64 {
+	h := &XXHash64{seed: seed}
+	h.Reset()
+	return h
+}
+
+func (h *XXHash64) Reset() {
+	h.v[0] = h.seed + kXXH64Prime1 + kXXH64Prime2
+	h.v[1] = h.seed + kXXH64Prime2
+	h.v[2] = h.seed
+	h.v[3] = h.seed - kXXH64Prime1
+	h.total = 0
+	h.n = 0
+}
+
+func (h *XXHash64) Size() int {
+	return 8
+}
+
+func (h *XXHash64) BlockSize() int {
+	return 32
+}
+
+func (h *XXHash64) Write(p []byte) (int, error) {
+	length := len(p)
+	h.total += uint64(length)
+
+	if h.n+len(p) < 32 {
+		h.n += copy(h.buf[h.n:], p)
+		return length, nil
+	}
+
+	if h.n > 0 {
+		copied := copy(h.buf[h.n:], p)
+		h.stripe(h.buf[:])
+		p = p[copied:]
+		h.n = 0
+	}
+
+	for ; len(p) >= 32; p = p[32:] {
+		h.stripe(p)
+	}
+
+	h.n = copy(h.buf[:], p)
+	return length, nil
+}
+
+func (h *XXHash64) stripe(p []byte) {
+	for i := range h.v {
+		h.v[i] = xxh64Round(h.v[i], binary.LittleEndian.Uint64(p[i*8:]))
+	}
+}
+
+func (h *XXHash64) Sum(b []byte) []byte {
+	var sum [8]byte
+	binary.BigEndian.PutUint64(sum[:], h.Sum64())
+	return append(b, sum[:]...)
+}
+
+func (h *XXHash64) Sum64() uint64 {
+	var acc uint64
+
+	if h.total >= 32 {
+		acc = bits.RotateLeft64(h.v[0], 1) + bits.RotateLeft64(h.v[1], 7) +
+			bits.RotateLeft64(h.v[2], 12) + bits.RotateLeft64(h.v[3], 18)
+		for _, v := range h.v {
+			acc = (acc^xxh64Round(0, v))*kXXH64Prime1 + kXXH64Prime4
+		}
+	} else {
+		acc = h.seed + kXXH64Prime5
+	}
+
+	acc += h.total
+
+	p := h.buf[:h.n]
+	for ; len(p) >= 8; p = p[8:] {
+		acc ^= xxh64Round(0, binary.LittleEndian.Uint64(p))
+		acc = bits.RotateLeft64(acc, 27)*kXXH64Prime1 + kXXH64Prime4
+	}
+	if len(p) >= 4 {
+		acc ^= uint64(binary.LittleEndian.Uint32(p)) * kXXH64Prime1
+		acc = bits.RotateLeft64(acc, 23)*kXXH64Prime2 + kXXH64Prime3
+		p = p[4:]
+	}
+	for _, b := range p {
+		acc ^= uint64(b) * kXXH64Prime5
+		acc = bits.RotateLeft64(acc, 11) * kXXH64Prime1
+	}
+
+	acc ^= acc >> 33
+	acc *= kXXH64Prime2
+	acc ^= acc >> 29
+	acc *= kXXH64Prime3
+	acc ^= acc >> 32
+
+	return acc
+}
+
+func xxh64Round(acc, input uint64) uint64 {
+	acc += input * kXXH64Prime2
+	acc = bits.RotateLeft64(acc, 31)
+	return acc * kXXH64Prime1
+}
@@ -0,0 +1,129 @@
+package XPSuperKit
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// 各算法标准文档中的测试向量
+func TestHashKnownAnswers(t *testing.T) {
+	str := XPString()
+
+	for _, c := range []struct {
+		alg   HashAlgorithm
+		input string
+		want  string
+	}{
+		{HASH_MD5, "abc", "900150983cd24fb0d6963f7d28e17f72"},
+		{HASH_SHA1, "abc", "a9993e364706816aba3e25717850c26c9cd0d89d"},
+		{HASH_SHA224, "abc", "23097d223405d8228642a477bda255b32aadbce4bda0b3f7e36c9da7"},
+		{HASH_SHA256, "abc", "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{HASH_SHA384, "abc", "cb00753f45a35e8bb5a03d699ac65007272c32ab0eded1631a8b605a43ff5bed8086072ba1e7cc2358baeca134c825a7"},
+		{HASH_SHA512, "abc", "ddaf35a193617abacc417349ae20413112e6fa4e89a97ea20a9eeee64b55d39a2192992a274fc1a836ba3c23a3feebbd454d4423643ce80e2a9ac94fa54ca49f"},
+		{HASH_SHA512_256, "abc", "53048e2681941ef99b2e29b76b4c7dabe4c2d0c634fc6d46e0e2f13107e7af23"},
+		{HASH_SHA3_256, "", "a7ffc6f8bf1ed76651c14756a061d662f580ff4de43b49fa82d80a4b80f8434a"},
+		{HASH_SHA3_256, "abc", "3a985da74fe225b2045c172d6bd390bd855f086e3e9d525b46bfe24511431532"},
+		{HASH_BLAKE2B_512, "abc", "ba80a53f981c4d0d6a2797b69f12f6e94c212f14685ac4b74b12bb6fdbffa2d17d87c5392aab792dc252d5de4533cc9518d38aa8dbf1925ab92386edd4009923"},
+		{HASH_XXHASH64, "", "ef46db3751d8e999"},
+		{HASH_XXHASH64, "abc", "44bc2cf5ad770999"},
+		{HASH_XXHASH64, "Nobody inspects the spammish repetition", "fbcea83c8a378bf1"},
+		{HASH_FNV32, "", "811c9dc5"},
+		{HASH_FNV32, "a", "050c5d7e"},
+		{HASH_FNV32A, "a", "e40c292c"},
+		{HASH_FNV64, "", "cbf29ce484222325"},
+		{HASH_FNV64A, "a", "af63dc4c8601ec8c"},
+		{HASH_CRC32, "123456789", "cbf43926"},
+		{HASH_CRC64, "123456789", "995dc9bbdf1939fa"},
+		{HASH_CRC64_ISO, "123456789", "b90956c775a41001"},
+	} {
+		if got, err := str.Hash(c.alg, c.input); err != nil || got != c.want {
+			t.Fatalf("%v(%q) = %v, %v, want %v", c.alg, c.input, got, err, c.want)
+		}
+	}
+
+	if str.SHA256("abc") != "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad" {
+		t.Fatal(str.SHA256("abc"))
+	}
+	if str.XXHash64("abc") != 0x44bc2cf5ad770999 || str.CRC64("123456789") != 0x995dc9bbdf1939fa {
+		t.Fatalf("%x %x", str.XXHash64("abc"), str.CRC64("123456789"))
+	}
+}
+
+// 分多次写入的结果与一次写入相同，覆盖 32 字节分块的各个边界
+func TestXXHash64Streaming(t *testing.T) {
+	data := []byte(strings.Repeat("0123456789abcdef", 20))
+
+	for _, n := range []int{0, 1, 31, 32, 33, 63, 64, 65, len(data)} {
+		want := NewXXHash64(7)
+		want.Write(data[:n])
+
+		for _, step := range []int{1, 3, 7, 32, 40} {
+			h := NewXXHash64(7)
+			for i := 0; i < n; i += step {
+				end := i + step
+				if end > n {
+					end = n
+				}
+				h.Write(data[i:end])
+			}
+			if h.Sum64() != want.Sum64() {
+				t.Fatalf("%d bytes in steps of %d: %x, want %x", n, step, h.Sum64(), want.Sum64())
+			}
+		}
+
+		h := NewXXHash64(0)
+		h.Write(data[:n])
+		if h.Sum64() != XPString().XXHash64(string(data[:n])) {
+			t.Fatalf("%d bytes: streaming and one-shot differ", n)
+		}
+		h.Reset()
+		if h.Sum64() != 0xef46db3751d8e999 {
+			t.Fatalf("reset: %x", h.Sum64())
+		}
+	}
+
+	if NewXXHash64(1).Sum64() == NewXXHash64(0).Sum64() {
+		t.Fatal("seed ignored")
+	}
+}
+
+func TestHashEncodingsAndFile(t *testing.T) {
+	str := XPString()
+
+	for enc, want := range map[HashEncoding]string{
+		HASH_ENCODING_HEX_UPPER:  "900150983CD24FB0D6963F7D28E17F72",
+		HASH_ENCODING_BASE64:     "kAFQmDzST7DWlj99KOF/cg==",
+		HASH_ENCODING_BASE64_URL: "kAFQmDzST7DWlj99KOF_cg",
+		HASH_ENCODING_BASE32:     "SAAVBGB42JH3BVUWH56SRYL7OI======",
+	} {
+		if got, err := str.Hash(HASH_MD5, "abc", enc); err != nil || got != want {
+			t.Fatalf("%v: %v, %v", enc, got, err)
+		}
+	}
+	if _, err := str.Hash(HASH_MD5, "abc", "base58"); err == nil {
+		t.Fatal("unsupported encoding accepted")
+	}
+	if _, err := str.Hash("MD4", "abc"); err == nil {
+		t.Fatal("unsupported algorithm accepted")
+	}
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "data")
+	if err := ioutil.WriteFile(file, []byte("abc"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := str.HashFile(HASH_SHA256, file, HASH_ENCODING_BASE64); err != nil || got != "ungWv48Bz+pBQUDeXa4iI7ADYaOWF3qctBD/YfIAFa0=" {
+		t.Fatal(got, err)
+	}
+	if _, err := str.HashFile(HASH_SHA256, dir); err == nil {
+		t.Fatal("directory hashed")
+	}
+
+	sum, err := str.HashReader(HASH_XXHASH64, bytes.NewReader([]byte("abc")))
+	if err != nil || !bytes.Equal(sum, mustHex("44bc2cf5ad770999")) {
+		t.Fatalf("%x, %v", sum, err)
+	}
+}
@@ -92,6 +92,18 @@ func XPMemoryCache(count int, capacity int) *XPMemoryCacheImpl {
 	return NewMemoryCache(count, capacity)
 }
 
+func XPKeyRing() *XPKeyRingImpl {
+	return NewKeyRing()
+}
+
+func XPPassword() *XPPasswordImpl {
+	return NewPasswordHasher(nil)
+}
+
+func XPOtp() *XPOtpImpl {
+	return NewOtp(nil)
+}
+
 func XPIP() *XPIPImpl {
 	return &(XPIPImpl{})
 }
\ No newline at end of file